@@ -89,7 +89,7 @@ module "rds" {
 
   db_identifier   = "${var.project_name}-metadata"
   engine          = "postgres"
-  engine_version  = "15.4"
+  engine_version  = "15"
   instance_class  = var.db_instance_class
 
   database_name   = var.db_name
@@ -17,3 +17,13 @@ output "coordinator_instance_id" {
   description = "Coordinator instance ID"
   value       = module.coordinator.instance_id
 }
+
+output "vpc_id" {
+  description = "VPC ID"
+  value       = module.vpc.vpc_id
+}
+
+output "private_subnet_ids" {
+  description = "Private subnet IDs"
+  value       = module.vpc.private_subnet_ids
+}
@@ -17,3 +17,13 @@ output "database_endpoint" {
   description = "Database endpoint"
   value       = module.rds.db_instance_endpoint
 }
+
+output "vpc_id" {
+  description = "VPC ID"
+  value       = module.vpc.vpc_id
+}
+
+output "private_subnet_ids" {
+  description = "Private subnet IDs"
+  value       = module.vpc.private_subnet_ids
+}
@@ -68,7 +68,7 @@ module "rds" {
 
   db_identifier   = "${var.project_name}-db"
   engine          = "postgres"
-  engine_version  = "15.4"
+  engine_version  = "15"
   instance_class  = var.db_instance_class
 
   database_name   = var.db_name
@@ -114,7 +114,10 @@ module "alb" {
   security_group_ids = [aws_security_group.alb.id]
   vpc_id             = module.vpc.vpc_id
 
-  enable_http_listener = true
+  # The example has no certificate, so it serves plain HTTP
+  enable_http_listener   = true
+  http_redirect_to_https = false
+  enable_https_listener  = false
   
   target_groups = {
     web = {
@@ -143,6 +146,14 @@ module "alb" {
   }
 
   default_target_group_key = "web"
+
+  target_attachments = {
+    for index in range(var.web_server_count) : "web-${index + 1}" => {
+      target_group_key = "web"
+      target_id        = module.web_server[index].instance_id
+      port             = 80
+    }
+  }
 }
 
 module "web_server" {
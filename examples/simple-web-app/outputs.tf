@@ -17,3 +17,33 @@ output "assets_bucket_name" {
   description = "Assets bucket"
   value       = module.assets_bucket.bucket_id
 }
+
+output "vpc_id" {
+  description = "VPC ID"
+  value       = module.vpc.vpc_id
+}
+
+output "public_subnet_ids" {
+  description = "Public subnet IDs"
+  value       = module.vpc.public_subnet_ids
+}
+
+output "private_subnet_ids" {
+  description = "Private subnet IDs"
+  value       = module.vpc.private_subnet_ids
+}
+
+output "alb_arn" {
+  description = "ALB ARN"
+  value       = module.alb.alb_arn
+}
+
+output "web_target_group_arn" {
+  description = "Web target group ARN"
+  value       = module.alb.target_group_arns["web"]
+}
+
+output "web_server_instance_ids" {
+  description = "Web server instance IDs"
+  value       = module.web_server[*].instance_id
+}
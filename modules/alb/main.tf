@@ -181,6 +181,8 @@ resource "aws_lb_listener_rule" "host_based" {
       }
     }
   }
+
+  tags = var.tags
 }
 
 resource "aws_lb_target_group_attachment" "static" {
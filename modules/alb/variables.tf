@@ -76,6 +76,10 @@ variable "http_port" {
   description = "HTTP port"
   type        = number
   default     = 80
+  validation {
+    condition     = var.http_port >= 1 && var.http_port <= 65535
+    error_message = "HTTP port must be between 1 and 65535."
+  }
 }
 
 variable "http_redirect_to_https" {
@@ -94,6 +98,10 @@ variable "https_port" {
   description = "HTTPS port"
   type        = number
   default     = 443
+  validation {
+    condition     = var.https_port >= 1 && var.https_port <= 65535
+    error_message = "HTTPS port must be between 1 and 65535."
+  }
 }
 
 variable "ssl_policy" {
@@ -106,6 +114,10 @@ variable "certificate_arn" {
   description = "ARN of the SSL certificate"
   type        = string
   default     = null
+  validation {
+    condition     = var.certificate_arn == null ? true : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", var.certificate_arn))
+    error_message = "Certificate ARN must be a valid ARN."
+  }
 }
 
 variable "additional_certificates" {
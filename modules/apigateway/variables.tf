@@ -173,6 +173,10 @@ variable "certificate_arn" {
   description = "ARN of the ACM certificate"
   type        = string
   default     = null
+  validation {
+    condition     = var.certificate_arn == null ? true : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", var.certificate_arn))
+    error_message = "Certificate ARN must be a valid ARN."
+  }
 }
 
 variable "security_policy" {
@@ -228,6 +232,10 @@ variable "log_retention_days" {
   description = "CloudWatch log group retention in days"
   type        = number
   default     = 7
+  validation {
+    condition     = contains([0, 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653], var.log_retention_days)
+    error_message = "Log retention must be one of the retention periods CloudWatch Logs supports: 0 (never expire), 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288 or 3653."
+  }
 }
 
 variable "log_kms_key_id" {
@@ -240,6 +248,10 @@ variable "web_acl_arn" {
   description = "ARN of the WAFv2 Web ACL to associate"
   type        = string
   default     = null
+  validation {
+    condition     = var.web_acl_arn == null ? true : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", var.web_acl_arn))
+    error_message = "Web ACL ARN must be a valid ARN."
+  }
 }
 
 variable "tags" {
@@ -34,6 +34,10 @@ variable "price_class" {
   description = "Price class for the distribution"
   type        = string
   default     = "PriceClass_All"
+  validation {
+    condition     = contains(["PriceClass_All", "PriceClass_200", "PriceClass_100"], var.price_class)
+    error_message = "Price class must be PriceClass_All, PriceClass_200 or PriceClass_100."
+  }
 }
 
 variable "http_version" {
@@ -103,12 +107,20 @@ variable "acm_certificate_arn" {
   description = "ARN of the ACM certificate"
   type        = string
   default     = null
+  validation {
+    condition     = var.acm_certificate_arn == null ? true : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", var.acm_certificate_arn))
+    error_message = "ACM certificate ARN must be a valid ARN."
+  }
 }
 
 variable "ssl_support_method" {
   description = "SSL support method (sni-only or vip)"
   type        = string
   default     = "sni-only"
+  validation {
+    condition     = contains(["sni-only", "vip", "static-ip"], var.ssl_support_method)
+    error_message = "SSL support method must be sni-only, vip or static-ip."
+  }
 }
 
 variable "minimum_protocol_version" {
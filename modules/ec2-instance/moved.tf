@@ -0,0 +1,6 @@
+# aws_instance.main became count-gated when ignore_ami_changes moved to its
+# own resource, so existing state keeps its instance
+moved {
+  from = aws_instance.main
+  to   = aws_instance.main[0]
+}
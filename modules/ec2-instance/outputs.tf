@@ -1,21 +1,21 @@
 output "instance_id" {
   description = "ID of the EC2 instance"
-  value       = aws_instance.main.id
+  value       = local.instance.id
 }
 
 output "instance_arn" {
   description = "ARN of the EC2 instance"
-  value       = aws_instance.main.arn
+  value       = local.instance.arn
 }
 
 output "private_ip" {
   description = "Private IP address of the instance"
-  value       = aws_instance.main.private_ip
+  value       = local.instance.private_ip
 }
 
 output "public_ip" {
   description = "Public IP address of the instance"
-  value       = aws_instance.main.public_ip
+  value       = local.instance.public_ip
 }
 
 output "eip_public_ip" {
@@ -25,20 +25,20 @@ output "eip_public_ip" {
 
 output "instance_state" {
   description = "State of the instance"
-  value       = aws_instance.main.instance_state
+  value       = local.instance.instance_state
 }
 
 output "ami_id" {
   description = "AMI ID used for the instance"
-  value       = aws_instance.main.ami
+  value       = local.instance.ami
 }
 
 output "availability_zone" {
   description = "Availability zone of the instance"
-  value       = aws_instance.main.availability_zone
+  value       = local.instance.availability_zone
 }
 
 output "primary_network_interface_id" {
   description = "Primary network interface ID"
-  value       = aws_instance.main.primary_network_interface_id
+  value       = local.instance.primary_network_interface_id
 }
@@ -21,6 +21,10 @@ variable "kms_key_arn" {
   description = "ARN of the KMS key for encryption"
   type        = string
   default     = null
+  validation {
+    condition     = var.kms_key_arn == null ? true : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", var.kms_key_arn))
+    error_message = "KMS key ARN must be a valid ARN."
+  }
 }
 
 variable "scan_on_push" {
@@ -81,6 +85,10 @@ variable "log_retention_days" {
   description = "CloudWatch log group retention in days"
   type        = number
   default     = 7
+  validation {
+    condition     = contains([0, 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653], var.log_retention_days)
+    error_message = "Log retention must be one of the retention periods CloudWatch Logs supports: 0 (never expire), 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288 or 3653."
+  }
 }
 
 variable "log_kms_key_id" {
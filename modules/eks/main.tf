@@ -1,4 +1,6 @@
+data "aws_caller_identity" "current" {}
 data "aws_partition" "current" {}
+data "aws_region" "current" {}
 
 # EKS Cluster IAM Role
 resource "aws_iam_role" "cluster" {
@@ -213,9 +215,12 @@ resource "aws_eks_node_group" "main" {
 
   instance_types = each.value.instance_types
   capacity_type  = each.value.capacity_type
-  disk_size      = each.value.disk_size
   ami_type       = each.value.ami_type
 
+  # EKS rejects disk_size when a launch template is used, storage then comes
+  # from the template's block device mappings
+  disk_size = each.value.launch_template_id == null ? each.value.disk_size : null
+
   dynamic "remote_access" {
     for_each = each.value.key_name != null ? [1] : []
     content {
@@ -291,6 +296,11 @@ resource "aws_iam_role" "fargate" {
       Principal = {
         Service = "eks-fargate-pods.amazonaws.com"
       }
+      Condition = {
+        ArnLike = {
+          "aws:SourceArn" = "arn:${data.aws_partition.current.partition}:eks:${data.aws_region.current.name}:${data.aws_caller_identity.current.account_id}:fargateprofile/${var.cluster_name}/*"
+        }
+      }
     }]
   })
 
@@ -54,6 +54,11 @@ output "node_iam_role_name" {
   value       = aws_iam_role.node.name
 }
 
+output "fargate_pod_execution_role_arn" {
+  description = "IAM role ARN used by Fargate profiles to run pods"
+  value       = length(var.fargate_profiles) > 0 ? aws_iam_role.fargate[0].arn : null
+}
+
 output "oidc_provider_arn" {
   description = "ARN of the OIDC Provider for EKS"
   value       = var.enable_irsa ? aws_iam_openid_connect_provider.cluster[0].arn : null
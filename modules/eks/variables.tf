@@ -35,6 +35,10 @@ variable "public_access_cidrs" {
   description = "List of CIDR blocks that can access the public API server endpoint"
   type        = list(string)
   default     = ["0.0.0.0/0"]
+  validation {
+    condition     = alltrue([for cidr in var.public_access_cidrs : can(cidrhost(cidr, 0))])
+    error_message = "Public access CIDRs must all be valid CIDR blocks."
+  }
 }
 
 variable "enabled_cluster_log_types" {
@@ -47,6 +51,10 @@ variable "cloudwatch_log_retention_days" {
   description = "Number of days to retain CloudWatch logs"
   type        = number
   default     = 7
+  validation {
+    condition     = contains([0, 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653], var.cloudwatch_log_retention_days)
+    error_message = "Log retention must be one of the retention periods CloudWatch Logs supports: 0 (never expire), 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288 or 3653."
+  }
 }
 
 variable "cloudwatch_log_kms_key_id" {
@@ -58,6 +66,10 @@ variable "cloudwatch_log_kms_key_id" {
 variable "cluster_encryption_key_arn" {
   description = "KMS key ARN for cluster encryption"
   type        = string
+  validation {
+    condition     = can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", var.cluster_encryption_key_arn))
+    error_message = "Cluster encryption key ARN must be a valid ARN."
+  }
 }
 
 variable "enable_irsa" {
@@ -80,7 +92,7 @@ variable "node_groups" {
     min_size                   = number
     instance_types             = list(string)
     capacity_type              = string
-    disk_size                  = number
+    disk_size                  = optional(number)
     ami_type                   = string
     kubernetes_version         = optional(string)
     max_unavailable_percentage = optional(number, 33)
@@ -48,6 +48,8 @@ resource "aws_elasticache_replication_group" "redis" {
   engine_version             = var.engine_version
   node_type                  = var.node_type
   num_cache_clusters         = var.cluster_mode_enabled ? null : var.num_cache_clusters
+  num_node_groups            = var.cluster_mode_enabled ? var.num_node_groups : null
+  replicas_per_node_group    = var.cluster_mode_enabled ? var.replicas_per_node_group : null
   parameter_group_name       = var.create_parameter_group ? aws_elasticache_parameter_group.this[0].name : var.parameter_group_name
   port                       = var.port
   subnet_group_name          = var.create_subnet_group ? aws_elasticache_subnet_group.this[0].name : var.subnet_group_name
@@ -57,6 +59,7 @@ resource "aws_elasticache_replication_group" "redis" {
   at_rest_encryption_enabled = var.at_rest_encryption_enabled
   transit_encryption_enabled = var.transit_encryption_enabled
   auth_token                 = var.auth_token
+  user_group_ids             = var.user_group_id != null ? [aws_elasticache_user_group.this[0].user_group_id] : null
   kms_key_id                 = var.kms_key_id
   snapshot_retention_limit   = var.snapshot_retention_limit
   snapshot_window            = var.snapshot_window
@@ -78,6 +81,13 @@ resource "aws_elasticache_replication_group" "redis" {
   apply_immediately = var.apply_immediately
 
   tags = var.tags
+
+  lifecycle {
+    precondition {
+      condition     = !var.transit_encryption_enabled || var.auth_token != null || var.user_group_id != null
+      error_message = "Encryption in transit requires either auth_token or user_group_id to be set."
+    }
+  }
 }
 
 # ElastiCache Cluster (Memcached)
@@ -158,6 +168,8 @@ resource "aws_cloudwatch_metric_alarm" "cpu" {
   } : {
     CacheClusterId = var.cluster_id
   }
+
+  tags = var.tags
 }
 
 resource "aws_cloudwatch_metric_alarm" "memory" {
@@ -177,4 +189,6 @@ resource "aws_cloudwatch_metric_alarm" "memory" {
   dimensions = {
     ReplicationGroupId = var.replication_group_id
   }
+
+  tags = var.tags
 }
\ No newline at end of file
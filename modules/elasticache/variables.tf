@@ -56,6 +56,10 @@ variable "port" {
   description = "Port number on which the cache accepts connections"
   type        = number
   default     = null
+  validation {
+    condition     = var.port == null ? true : var.port >= 1 && var.port <= 65535
+    error_message = "Port must be between 1 and 65535."
+  }
 }
 
 variable "create_subnet_group" {
@@ -127,6 +131,18 @@ variable "cluster_mode_enabled" {
   default     = false
 }
 
+variable "num_node_groups" {
+  description = "Number of node groups (shards) when cluster mode is enabled (Redis)"
+  type        = number
+  default     = null
+}
+
+variable "replicas_per_node_group" {
+  description = "Number of replicas per node group when cluster mode is enabled (Redis)"
+  type        = number
+  default     = null
+}
+
 variable "at_rest_encryption_enabled" {
   description = "Enable encryption at rest"
   type        = bool
@@ -156,6 +172,10 @@ variable "snapshot_retention_limit" {
   description = "Number of days to retain snapshots"
   type        = number
   default     = 5
+  validation {
+    condition     = var.snapshot_retention_limit >= 0 && var.snapshot_retention_limit <= 35
+    error_message = "Snapshot retention limit must be between 0 and 35 days."
+  }
 }
 
 variable "snapshot_window" {
@@ -174,6 +194,10 @@ variable "notification_topic_arn" {
   description = "ARN of SNS topic for notifications"
   type        = string
   default     = null
+  validation {
+    condition     = var.notification_topic_arn == null ? true : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", var.notification_topic_arn))
+    error_message = "Notification topic ARN must be a valid ARN."
+  }
 }
 
 variable "auto_minor_version_upgrade" {
@@ -192,6 +216,10 @@ variable "az_mode" {
   description = "AZ mode (single-az or cross-az) for Memcached"
   type        = string
   default     = "single-az"
+  validation {
+    condition     = contains(["single-az", "cross-az"], var.az_mode)
+    error_message = "AZ mode must be single-az or cross-az."
+  }
 }
 
 variable "preferred_availability_zones" {
@@ -261,6 +289,10 @@ variable "alarm_sns_topic_arns" {
   description = "List of SNS topic ARNs for alarm notifications"
   type        = list(string)
   default     = []
+  validation {
+    condition     = alltrue([for arn in var.alarm_sns_topic_arns : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", arn))])
+    error_message = "Alarm SNS topic ARNs must all be valid ARNs."
+  }
 }
 
 variable "tags" {
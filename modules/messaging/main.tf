@@ -126,10 +126,12 @@ resource "aws_sns_topic_subscription" "sqs" {
 }
 
 # SQS Queue Policy for SNS
+# One policy per queue, since a queue subscribed to several topics can only
+# hold a single policy
 resource "aws_sqs_queue_policy" "sns_publish" {
-  for_each = var.sns_to_sqs_subscriptions
+  for_each = toset([for subscription in var.sns_to_sqs_subscriptions : subscription.queue_name])
 
-  queue_url = aws_sqs_queue.this[each.value.queue_name].url
+  queue_url = aws_sqs_queue.this[each.key].url
 
   policy = jsonencode({
     Version = "2012-10-17"
@@ -139,10 +141,13 @@ resource "aws_sqs_queue_policy" "sns_publish" {
         Service = "sns.amazonaws.com"
       }
       Action   = "sqs:SendMessage"
-      Resource = aws_sqs_queue.this[each.value.queue_name].arn
+      Resource = aws_sqs_queue.this[each.key].arn
       Condition = {
         ArnEquals = {
-          "aws:SourceArn" = aws_sns_topic.this[each.value.topic_name].arn
+          "aws:SourceArn" = distinct([
+            for subscription in var.sns_to_sqs_subscriptions : aws_sns_topic.this[subscription.topic_name].arn
+            if subscription.queue_name == each.key
+          ])
         }
       }
     }]
@@ -167,6 +172,8 @@ resource "aws_cloudwatch_metric_alarm" "sqs_age" {
   dimensions = {
     QueueName = each.key
   }
+
+  tags = var.tags
 }
 
 resource "aws_cloudwatch_metric_alarm" "sqs_depth" {
@@ -186,4 +193,6 @@ resource "aws_cloudwatch_metric_alarm" "sqs_depth" {
   dimensions = {
     QueueName = each.key
   }
+
+  tags = var.tags
 }
\ No newline at end of file
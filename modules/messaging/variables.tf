@@ -113,6 +113,10 @@ variable "alarm_sns_topic_arns" {
   description = "List of SNS topic ARNs for alarm notifications"
   type        = list(string)
   default     = []
+  validation {
+    condition     = alltrue([for arn in var.alarm_sns_topic_arns : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", arn))])
+    error_message = "Alarm SNS topic ARNs must all be valid ARNs."
+  }
 }
 
 variable "tags" {
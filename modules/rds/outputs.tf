@@ -52,3 +52,13 @@ output "read_replica_addresses" {
   description = "Hostnames of read replicas"
   value       = aws_db_instance.replica[*].address
 }
+
+output "db_option_group_name" {
+  description = "DB option group name"
+  value       = var.option_group_name != null ? var.option_group_name : (length(var.db_options) > 0 ? aws_db_option_group.main[0].name : null)
+}
+
+output "read_replica_ids" {
+  description = "Identifiers of read replicas"
+  value       = aws_db_instance.replica[*].identifier
+}
@@ -82,6 +82,10 @@ variable "database_port" {
   description = "Database port"
   type        = number
   default     = 5432
+  validation {
+    condition     = var.database_port >= 1 && var.database_port <= 65535
+    error_message = "Database port must be between 1 and 65535."
+  }
 }
 
 variable "subnet_ids" {
@@ -156,6 +160,10 @@ variable "backup_retention_period" {
   description = "Backup retention period in days"
   type        = number
   default     = 7
+  validation {
+    condition     = var.backup_retention_period >= 0 && var.backup_retention_period <= 35
+    error_message = "Backup retention period must be between 0 and 35 days."
+  }
 }
 
 variable "backup_window" {
@@ -186,6 +194,10 @@ variable "monitoring_role_arn" {
   description = "IAM role ARN for enhanced monitoring"
   type        = string
   default     = null
+  validation {
+    condition     = var.monitoring_role_arn == null ? true : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", var.monitoring_role_arn))
+    error_message = "Monitoring role ARN must be a valid ARN."
+  }
 }
 
 variable "performance_insights_enabled" {
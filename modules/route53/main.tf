@@ -10,9 +10,15 @@ terraform {
   }
 }
 
+locals {
+  associated_zone_names = toset([for association in var.zone_associations : association.zone_name])
+
+  zones = merge(aws_route53_zone.this, aws_route53_zone.associated)
+}
+
 # Route53 Hosted Zone
 resource "aws_route53_zone" "this" {
-  for_each = var.hosted_zones
+  for_each = { for name, zone in var.hosted_zones : name => zone if !contains(local.associated_zone_names, name) }
 
   name              = each.key
   comment           = lookup(each.value, "comment", null)
@@ -20,7 +26,7 @@ resource "aws_route53_zone" "this" {
   delegation_set_id = lookup(each.value, "delegation_set_id", null)
 
   dynamic "vpc" {
-    for_each = lookup(each.value, "vpcs", [])
+    for_each = each.value.vpcs
     content {
       vpc_id     = vpc.value.vpc_id
       vpc_region = lookup(vpc.value, "vpc_region", null)
@@ -30,18 +36,45 @@ resource "aws_route53_zone" "this" {
   tags = var.tags
 }
 
+# Private zones that zone_associations adds VPCs to. The provider would
+# remove those associations from the inline vpc blocks on every apply, and
+# ignore_changes only takes a static list, so they are a separate resource.
+resource "aws_route53_zone" "associated" {
+  for_each = { for name, zone in var.hosted_zones : name => zone if contains(local.associated_zone_names, name) }
+
+  name              = each.key
+  comment           = lookup(each.value, "comment", null)
+  force_destroy     = lookup(each.value, "force_destroy", false)
+  delegation_set_id = lookup(each.value, "delegation_set_id", null)
+
+  dynamic "vpc" {
+    for_each = each.value.vpcs
+    content {
+      vpc_id     = vpc.value.vpc_id
+      vpc_region = lookup(vpc.value, "vpc_region", null)
+    }
+  }
+
+  tags = var.tags
+
+  lifecycle {
+    ignore_changes = [vpc]
+  }
+}
+
 # Route53 Records
 resource "aws_route53_record" "this" {
   for_each = var.records
 
-  zone_id = aws_route53_zone.this[each.value.zone_name].zone_id
+  zone_id = local.zones[each.value.zone_name].zone_id
   name    = each.value.name
   type    = each.value.type
   ttl     = lookup(each.value, "ttl", null)
   records = lookup(each.value, "records", null)
 
   set_identifier = lookup(each.value, "set_identifier", null)
-  health_check_id = lookup(each.value, "health_check_id", null)
+  # health_check_id may name a health check created by this module or be an existing health check ID
+  health_check_id = contains(keys(var.health_checks), coalesce(each.value.health_check_id, "-")) ? aws_route53_health_check.this[each.value.health_check_id].id : each.value.health_check_id
   multivalue_answer_routing_policy = lookup(each.value, "multivalue_answer_routing_policy", null)
   allow_overwrite = lookup(each.value, "allow_overwrite", false)
 
@@ -123,7 +156,7 @@ resource "aws_route53_health_check" "this" {
 resource "aws_route53_query_log" "this" {
   for_each = var.query_logs
 
-  zone_id                  = aws_route53_zone.this[each.key].zone_id
+  zone_id                  = local.zones[each.key].zone_id
   cloudwatch_log_group_arn = each.value.cloudwatch_log_group_arn
 }
 
@@ -143,7 +176,7 @@ resource "aws_route53_traffic_policy_instance" "this" {
   name                   = each.value.name
   traffic_policy_id      = aws_route53_traffic_policy.this[each.value.traffic_policy_name].id
   traffic_policy_version = each.value.traffic_policy_version
-  hosted_zone_id         = aws_route53_zone.this[each.value.zone_name].zone_id
+  hosted_zone_id         = local.zones[each.value.zone_name].zone_id
   ttl                    = each.value.ttl
 }
 
@@ -158,7 +191,7 @@ resource "aws_route53_delegation_set" "this" {
 resource "aws_route53_vpc_association_authorization" "this" {
   for_each = var.vpc_association_authorizations
 
-  zone_id = aws_route53_zone.this[each.value.zone_name].zone_id
+  zone_id = local.zones[each.value.zone_name].zone_id
   vpc_id  = each.value.vpc_id
 }
 
@@ -166,7 +199,7 @@ resource "aws_route53_vpc_association_authorization" "this" {
 resource "aws_route53_zone_association" "this" {
   for_each = var.zone_associations
 
-  zone_id = aws_route53_zone.this[each.value.zone_name].zone_id
+  zone_id = local.zones[each.value.zone_name].zone_id
   vpc_id  = each.value.vpc_id
 }
 
@@ -188,4 +221,6 @@ resource "aws_cloudwatch_metric_alarm" "health_check" {
   dimensions = {
     HealthCheckId = aws_route53_health_check.this[each.key].id
   }
+
+  tags = var.tags
 }
\ No newline at end of file
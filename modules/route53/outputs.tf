@@ -2,12 +2,12 @@
 
 output "zone_ids" {
   description = "Map of hosted zone names to zone IDs"
-  value       = { for k, v in aws_route53_zone.this : k => v.zone_id }
+  value       = { for k, v in local.zones : k => v.zone_id }
 }
 
 output "zone_name_servers" {
   description = "Map of hosted zone names to name servers"
-  value       = { for k, v in aws_route53_zone.this : k => v.name_servers }
+  value       = { for k, v in local.zones : k => v.name_servers }
 }
 
 output "health_check_ids" {
@@ -9,7 +9,7 @@ variable "hosted_zones" {
     vpcs = optional(list(object({
       vpc_id     = string
       vpc_region = optional(string)
-    })))
+    })), [])
   }))
   default = {}
 }
@@ -123,7 +123,7 @@ variable "vpc_association_authorizations" {
 }
 
 variable "zone_associations" {
-  description = "Map of zone associations"
+  description = "Map of extra VPC associations for private zones. Zones named here ignore later changes to their inline vpcs"
   type = map(object({
     zone_name = string
     vpc_id    = string
@@ -141,6 +141,10 @@ variable "alarm_sns_topic_arns" {
   description = "List of SNS topic ARNs for alarm notifications"
   type        = list(string)
   default     = []
+  validation {
+    condition     = alltrue([for arn in var.alarm_sns_topic_arns : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", arn))])
+    error_message = "Alarm SNS topic ARNs must all be valid ARNs."
+  }
 }
 
 variable "tags" {
@@ -58,17 +58,16 @@ resource "aws_s3_bucket_lifecycle_configuration" "main" {
         }
       }
 
-      dynamic "filter" {
-        for_each = rule.value.prefix != null || rule.value.tags != null ? [1] : []
-        content {
-          prefix = rule.value.prefix
-
-          dynamic "tag" {
-            for_each = rule.value.tags != null ? rule.value.tags : {}
-            content {
-              key   = tag.key
-              value = tag.value
-            }
+      # A filter can hold a single prefix, or an "and" block once tags are
+      # involved. An empty filter applies the rule to the whole bucket.
+      filter {
+        prefix = rule.value.tags == null ? rule.value.prefix : null
+
+        dynamic "and" {
+          for_each = rule.value.tags != null ? [1] : []
+          content {
+            prefix = rule.value.prefix
+            tags   = rule.value.tags
           }
         }
       }
@@ -10,6 +10,10 @@ terraform {
   }
 }
 
+data "aws_caller_identity" "current" {}
+data "aws_partition" "current" {}
+data "aws_region" "current" {}
+
 # Step Functions State Machine
 resource "aws_sfn_state_machine" "this" {
   name       = var.name
@@ -34,6 +38,8 @@ resource "aws_sfn_state_machine" "this" {
   }
 
   tags = var.tags
+
+  depends_on = [aws_iam_role_policy.sfn_logging]
 }
 
 # IAM Role for Step Functions
@@ -56,6 +62,11 @@ data "aws_iam_policy_document" "sfn_assume_role" {
       identifiers = ["states.amazonaws.com"]
     }
     actions = ["sts:AssumeRole"]
+    condition {
+      test     = "ArnLike"
+      variable = "aws:SourceArn"
+      values   = ["arn:${data.aws_partition.current.partition}:states:${data.aws_region.current.name}:${data.aws_caller_identity.current.account_id}:stateMachine:${var.name}"]
+    }
   }
 }
 
@@ -68,6 +79,35 @@ resource "aws_iam_role_policy" "sfn_custom" {
   policy = var.custom_policies[count.index]
 }
 
+# Log delivery permissions, required when logging_configuration is set
+resource "aws_iam_role_policy" "sfn_logging" {
+  count = var.create_role && var.logging_configuration != null ? 1 : 0
+
+  name   = "${var.name}-logging-policy"
+  role   = aws_iam_role.sfn[0].id
+  policy = data.aws_iam_policy_document.sfn_logging[0].json
+}
+
+data "aws_iam_policy_document" "sfn_logging" {
+  count = var.create_role && var.logging_configuration != null ? 1 : 0
+
+  # Log delivery APIs don't support resource-level permissions
+  statement {
+    effect = "Allow"
+    actions = [
+      "logs:CreateLogDelivery",
+      "logs:GetLogDelivery",
+      "logs:UpdateLogDelivery",
+      "logs:DeleteLogDelivery",
+      "logs:ListLogDeliveries",
+      "logs:PutResourcePolicy",
+      "logs:DescribeResourcePolicies",
+      "logs:DescribeLogGroups"
+    ]
+    resources = ["*"]
+  }
+}
+
 # CloudWatch Log Group
 resource "aws_cloudwatch_log_group" "sfn" {
   count = var.create_log_group ? 1 : 0
@@ -97,6 +137,8 @@ resource "aws_cloudwatch_metric_alarm" "execution_failed" {
   dimensions = {
     StateMachineArn = aws_sfn_state_machine.this.arn
   }
+
+  tags = var.tags
 }
 
 resource "aws_cloudwatch_metric_alarm" "execution_throttled" {
@@ -116,6 +158,8 @@ resource "aws_cloudwatch_metric_alarm" "execution_throttled" {
   dimensions = {
     StateMachineArn = aws_sfn_state_machine.this.arn
   }
+
+  tags = var.tags
 }
 
 # EventBridge Rule to trigger Step Functions
@@ -161,6 +205,11 @@ data "aws_iam_policy_document" "eventbridge_assume_role" {
       identifiers = ["events.amazonaws.com"]
     }
     actions = ["sts:AssumeRole"]
+    condition {
+      test     = "ArnLike"
+      variable = "aws:SourceArn"
+      values   = ["arn:${data.aws_partition.current.partition}:events:${data.aws_region.current.name}:${data.aws_caller_identity.current.account_id}:rule/${var.name}-*"]
+    }
   }
 }
 
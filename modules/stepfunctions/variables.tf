@@ -26,6 +26,10 @@ variable "role_arn" {
   description = "ARN of existing IAM role (if create_role is false)"
   type        = string
   default     = null
+  validation {
+    condition     = var.role_arn == null ? true : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", var.role_arn))
+    error_message = "Role ARN must be a valid ARN."
+  }
 }
 
 variable "custom_policies" {
@@ -59,6 +63,10 @@ variable "log_retention_days" {
   description = "CloudWatch log group retention in days"
   type        = number
   default     = 7
+  validation {
+    condition     = contains([0, 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653], var.log_retention_days)
+    error_message = "Log retention must be one of the retention periods CloudWatch Logs supports: 0 (never expire), 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288 or 3653."
+  }
 }
 
 variable "log_kms_key_id" {
@@ -77,6 +85,10 @@ variable "alarm_sns_topic_arns" {
   description = "List of SNS topic ARNs for alarm notifications"
   type        = list(string)
   default     = []
+  validation {
+    condition     = alltrue([for arn in var.alarm_sns_topic_arns : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", arn))])
+    error_message = "Alarm SNS topic ARNs must all be valid ARNs."
+  }
 }
 
 variable "event_triggers" {
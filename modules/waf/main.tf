@@ -1,4 +1,4 @@
-# modules/cloudfront/main.tf
+# modules/waf/main.tf
 
 terraform {
   required_version = ">= 1.0"
@@ -10,243 +10,278 @@ terraform {
   }
 }
 
-# CloudFront Distribution
-resource "aws_cloudfront_distribution" "this" {
-  enabled             = var.enabled
-  is_ipv6_enabled     = var.is_ipv6_enabled
-  comment             = var.comment
-  default_root_object = var.default_root_object
-  aliases             = var.aliases
-  price_class         = var.price_class
-  http_version        = var.http_version
-  web_acl_id          = var.web_acl_id
-  retain_on_delete    = var.retain_on_delete
-  wait_for_deployment = var.wait_for_deployment
-
-  dynamic "origin" {
-    for_each = var.origins
-    content {
-      domain_name              = origin.value.domain_name
-      origin_id                = origin.value.origin_id
-      origin_path              = lookup(origin.value, "origin_path", "")
-      connection_attempts      = lookup(origin.value, "connection_attempts", 3)
-      connection_timeout       = lookup(origin.value, "connection_timeout", 10)
-      origin_access_control_id = lookup(origin.value, "origin_access_control_id", null)
-
-      dynamic "custom_origin_config" {
-        for_each = lookup(origin.value, "custom_origin_config", null) != null ? [origin.value.custom_origin_config] : []
-        content {
-          http_port                = lookup(custom_origin_config.value, "http_port", 80)
-          https_port               = lookup(custom_origin_config.value, "https_port", 443)
-          origin_protocol_policy   = custom_origin_config.value.origin_protocol_policy
-          origin_ssl_protocols     = lookup(custom_origin_config.value, "origin_ssl_protocols", ["TLSv1.2"])
-          origin_keepalive_timeout = lookup(custom_origin_config.value, "origin_keepalive_timeout", 5)
-          origin_read_timeout      = lookup(custom_origin_config.value, "origin_read_timeout", 30)
-        }
-      }
-
-      dynamic "s3_origin_config" {
-        for_each = lookup(origin.value, "s3_origin_config", null) != null ? [origin.value.s3_origin_config] : []
-        content {
-          origin_access_identity = lookup(s3_origin_config.value, "origin_access_identity", null)
-        }
-      }
+data "aws_region" "current" {}
 
-      dynamic "custom_header" {
-        for_each = lookup(origin.value, "custom_headers", [])
-        content {
-          name  = custom_header.value.name
-          value = custom_header.value.value
-        }
-      }
+# IP Sets
+resource "aws_wafv2_ip_set" "this" {
+  for_each = var.ip_sets
 
-      dynamic "origin_shield" {
-        for_each = lookup(origin.value, "origin_shield", null) != null ? [origin.value.origin_shield] : []
-        content {
-          enabled              = origin_shield.value.enabled
-          origin_shield_region = origin_shield.value.origin_shield_region
-        }
-      }
-    }
-  }
+  name               = each.key
+  description        = lookup(each.value, "description", null)
+  scope              = var.scope
+  ip_address_version = each.value.ip_address_version
+  addresses          = each.value.addresses
 
-  dynamic "origin_group" {
-    for_each = var.origin_groups
-    content {
-      origin_id = origin_group.value.origin_id
+  tags = var.tags
+}
 
-      failover_criteria {
-        status_codes = origin_group.value.failover_status_codes
-      }
+# Regex Pattern Sets
+resource "aws_wafv2_regex_pattern_set" "this" {
+  for_each = var.regex_pattern_sets
 
-      member {
-        origin_id = origin_group.value.primary_member_origin_id
-      }
+  name        = each.key
+  description = lookup(each.value, "description", null)
+  scope       = var.scope
 
-      member {
-        origin_id = origin_group.value.secondary_member_origin_id
-      }
+  dynamic "regular_expression" {
+    for_each = each.value.patterns
+    content {
+      regex_string = regular_expression.value
     }
   }
 
-  default_cache_behavior {
-    allowed_methods        = var.default_cache_behavior.allowed_methods
-    cached_methods         = var.default_cache_behavior.cached_methods
-    target_origin_id       = var.default_cache_behavior.target_origin_id
-    viewer_protocol_policy = var.default_cache_behavior.viewer_protocol_policy
-    compress               = lookup(var.default_cache_behavior, "compress", true)
-    cache_policy_id        = lookup(var.default_cache_behavior, "cache_policy_id", null)
-    origin_request_policy_id = lookup(var.default_cache_behavior, "origin_request_policy_id", null)
-    response_headers_policy_id = lookup(var.default_cache_behavior, "response_headers_policy_id", null)
-    realtime_log_config_arn = lookup(var.default_cache_behavior, "realtime_log_config_arn", null)
-    field_level_encryption_id = lookup(var.default_cache_behavior, "field_level_encryption_id", null)
-    smooth_streaming = lookup(var.default_cache_behavior, "smooth_streaming", false)
-    trusted_key_groups = lookup(var.default_cache_behavior, "trusted_key_groups", [])
-    trusted_signers = lookup(var.default_cache_behavior, "trusted_signers", [])
-
-    dynamic "forwarded_values" {
-      for_each = lookup(var.default_cache_behavior, "forwarded_values", null) != null ? [var.default_cache_behavior.forwarded_values] : []
-      content {
-        query_string = forwarded_values.value.query_string
-        headers      = lookup(forwarded_values.value, "headers", [])
+  tags = var.tags
+}
 
-        cookies {
-          forward           = forwarded_values.value.cookies_forward
-          whitelisted_names = lookup(forwarded_values.value, "cookies_whitelisted_names", [])
-        }
-      }
-    }
+# WAF Web ACL
+resource "aws_wafv2_web_acl" "this" {
+  name        = var.name
+  description = var.description
+  scope       = var.scope
 
-    dynamic "function_association" {
-      for_each = lookup(var.default_cache_behavior, "function_associations", [])
-      content {
-        event_type   = function_association.value.event_type
-        function_arn = function_association.value.function_arn
-      }
+  default_action {
+    dynamic "allow" {
+      for_each = var.default_action == "allow" ? [1] : []
+      content {}
     }
 
-    dynamic "lambda_function_association" {
-      for_each = lookup(var.default_cache_behavior, "lambda_function_associations", [])
+    dynamic "block" {
+      for_each = var.default_action == "block" ? [1] : []
       content {
-        event_type   = lambda_function_association.value.event_type
-        lambda_arn   = lambda_function_association.value.lambda_arn
-        include_body = lookup(lambda_function_association.value, "include_body", false)
+        dynamic "custom_response" {
+          for_each = var.default_block_custom_response != null ? [var.default_block_custom_response] : []
+          content {
+            response_code = custom_response.value.response_code
+
+            dynamic "response_header" {
+              for_each = coalesce(custom_response.value.response_headers, [])
+              content {
+                name  = response_header.value.name
+                value = response_header.value.value
+              }
+            }
+          }
+        }
       }
     }
-
-    min_ttl     = lookup(var.default_cache_behavior, "min_ttl", null)
-    default_ttl = lookup(var.default_cache_behavior, "default_ttl", null)
-    max_ttl     = lookup(var.default_cache_behavior, "max_ttl", null)
   }
 
-  dynamic "ordered_cache_behavior" {
-    for_each = var.ordered_cache_behaviors
+  dynamic "rule" {
+    for_each = var.rules
     content {
-      path_pattern           = ordered_cache_behavior.value.path_pattern
-      allowed_methods        = ordered_cache_behavior.value.allowed_methods
-      cached_methods         = ordered_cache_behavior.value.cached_methods
-      target_origin_id       = ordered_cache_behavior.value.target_origin_id
-      viewer_protocol_policy = ordered_cache_behavior.value.viewer_protocol_policy
-      compress               = lookup(ordered_cache_behavior.value, "compress", true)
-      cache_policy_id        = lookup(ordered_cache_behavior.value, "cache_policy_id", null)
-      origin_request_policy_id = lookup(ordered_cache_behavior.value, "origin_request_policy_id", null)
-      response_headers_policy_id = lookup(ordered_cache_behavior.value, "response_headers_policy_id", null)
-      realtime_log_config_arn = lookup(ordered_cache_behavior.value, "realtime_log_config_arn", null)
-      field_level_encryption_id = lookup(ordered_cache_behavior.value, "field_level_encryption_id", null)
-      smooth_streaming = lookup(ordered_cache_behavior.value, "smooth_streaming", false)
-      trusted_key_groups = lookup(ordered_cache_behavior.value, "trusted_key_groups", [])
-      trusted_signers = lookup(ordered_cache_behavior.value, "trusted_signers", [])
-
-      dynamic "forwarded_values" {
-        for_each = lookup(ordered_cache_behavior.value, "forwarded_values", null) != null ? [ordered_cache_behavior.value.forwarded_values] : []
+      name     = rule.value.name
+      priority = rule.value.priority
+
+      # Plain rules take an action, rule group references take an override_action
+      dynamic "action" {
+        for_each = rule.value.action != null ? [lower(rule.value.action)] : []
         content {
-          query_string = forwarded_values.value.query_string
-          headers      = lookup(forwarded_values.value, "headers", [])
+          dynamic "allow" {
+            for_each = action.value == "allow" ? [1] : []
+            content {}
+          }
+
+          dynamic "block" {
+            for_each = action.value == "block" ? [1] : []
+            content {
+              dynamic "custom_response" {
+                for_each = rule.value.custom_response != null ? [rule.value.custom_response] : []
+                content {
+                  response_code = custom_response.value.response_code
+
+                  dynamic "response_header" {
+                    for_each = coalesce(custom_response.value.response_headers, [])
+                    content {
+                      name  = response_header.value.name
+                      value = response_header.value.value
+                    }
+                  }
+                }
+              }
+            }
+          }
+
+          dynamic "count" {
+            for_each = action.value == "count" ? [1] : []
+            content {}
+          }
 
-          cookies {
-            forward           = forwarded_values.value.cookies_forward
-            whitelisted_names = lookup(forwarded_values.value, "cookies_whitelisted_names", [])
+          dynamic "captcha" {
+            for_each = action.value == "captcha" ? [1] : []
+            content {}
+          }
+
+          dynamic "challenge" {
+            for_each = action.value == "challenge" ? [1] : []
+            content {}
           }
         }
       }
 
-      dynamic "function_association" {
-        for_each = lookup(ordered_cache_behavior.value, "function_associations", [])
+      dynamic "override_action" {
+        for_each = rule.value.override_action != null ? [lower(rule.value.override_action)] : []
         content {
-          event_type   = function_association.value.event_type
-          function_arn = function_association.value.function_arn
+          dynamic "none" {
+            for_each = override_action.value == "none" ? [1] : []
+            content {}
+          }
+
+          dynamic "count" {
+            for_each = override_action.value == "count" ? [1] : []
+            content {}
+          }
         }
       }
 
-      dynamic "lambda_function_association" {
-        for_each = lookup(ordered_cache_behavior.value, "lambda_function_associations", [])
-        content {
-          event_type   = lambda_function_association.value.event_type
-          lambda_arn   = lambda_function_association.value.lambda_arn
-          include_body = lookup(lambda_function_association.value, "include_body", false)
+      statement {
+        dynamic "managed_rule_group_statement" {
+          for_each = rule.value.statement.managed_rule_group_statement != null ? [rule.value.statement.managed_rule_group_statement] : []
+          content {
+            name        = managed_rule_group_statement.value.name
+            vendor_name = managed_rule_group_statement.value.vendor_name
+            version     = managed_rule_group_statement.value.version
+
+            dynamic "rule_action_override" {
+              for_each = managed_rule_group_statement.value.excluded_rules
+              content {
+                name = rule_action_override.value
+                action_to_use {
+                  count {}
+                }
+              }
+            }
+          }
+        }
+
+        dynamic "rate_based_statement" {
+          for_each = rule.value.statement.rate_based_statement != null ? [rule.value.statement.rate_based_statement] : []
+          content {
+            limit              = rate_based_statement.value.limit
+            aggregate_key_type = rate_based_statement.value.aggregate_key_type
+          }
+        }
+
+        dynamic "ip_set_reference_statement" {
+          for_each = rule.value.statement.ip_set_reference_statement != null ? [rule.value.statement.ip_set_reference_statement] : []
+          content {
+            arn = contains(keys(var.ip_sets), ip_set_reference_statement.value.arn) ? aws_wafv2_ip_set.this[ip_set_reference_statement.value.arn].arn : ip_set_reference_statement.value.arn
+          }
+        }
+
+        dynamic "regex_pattern_set_reference_statement" {
+          for_each = rule.value.statement.regex_pattern_set_reference_statement != null ? [rule.value.statement.regex_pattern_set_reference_statement] : []
+          content {
+            arn = contains(keys(var.regex_pattern_sets), regex_pattern_set_reference_statement.value.arn) ? aws_wafv2_regex_pattern_set.this[regex_pattern_set_reference_statement.value.arn].arn : regex_pattern_set_reference_statement.value.arn
+
+            field_to_match {
+              dynamic "uri_path" {
+                for_each = regex_pattern_set_reference_statement.value.field_to_match.uri_path ? [1] : []
+                content {}
+              }
+
+              dynamic "query_string" {
+                for_each = regex_pattern_set_reference_statement.value.field_to_match.query_string ? [1] : []
+                content {}
+              }
+
+              dynamic "single_header" {
+                for_each = regex_pattern_set_reference_statement.value.field_to_match.single_header != null ? [regex_pattern_set_reference_statement.value.field_to_match.single_header] : []
+                content {
+                  name = single_header.value
+                }
+              }
+            }
+
+            dynamic "text_transformation" {
+              for_each = regex_pattern_set_reference_statement.value.text_transformations
+              content {
+                priority = text_transformation.value.priority
+                type     = text_transformation.value.type
+              }
+            }
+          }
+        }
+
+        dynamic "geo_match_statement" {
+          for_each = rule.value.statement.geo_match_statement != null ? [rule.value.statement.geo_match_statement] : []
+          content {
+            country_codes = geo_match_statement.value.country_codes
+          }
         }
       }
 
-      min_ttl     = lookup(ordered_cache_behavior.value, "min_ttl", null)
-      default_ttl = lookup(ordered_cache_behavior.value, "default_ttl", null)
-      max_ttl     = lookup(ordered_cache_behavior.value, "max_ttl", null)
+      visibility_config {
+        cloudwatch_metrics_enabled = coalesce(rule.value.cloudwatch_metrics_enabled, var.cloudwatch_metrics_enabled)
+        metric_name                = rule.value.name
+        sampled_requests_enabled   = coalesce(rule.value.sampled_requests_enabled, var.sampled_requests_enabled)
+      }
     }
   }
 
-  restrictions {
-    geo_restriction {
-      restriction_type = var.geo_restriction_type
-      locations        = var.geo_restriction_locations
-    }
+  visibility_config {
+    cloudwatch_metrics_enabled = var.cloudwatch_metrics_enabled
+    metric_name                = var.name
+    sampled_requests_enabled   = var.sampled_requests_enabled
   }
 
-  viewer_certificate {
-    acm_certificate_arn            = var.acm_certificate_arn
-    ssl_support_method             = var.acm_certificate_arn != null ? var.ssl_support_method : null
-    minimum_protocol_version       = var.minimum_protocol_version
-    cloudfront_default_certificate = var.acm_certificate_arn == null
-  }
+  tags = var.tags
 
-  dynamic "logging_config" {
-    for_each = var.logging_enabled ? [1] : []
-    content {
-      bucket          = var.logging_bucket
-      prefix          = var.logging_prefix
-      include_cookies = var.logging_include_cookies
+  lifecycle {
+    precondition {
+      condition     = var.scope != "CLOUDFRONT" || data.aws_region.current.name == "us-east-1"
+      error_message = "CLOUDFRONT scoped Web ACLs must be created in us-east-1."
     }
   }
+}
 
-  dynamic "custom_error_response" {
-    for_each = var.custom_error_responses
-    content {
-      error_code            = custom_error_response.value.error_code
-      response_code         = lookup(custom_error_response.value, "response_code", null)
-      response_page_path    = lookup(custom_error_response.value, "response_page_path", null)
-      error_caching_min_ttl = lookup(custom_error_response.value, "error_caching_min_ttl", null)
-    }
-  }
+# CloudWatch Log Group
+# WAF only delivers to log groups whose name starts with aws-waf-logs-
+resource "aws_cloudwatch_log_group" "waf" {
+  count = var.create_log_group ? 1 : 0
+
+  name              = "aws-waf-logs-${var.name}"
+  retention_in_days = var.log_retention_days
+  kms_key_id        = var.log_kms_key_id
 
   tags = var.tags
 }
 
-# Origin Access Control (OAC) for S3
-resource "aws_cloudfront_origin_access_control" "this" {
-  for_each = var.origin_access_controls
+# WAF Logging Configuration
+resource "aws_wafv2_web_acl_logging_configuration" "this" {
+  count = var.logging_configuration != null ? 1 : 0
 
-  name                              = each.key
-  description                       = lookup(each.value, "description", "")
-  origin_access_control_origin_type = lookup(each.value, "origin_type", "s3")
-  signing_behavior                  = lookup(each.value, "signing_behavior", "always")
-  signing_protocol                  = lookup(each.value, "signing_protocol", "sigv4")
-}
+  resource_arn            = aws_wafv2_web_acl.this.arn
+  log_destination_configs = var.create_log_group ? [aws_cloudwatch_log_group.waf[0].arn] : var.logging_configuration.log_destination_configs
 
-# CloudFront Function
-resource "aws_cloudfront_function" "this" {
-  for_each = var.cloudfront_functions
+  dynamic "redacted_fields" {
+    for_each = coalesce(var.logging_configuration.redacted_fields, [])
+    content {
+      dynamic "single_header" {
+        for_each = redacted_fields.value.single_header != null ? [redacted_fields.value.single_header] : []
+        content {
+          name = single_header.value
+        }
+      }
+
+      dynamic "uri_path" {
+        for_each = coalesce(redacted_fields.value.uri_path, false) ? [1] : []
+        content {}
+      }
 
-  name    = each.key
-  runtime = lookup(each.value, "runtime", "cloudfront-js-1.0")
-  comment = lookup(each.value, "comment", "")
-  code    = each.value.code
-  publish = lookup(each.value, "publish", true)
-}
\ No newline at end of file
+      dynamic "query_string" {
+        for_each = coalesce(redacted_fields.value.query_string, false) ? [1] : []
+        content {}
+      }
+    }
+  }
+}
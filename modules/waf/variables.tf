@@ -42,11 +42,42 @@ variable "default_block_custom_response" {
 variable "rules" {
   description = "List of WAF rules"
   type = list(object({
-    name     = string
-    priority = number
-    action   = optional(string)
+    name            = string
+    priority        = number
+    action          = optional(string)
     override_action = optional(string)
-    statement = any
+    statement = object({
+      managed_rule_group_statement = optional(object({
+        name           = string
+        vendor_name    = optional(string, "AWS")
+        version        = optional(string)
+        excluded_rules = optional(list(string), [])
+      }))
+      rate_based_statement = optional(object({
+        limit              = number
+        aggregate_key_type = optional(string, "IP")
+      }))
+      # arn may be the key of an IP set in ip_sets
+      ip_set_reference_statement = optional(object({
+        arn = string
+      }))
+      # arn may be the key of a regex pattern set in regex_pattern_sets
+      regex_pattern_set_reference_statement = optional(object({
+        arn = string
+        field_to_match = object({
+          uri_path      = optional(bool, false)
+          query_string  = optional(bool, false)
+          single_header = optional(string)
+        })
+        text_transformations = optional(list(object({
+          priority = number
+          type     = string
+        })), [{ priority = 0, type = "NONE" }])
+      }))
+      geo_match_statement = optional(object({
+        country_codes = list(string)
+      }))
+    })
     cloudwatch_metrics_enabled = optional(bool)
     sampled_requests_enabled   = optional(bool)
     custom_response = optional(object({
@@ -58,6 +89,10 @@ variable "rules" {
     }))
   }))
   default = []
+  validation {
+    condition     = length(distinct([for rule in var.rules : rule.priority])) == length(var.rules)
+    error_message = "Each rule must have a unique priority."
+  }
 }
 
 variable "ip_sets" {
@@ -114,6 +149,10 @@ variable "log_retention_days" {
   description = "CloudWatch log group retention in days"
   type        = number
   default     = 7
+  validation {
+    condition     = contains([0, 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653], var.log_retention_days)
+    error_message = "Log retention must be one of the retention periods CloudWatch Logs supports: 0 (never expire), 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288 or 3653."
+  }
 }
 
 variable "log_kms_key_id" {
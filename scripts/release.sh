@@ -80,6 +80,28 @@ increment_version() {
     echo "v${major}.${minor}.${patch}"
 }
 
+# Rank a version type, so that bumps can be compared
+version_rank() {
+    case $1 in
+        major) echo 3 ;;
+        minor) echo 2 ;;
+        *) echo 1 ;;
+    esac
+}
+
+# Detect the version bump that module interface changes since the last release need
+detect_version_type() {
+    local current=$1
+
+    if [[ $current == "v0.0.0" ]]; then
+        echo "patch"
+        return
+    fi
+
+    (cd tests && go run ./cmd/moddiff -from "$current" -format impact) \
+        || error "Could not compare module interfaces with $current"
+}
+
 # Update CHANGELOG
 update_changelog() {
     local new_version=$1
@@ -141,7 +163,7 @@ push_changes() {
 
 # Main function
 main() {
-    local version_type=${1:-patch}
+    local version_type=${1:-}
     
     echo -e "${BLUE}╔════════════════════════════════════╗${NC}"
     echo -e "${BLUE}║   AWS Terraform Modules Release   ║${NC}"
@@ -149,15 +171,29 @@ main() {
     echo ""
     
     # Validate input
-    if [[ ! $version_type =~ ^(major|minor|patch)$ ]]; then
+    if [[ -n $version_type && ! $version_type =~ ^(major|minor|patch)$ ]]; then
         error "Invalid version type. Use: major, minor, or patch"
     fi
     
     # Check git status
     check_git_clean
     
-    # Get current and new version
+    # Get current version
     local current_version=$(get_current_version)
+    
+    # Check the bump against module interface changes
+    info "Comparing module interfaces with $current_version..."
+    local required_type
+    required_type=$(detect_version_type "$current_version") || exit 1
+    info "Module interface changes need a $required_type release"
+    
+    if [[ -z $version_type ]]; then
+        version_type=$required_type
+    elif (( $(version_rank "$version_type") < $(version_rank "$required_type") )); then
+        error "A $version_type release is too small for the module interface changes since $current_version. Run 'make release-diff' for details."
+    fi
+    
+    # Get new version
     local new_version=$(increment_version "$current_version" "$version_type")
     
     info "Current version: $current_version"
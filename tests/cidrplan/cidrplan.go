@@ -0,0 +1,148 @@
+// Package cidrplan lays out and validates the subnet CIDRs passed to
+// modules/vpc without talking to AWS
+package cidrplan
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+var (
+	// ErrInvalidCIDR is returned for blocks that don't parse, aren't IPv4 or
+	// have host bits set
+	ErrInvalidCIDR = errors.New("invalid CIDR block")
+
+	// ErrNotContained is returned for subnets outside the VPC CIDR
+	ErrNotContained = errors.New("subnet is not contained in the VPC CIDR")
+
+	// ErrOverlap is returned for subnets that share addresses
+	ErrOverlap = errors.New("subnets overlap")
+
+	// ErrAZCountMismatch is returned when a subnet list doesn't have one entry
+	// per availability zone
+	ErrAZCountMismatch = errors.New("subnet count does not match availability zone count")
+
+	// ErrNoSpace is returned when the requested subnets don't fit in the VPC
+	ErrNoSpace = errors.New("not enough address space in the VPC CIDR")
+)
+
+// Layout is a set of subnet CIDRs in the shape modules/vpc expects. Entry i of
+// each list belongs to availability zone i.
+type Layout struct {
+	VPCCIDR            string
+	PublicSubnetCIDRs  []string
+	PrivateSubnetCIDRs []string
+}
+
+// Plan carves one public and one private subnet per availability zone out of
+// vpcCIDR. Every subnet gets the given prefix length. Public subnets take the
+// first azCount blocks and private subnets the next azCount.
+func Plan(vpcCIDR string, azCount int, prefixLength int) (Layout, error) {
+	vpc, err := parse(vpcCIDR)
+	if err != nil {
+		return Layout{}, err
+	}
+	if azCount < 1 {
+		return Layout{}, fmt.Errorf("availability zone count must be at least 1, got %d", azCount)
+	}
+	if prefixLength < vpc.Bits() || prefixLength > 32 {
+		return Layout{}, fmt.Errorf("%w: /%d subnets cannot be carved from %s", ErrInvalidCIDR, prefixLength, vpc)
+	}
+
+	available := uint64(1) << (prefixLength - vpc.Bits())
+	if uint64(2*azCount) > available {
+		return Layout{}, fmt.Errorf("%w: %d /%d subnets requested but %s only holds %d", ErrNoSpace, 2*azCount, prefixLength, vpc, available)
+	}
+
+	layout := Layout{VPCCIDR: vpc.String()}
+	for i := 0; i < azCount; i++ {
+		layout.PublicSubnetCIDRs = append(layout.PublicSubnetCIDRs, nthSubnet(vpc, prefixLength, i).String())
+		layout.PrivateSubnetCIDRs = append(layout.PrivateSubnetCIDRs, nthSubnet(vpc, prefixLength, azCount+i).String())
+	}
+
+	return layout, nil
+}
+
+// Validate checks user supplied subnet lists against the VPC CIDR and the
+// number of availability zones. All problems are reported, joined into one
+// error that matches the Err* values with errors.Is.
+func Validate(layout Layout, azCount int) error {
+	vpc, err := parse(layout.VPCCIDR)
+	if err != nil {
+		return fmt.Errorf("vpc_cidr: %w", err)
+	}
+
+	var errs []error
+	if len(layout.PublicSubnetCIDRs) != azCount {
+		errs = append(errs, fmt.Errorf("%w: %d public subnets for %d availability zones", ErrAZCountMismatch, len(layout.PublicSubnetCIDRs), azCount))
+	}
+	if len(layout.PrivateSubnetCIDRs) != azCount {
+		errs = append(errs, fmt.Errorf("%w: %d private subnets for %d availability zones", ErrAZCountMismatch, len(layout.PrivateSubnetCIDRs), azCount))
+	}
+
+	type subnet struct {
+		name   string
+		prefix netip.Prefix
+	}
+
+	var subnets []subnet
+	lists := []struct {
+		name  string
+		cidrs []string
+	}{
+		{"public_subnet_cidrs", layout.PublicSubnetCIDRs},
+		{"private_subnet_cidrs", layout.PrivateSubnetCIDRs},
+	}
+	for _, list := range lists {
+		for i, cidr := range list.cidrs {
+			name := fmt.Sprintf("%s[%d] %s", list.name, i, cidr)
+
+			prefix, err := parse(cidr)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+			if prefix.Bits() < vpc.Bits() || !vpc.Contains(prefix.Addr()) {
+				errs = append(errs, fmt.Errorf("%w: %s is outside %s", ErrNotContained, name, vpc))
+			}
+
+			subnets = append(subnets, subnet{name, prefix})
+		}
+	}
+
+	for i := range subnets {
+		for j := i + 1; j < len(subnets); j++ {
+			if subnets[i].prefix.Overlaps(subnets[j].prefix) {
+				errs = append(errs, fmt.Errorf("%w: %s and %s", ErrOverlap, subnets[i].name, subnets[j].name))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// parse parses an IPv4 network address in CIDR notation
+func parse(cidr string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%w: %q", ErrInvalidCIDR, cidr)
+	}
+	if !prefix.Addr().Is4() {
+		return netip.Prefix{}, fmt.Errorf("%w: %s is not IPv4", ErrInvalidCIDR, cidr)
+	}
+	if prefix.Masked() != prefix {
+		return netip.Prefix{}, fmt.Errorf("%w: %s has host bits set, expected %s", ErrInvalidCIDR, cidr, prefix.Masked())
+	}
+	return prefix, nil
+}
+
+// nthSubnet returns the n-th block of the given prefix length inside parent
+func nthSubnet(parent netip.Prefix, prefixLength int, n int) netip.Prefix {
+	base := parent.Addr().As4()
+	start := uint32(base[0])<<24 | uint32(base[1])<<16 | uint32(base[2])<<8 | uint32(base[3])
+	start += uint32(n) << (32 - prefixLength)
+
+	addr := netip.AddrFrom4([4]byte{byte(start >> 24), byte(start >> 16), byte(start >> 8), byte(start)})
+	return netip.PrefixFrom(addr, prefixLength)
+}
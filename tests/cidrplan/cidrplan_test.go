@@ -0,0 +1,224 @@
+package cidrplan
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		vpcCIDR      string
+		azCount      int
+		prefixLength int
+		expected     Layout
+		expectedErr  error
+	}{
+		{
+			name:         "two zones /24 in /16",
+			vpcCIDR:      "10.0.0.0/16",
+			azCount:      2,
+			prefixLength: 24,
+			expected: Layout{
+				VPCCIDR:            "10.0.0.0/16",
+				PublicSubnetCIDRs:  []string{"10.0.0.0/24", "10.0.1.0/24"},
+				PrivateSubnetCIDRs: []string{"10.0.2.0/24", "10.0.3.0/24"},
+			},
+		},
+		{
+			name:         "three zones /20 in /16",
+			vpcCIDR:      "172.16.0.0/16",
+			azCount:      3,
+			prefixLength: 20,
+			expected: Layout{
+				VPCCIDR:            "172.16.0.0/16",
+				PublicSubnetCIDRs:  []string{"172.16.0.0/20", "172.16.16.0/20", "172.16.32.0/20"},
+				PrivateSubnetCIDRs: []string{"172.16.48.0/20", "172.16.64.0/20", "172.16.80.0/20"},
+			},
+		},
+		{
+			name:         "exactly fills the VPC",
+			vpcCIDR:      "192.168.0.0/24",
+			azCount:      2,
+			prefixLength: 26,
+			expected: Layout{
+				VPCCIDR:            "192.168.0.0/24",
+				PublicSubnetCIDRs:  []string{"192.168.0.0/26", "192.168.0.64/26"},
+				PrivateSubnetCIDRs: []string{"192.168.0.128/26", "192.168.0.192/26"},
+			},
+		},
+		{
+			name:         "too many zones",
+			vpcCIDR:      "192.168.0.0/24",
+			azCount:      3,
+			prefixLength: 26,
+			expectedErr:  ErrNoSpace,
+		},
+		{
+			name:         "subnet larger than VPC",
+			vpcCIDR:      "10.0.0.0/16",
+			azCount:      1,
+			prefixLength: 12,
+			expectedErr:  ErrInvalidCIDR,
+		},
+		{
+			name:         "host bits set",
+			vpcCIDR:      "10.0.0.1/16",
+			azCount:      2,
+			prefixLength: 24,
+			expectedErr:  ErrInvalidCIDR,
+		},
+		{
+			name:         "IPv6",
+			vpcCIDR:      "2001:db8::/56",
+			azCount:      2,
+			prefixLength: 64,
+			expectedErr:  ErrInvalidCIDR,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			layout, err := Plan(testCase.vpcCIDR, testCase.azCount, testCase.prefixLength)
+			if testCase.expectedErr != nil {
+				assert.ErrorIs(t, err, testCase.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, layout)
+			assert.NoError(t, Validate(layout, testCase.azCount))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		layout       Layout
+		azCount      int
+		expectedErrs []error
+	}{
+		{
+			name: "module defaults",
+			layout: Layout{
+				VPCCIDR:            "10.0.0.0/16",
+				PublicSubnetCIDRs:  []string{"10.0.1.0/24", "10.0.2.0/24"},
+				PrivateSubnetCIDRs: []string{"10.0.10.0/24", "10.0.11.0/24"},
+			},
+			azCount: 2,
+		},
+		{
+			name: "module defaults in a custom VPC CIDR",
+			layout: Layout{
+				VPCCIDR:            "172.16.0.0/16",
+				PublicSubnetCIDRs:  []string{"10.0.1.0/24", "10.0.2.0/24"},
+				PrivateSubnetCIDRs: []string{"10.0.10.0/24", "10.0.11.0/24"},
+			},
+			azCount:      2,
+			expectedErrs: []error{ErrNotContained},
+		},
+		{
+			name: "subnet larger than the VPC",
+			layout: Layout{
+				VPCCIDR:            "10.0.0.0/16",
+				PublicSubnetCIDRs:  []string{"10.0.0.0/8"},
+				PrivateSubnetCIDRs: []string{"10.0.10.0/24"},
+			},
+			azCount:      1,
+			expectedErrs: []error{ErrNotContained, ErrOverlap},
+		},
+		{
+			name: "public and private overlap",
+			layout: Layout{
+				VPCCIDR:            "10.0.0.0/16",
+				PublicSubnetCIDRs:  []string{"10.0.0.0/23", "10.0.2.0/24"},
+				PrivateSubnetCIDRs: []string{"10.0.1.0/24", "10.0.3.0/24"},
+			},
+			azCount:      2,
+			expectedErrs: []error{ErrOverlap},
+		},
+		{
+			name: "duplicate public subnet",
+			layout: Layout{
+				VPCCIDR:            "10.0.0.0/16",
+				PublicSubnetCIDRs:  []string{"10.0.1.0/24", "10.0.1.0/24"},
+				PrivateSubnetCIDRs: []string{"10.0.10.0/24", "10.0.11.0/24"},
+			},
+			azCount:      2,
+			expectedErrs: []error{ErrOverlap},
+		},
+		{
+			name: "fewer private subnets than zones",
+			layout: Layout{
+				VPCCIDR:            "10.0.0.0/16",
+				PublicSubnetCIDRs:  []string{"10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"},
+				PrivateSubnetCIDRs: []string{"10.0.10.0/24", "10.0.11.0/24"},
+			},
+			azCount:      3,
+			expectedErrs: []error{ErrAZCountMismatch},
+		},
+		{
+			name: "no subnet lists",
+			layout: Layout{
+				VPCCIDR: "10.0.0.0/16",
+			},
+			azCount:      2,
+			expectedErrs: []error{ErrAZCountMismatch},
+		},
+		{
+			name: "malformed subnet",
+			layout: Layout{
+				VPCCIDR:            "10.0.0.0/16",
+				PublicSubnetCIDRs:  []string{"10.0.1.0"},
+				PrivateSubnetCIDRs: []string{"10.0.10.0/24"},
+			},
+			azCount:      1,
+			expectedErrs: []error{ErrInvalidCIDR},
+		},
+		{
+			name: "malformed VPC CIDR",
+			layout: Layout{
+				VPCCIDR:            "10.0.0.0/33",
+				PublicSubnetCIDRs:  []string{"10.0.1.0/24"},
+				PrivateSubnetCIDRs: []string{"10.0.10.0/24"},
+			},
+			azCount:      1,
+			expectedErrs: []error{ErrInvalidCIDR},
+		},
+	}
+
+	allErrs := []error{ErrInvalidCIDR, ErrNotContained, ErrOverlap, ErrAZCountMismatch}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := Validate(testCase.layout, testCase.azCount)
+			if len(testCase.expectedErrs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			for _, target := range allErrs {
+				expected := false
+				for _, expectedErr := range testCase.expectedErrs {
+					expected = expected || expectedErr == target
+				}
+				assert.Equal(t, expected, errors.Is(err, target), "errors.Is(%v) for %q", target, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Command driftcheck runs refresh-only plans of deployed stacks in parallel
+// and reports every resource and attribute that changed outside terraform,
+// classified as replacement, security, tags or other drift.
+//
+//	go run ./cmd/driftcheck -markdown drift.md -json drift.json ../examples/simple-web-app ../examples/microservices-platform
+//	go run ./cmd/driftcheck -recorded drift/testdata/plans ../examples/simple-web-app
+//
+// Each stack is named after its directory. -recorded replays plans recorded
+// with terraform show -json from <dir>/<stack name>.json instead of running
+// terraform. It exits with status 1 when a stack drifted, and 2 on usage
+// errors or when a stack could not be planned. The reports are written in
+// either case.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/drift"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("driftcheck", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	parallelism := flags.Int("parallelism", 4, "number of stacks to plan at once")
+	recorded := flags.String("recorded", "", "directory of recorded plans to replay instead of running terraform")
+	binary := flags.String("terraform", "terraform", "terraform executable")
+	timeout := flags.Duration("timeout", 30*time.Minute, "time limit for planning all stacks")
+	format := flags.String("format", "markdown", "output format: markdown or json")
+	markdownPath := flags.String("markdown", "", "also write the Markdown report to this file")
+	jsonPath := flags.String("json", "", "also write the JSON report to this file")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: driftcheck [flags] stack-dir...")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	writers := map[string]func(io.Writer, *drift.Report) error{
+		"markdown": drift.WriteMarkdown,
+		"json":     drift.WriteJSON,
+	}
+	write, ok := writers[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown format %q, expected markdown or json\n", *format)
+		return 2
+	}
+
+	stacks := []drift.Stack{}
+	names := map[string]string{}
+	for _, dir := range flags.Args() {
+		stack := drift.Stack{Name: filepath.Base(filepath.Clean(dir)), Dir: dir}
+		if other, exists := names[stack.Name]; exists {
+			fmt.Fprintf(stderr, "stacks %s and %s are both named %s\n", other, dir, stack.Name)
+			return 2
+		}
+		names[stack.Name] = dir
+		stacks = append(stacks, stack)
+	}
+
+	var planner drift.Planner = drift.Terraform{Binary: *binary}
+	if *recorded != "" {
+		planner = drift.Recorded{Dir: *recorded}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	report := drift.Check(ctx, stacks, planner, *parallelism)
+
+	if err := write(stdout, report); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if *markdownPath != "" {
+		if err := writeReport(*markdownPath, report, drift.WriteMarkdown); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+	if *jsonPath != "" {
+		if err := writeReport(*jsonPath, report, drift.WriteJSON); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+
+	for _, stack := range report.Stacks {
+		if stack.Error != "" {
+			fmt.Fprintf(stderr, "%s: %s\n", stack.Name, stack.Error)
+		}
+	}
+	switch {
+	case report.Failed():
+		return 2
+	case report.Drifted():
+		return 1
+	}
+	return 0
+}
+
+// writeReport writes a report to a file
+func writeReport(path string, report *drift.Report, write func(io.Writer, *drift.Report) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file, report); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	t.Parallel()
+
+	plans := filepath.Join("..", "..", "drift", "testdata", "plans")
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"drift", []string{"-recorded", plans, "../../../examples/simple-web-app"}, 1},
+		{"no drift", []string{"-recorded", plans, "../../../examples/data-processing-pipeline"}, 0},
+		{"json output", []string{"-recorded", plans, "-format", "json", "../../../examples/simple-web-app"}, 1},
+		{"stack fails to plan", []string{"-recorded", plans, "../../../examples/simple-web-app", "../../../examples/microservices-platform"}, 2},
+		{"terraform missing", []string{"-terraform", filepath.Join(t.TempDir(), "terraform"), "../../../examples/simple-web-app"}, 2},
+		{"duplicate stack names", []string{"-recorded", plans, "a/simple-web-app", "b/simple-web-app"}, 2},
+		{"no stacks", []string{"-recorded", plans}, 2},
+		{"bad format", []string{"-recorded", plans, "-format", "html", "../../../examples/simple-web-app"}, 2},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, testCase.expected, run(testCase.args, &stdout, &stderr), stderr.String())
+		})
+	}
+}
+
+func TestRunWritesReports(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	markdownPath := filepath.Join(dir, "drift.md")
+	jsonPath := filepath.Join(dir, "drift.json")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{
+		"-recorded", filepath.Join("..", "..", "drift", "testdata", "plans"),
+		"-markdown", markdownPath,
+		"-json", jsonPath,
+		"../../../examples/simple-web-app",
+		"../../../examples/data-processing-pipeline",
+	}, &stdout, &stderr)
+	require.Equal(t, 1, code, stderr.String())
+
+	markdown, err := os.ReadFile(markdownPath)
+	require.NoError(t, err)
+	assert.Equal(t, stdout.String(), string(markdown))
+	assert.Contains(t, string(markdown), "| simple-web-app | 5 | 2 | 3 | 1 |\n")
+
+	var report struct {
+		Stacks []struct {
+			Name      string `json:"name"`
+			Dir       string `json:"dir"`
+			Resources []struct {
+				Address string   `json:"address"`
+				Classes []string `json:"classes"`
+			} `json:"resources"`
+		} `json:"stacks"`
+	}
+	document, err := os.ReadFile(jsonPath)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(document, &report))
+	require.Len(t, report.Stacks, 2)
+	assert.Equal(t, "simple-web-app", report.Stacks[0].Name)
+	assert.Equal(t, "../../../examples/simple-web-app", report.Stacks[0].Dir)
+	assert.Equal(t, "module.web_server[1].aws_instance.main[0]", report.Stacks[0].Resources[4].Address)
+	assert.Equal(t, []string{"replacement"}, report.Stacks[0].Resources[4].Classes)
+	assert.Empty(t, report.Stacks[1].Resources)
+}
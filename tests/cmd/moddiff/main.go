@@ -0,0 +1,127 @@
+// Command moddiff compares the variables and outputs of every module between
+// two git revisions and classifies the change as a major, minor or patch
+// release, so that scripts/release.sh can pick the version bump.
+//
+//	go run ./cmd/moddiff
+//	go run ./cmd/moddiff -from v1.2.0 -to HEAD -format json
+//	go run ./cmd/moddiff -format impact
+//
+// Removed or renamed variables, new required variables, incompatible type
+// changes, removed outputs and outputs that became sensitive are major. New
+// optional variables and outputs, widened types and changed defaults are
+// minor. Everything else is a patch. -from defaults to the latest tag. It
+// exits with status 2 on usage or git errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/moddiff"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("moddiff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	from := flags.String("from", "", "git revision of the previous release (default the latest tag)")
+	to := flags.String("to", "HEAD", "git revision of the new release")
+	modulesDir := flags.String("modules", "../modules", "directory containing one subdirectory per module")
+	format := flags.String("format", "text", "output format: text, json, or impact to print only major, minor or patch")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: moddiff [flags]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+	if *format != "text" && *format != "json" && *format != "impact" {
+		fmt.Fprintf(stderr, "unknown format %q, expected text, json or impact\n", *format)
+		return 2
+	}
+
+	if *from == "" {
+		tag, err := latestTag(*modulesDir)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		*from = tag
+	}
+
+	before, err := loadInterfaces(*modulesDir, *from)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	after, err := loadInterfaces(*modulesDir, *to)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	report := moddiff.NewReport(*from, *to, moddiff.Diff(before, after))
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	case "impact":
+		fmt.Fprintln(stdout, report.Impact)
+	default:
+		printText(stdout, report)
+	}
+	return 0
+}
+
+// loadInterfaces extracts the interface of every module at a revision
+func loadInterfaces(modulesDir string, ref string) (map[string]*moddiff.Interface, error) {
+	modules, err := tfmodule.LoadAllAtRef(modulesDir, ref)
+	if err != nil {
+		return nil, err
+	}
+	return moddiff.ExtractAll(modules)
+}
+
+// latestTag returns the most recent tag reachable from HEAD
+func latestTag(dir string) (string, error) {
+	output, err := exec.Command("git", "-C", dir, "describe", "--tags", "--abbrev=0").Output()
+	if err != nil {
+		return "", fmt.Errorf("finding the latest tag: %w, pass -from", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func printText(w io.Writer, report moddiff.Report) {
+	for _, change := range report.Changes {
+		fmt.Fprintln(w, change)
+	}
+
+	modules := make([]string, 0, len(report.Modules))
+	for module, impact := range report.Modules {
+		modules = append(modules, fmt.Sprintf("%s %s", module, impact))
+	}
+	sort.Strings(modules)
+	if len(modules) > 0 {
+		fmt.Fprintf(w, "modules changed: %s\n", strings.Join(modules, ", "))
+	}
+	fmt.Fprintf(w, "%s..%s: %d changes, %s release\n", report.From, report.To, len(report.Changes), report.Impact)
+}
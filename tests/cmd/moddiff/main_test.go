@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	t.Parallel()
+
+	modules := createReleaseRepo(t)
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"since the latest tag", []string{"-modules", modules}, 0},
+		{"explicit revisions", []string{"-modules", modules, "-from", "v1.0.0", "-to", "HEAD~1"}, 0},
+		{"json output", []string{"-modules", modules, "-format", "json"}, 0},
+		{"unknown revision", []string{"-modules", modules, "-from", "v9.9.9"}, 2},
+		{"not a repository", []string{"-modules", t.TempDir(), "-from", "HEAD"}, 2},
+		{"unknown format", []string{"-modules", modules, "-format", "yaml"}, 2},
+		{"positional argument", []string{"-modules", modules, "extra"}, 2},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, testCase.expected, run(testCase.args, &stdout, &stderr), stderr.String())
+		})
+	}
+}
+
+func TestRunReportsImpact(t *testing.T) {
+	t.Parallel()
+
+	modules := createReleaseRepo(t)
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-modules", modules, "-format", "impact"}, &stdout, &stderr), stderr.String())
+	assert.Equal(t, "major\n", stdout.String())
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"-modules", modules, "-from", "HEAD~1"}, &stdout, &stderr), stderr.String())
+	assert.Equal(t, "HEAD~1..HEAD: 0 changes, patch release\n", stdout.String())
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"-modules", modules}, &stdout, &stderr), stderr.String())
+	output := stdout.String()
+	assert.Contains(t, output, "[major] network: variable \"cidr_block\" renamed to \"vpc_cidr_block\"\n")
+	assert.Contains(t, output, "modules changed: legacy major, network major, queue minor\n")
+	assert.True(t, strings.HasSuffix(output, "v1.0.0..HEAD: 19 changes, major release\n"), output)
+}
+
+func TestRunJSONReport(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-modules", createReleaseRepo(t), "-format", "json"}, &stdout, &stderr), stderr.String())
+
+	var report struct {
+		From    string            `json:"from"`
+		To      string            `json:"to"`
+		Impact  string            `json:"impact"`
+		Modules map[string]string `json:"modules"`
+		Changes []struct {
+			Module string `json:"module"`
+			Kind   string `json:"kind"`
+			Name   string `json:"name"`
+			Impact string `json:"impact"`
+		} `json:"changes"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+
+	assert.Equal(t, "v1.0.0", report.From)
+	assert.Equal(t, "HEAD", report.To)
+	assert.Equal(t, "major", report.Impact)
+	assert.Equal(t, map[string]string{"legacy": "major", "network": "major", "queue": "minor"}, report.Modules)
+	require.Len(t, report.Changes, 19)
+	assert.Equal(t, "module_removed", report.Changes[0].Kind)
+}
+
+// createReleaseRepo creates a git repository whose modules directory holds
+// the before fixtures of the moddiff package at tag v1.0.0 and the after
+// fixtures at HEAD, followed by a commit that doesn't touch the modules. It
+// returns the modules directory.
+func createReleaseRepo(t *testing.T) string {
+	repo := t.TempDir()
+	modules := filepath.Join(repo, "modules")
+	fixtures := filepath.Join("..", "..", "moddiff", "testdata")
+
+	runGit(t, repo, "init", "--quiet")
+	copyDir(t, filepath.Join(fixtures, "before"), modules)
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "Release modules")
+	runGit(t, repo, "tag", "v1.0.0")
+
+	require.NoError(t, os.RemoveAll(modules))
+	copyDir(t, filepath.Join(fixtures, "after"), modules)
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "Change module interfaces")
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "README.md"), []byte("# Modules\n"), 0o644))
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "Add README")
+	return modules
+}
+
+// runGit runs a git command in dir with a fixed identity
+func runGit(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+// copyDir copies the files of src into dst, recursively
+func copyDir(t *testing.T, src string, dst string) {
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0o755)
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, rel), contents, 0o644)
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,131 @@
+// Command modgraph parses examples/ and modules/ and renders the graph of
+// module calls, the outputs each example wires into other modules' inputs and
+// the resources of each called module, without terraform init or network.
+//
+//	go run ./cmd/modgraph -format dot | dot -Tpng > graph.png
+//	go run ./cmd/modgraph -format mermaid
+//	go run ./cmd/modgraph -update ../docs/ARCHITECTURE.md
+//
+// -update replaces the Mermaid block between the begin and end markers of a
+// Markdown file. It exits with status 1 when an example references a module
+// call it doesn't declare, or an output the called module doesn't declare,
+// and 2 on usage or parse errors.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/modgraph"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+)
+
+const (
+	beginMarker = "<!-- BEGIN MODULE GRAPH: generated by tests/cmd/modgraph, do not edit -->"
+	endMarker   = "<!-- END MODULE GRAPH -->"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("modgraph", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	examplesDir := flags.String("examples", "../examples", "directory containing one subdirectory per example")
+	modulesDir := flags.String("modules", "../modules", "directory containing one subdirectory per module")
+	format := flags.String("format", "mermaid", "output format: dot, mermaid or json")
+	resources := flags.Bool("resources", true, "list the resources of each module in its node")
+	update := flags.String("update", "", "Markdown file whose module graph block to replace with the Mermaid graph")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: modgraph [flags]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+	if *format != "dot" && *format != "mermaid" && *format != "json" {
+		fmt.Fprintf(stderr, "unknown format %q, expected dot, mermaid or json\n", *format)
+		return 2
+	}
+
+	examples, err := tfmodule.LoadAll(*examplesDir)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	modules, err := tfmodule.LoadAll(*modulesDir)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	graph, diagnostics := modgraph.Build(examples, modules)
+	if len(diagnostics) > 0 {
+		for _, diagnostic := range diagnostics {
+			fmt.Fprintln(stderr, diagnostic)
+		}
+		return 1
+	}
+
+	options := modgraph.Options{Resources: *resources}
+	if *update != "" {
+		if err := updateDocument(*update, graph, options); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		return 0
+	}
+
+	switch *format {
+	case "dot":
+		err = graph.WriteDOT(stdout, options)
+	case "json":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(graph)
+	default:
+		err = graph.WriteMermaid(stdout, options)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// updateDocument replaces everything between the markers in a Markdown file
+// with the graph as a mermaid code block
+func updateDocument(path string, graph *modgraph.Graph, options modgraph.Options) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	text := string(contents)
+	begin := strings.Index(text, beginMarker)
+	end := strings.Index(text, endMarker)
+	if begin < 0 || end < begin {
+		return fmt.Errorf("%s has no module graph block, add %q and %q lines where it belongs", path, beginMarker, endMarker)
+	}
+
+	var block bytes.Buffer
+	block.WriteString(beginMarker + "\n\n```mermaid\n")
+	if err := graph.WriteMermaid(&block, options); err != nil {
+		return err
+	}
+	block.WriteString("```\n\n")
+
+	updated := text[:begin] + block.String() + text[end:]
+	return os.WriteFile(path, []byte(updated), 0o644)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	t.Parallel()
+
+	examples := filepath.Join("..", "..", "..", "examples")
+	modules := filepath.Join("..", "..", "..", "modules")
+	testdata := filepath.Join("..", "..", "modgraph", "testdata")
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"repository examples", []string{"-examples", examples, "-modules", modules}, 0},
+		{"dot output", []string{"-examples", examples, "-modules", modules, "-format", "dot"}, 0},
+		{"json output", []string{"-examples", examples, "-modules", modules, "-format", "json"}, 0},
+		{"undeclared output", []string{"-examples", filepath.Join(testdata, "examples"), "-modules", filepath.Join(testdata, "modules")}, 1},
+		{"missing examples", []string{"-examples", filepath.Join(t.TempDir(), "examples"), "-modules", modules}, 2},
+		{"unknown format", []string{"-examples", examples, "-modules", modules, "-format", "png"}, 2},
+		{"positional argument", []string{"-examples", examples, "-modules", modules, "extra"}, 2},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, testCase.expected, run(testCase.args, &stdout, &stderr), stderr.String())
+		})
+	}
+}
+
+func TestRunReportsUndeclaredOutputs(t *testing.T) {
+	t.Parallel()
+
+	testdata := filepath.Join("..", "..", "modgraph", "testdata")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-examples", filepath.Join(testdata, "examples"), "-modules", filepath.Join(testdata, "modules")}, &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Empty(t, stdout.String(), "nothing is rendered for a broken graph")
+	assert.Contains(t, stderr.String(), "module.network.private_subnet_ids is not an output of modules/network\n")
+}
+
+func TestRunUpdatesDocument(t *testing.T) {
+	t.Parallel()
+
+	testdata := filepath.Join("..", "..", "modgraph", "testdata")
+	document := filepath.Join(t.TempDir(), "ARCHITECTURE.md")
+	original := "# Architecture\n\n" + beginMarker + "\nstale graph\n" + endMarker + "\n\n## Next\n"
+	require.NoError(t, os.WriteFile(document, []byte(original), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-examples", filepath.Join(testdata, "examples"), "-modules", filepath.Join(testdata, "modules"), "-update", document}
+	require.Equal(t, 1, run(args, &stdout, &stderr), "a broken graph is not written")
+
+	args = []string{"-examples", filepath.Join("..", "..", "..", "examples"), "-modules", filepath.Join("..", "..", "..", "modules"), "-resources=false", "-update", document}
+	require.Equal(t, 0, run(args, &stdout, &stderr), stderr.String())
+
+	updated, err := os.ReadFile(document)
+	require.NoError(t, err)
+	assert.NotContains(t, string(updated), "stale graph")
+	assert.Contains(t, string(updated), beginMarker+"\n\n```mermaid\nflowchart LR\n")
+	assert.Contains(t, string(updated), "  simple_web_app_module_vpc -->|\"private_subnet_ids -> subnet_ids\"| simple_web_app_module_rds\n")
+	assert.Contains(t, string(updated), "```\n\n"+endMarker+"\n\n## Next\n")
+
+	missing := filepath.Join(t.TempDir(), "README.md")
+	require.NoError(t, os.WriteFile(missing, []byte("# No markers\n"), 0o644))
+	args[len(args)-1] = missing
+	assert.Equal(t, 2, run(args, &stdout, &stderr))
+}
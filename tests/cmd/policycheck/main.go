@@ -0,0 +1,99 @@
+// Command policycheck evaluates a Terraform plan against the built-in
+// security rule pack and the IAM policy checks.
+//
+//	terraform plan -out tfplan && terraform show -json tfplan > plan.json
+//	go run ./cmd/policycheck -suppressions suppressions.json plan.json
+//
+// Policies may grant access to the account the plan deploys into, found
+// from data.aws_caller_identity or the plan's ARNs, and to the accounts in
+// -trusted-accounts. It exits with status 1 when an unsuppressed finding is
+// at least as severe as -fail-on, and 2 on usage or input errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/iampolicy"
+	"github.com/jaaparjazzery/aws-terraform-tests/policy"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("policycheck", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	suppressionsPath := flags.String("suppressions", "", "JSON file of suppressions")
+	failOn := flags.String("fail-on", "high", "lowest severity that fails the check: low, medium, high or critical")
+	format := flags.String("format", "text", "output format: text or json")
+	trustedAccounts := flags.String("trusted-accounts", "", "comma-separated account IDs, besides the plan's own, that policies may grant access to")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: policycheck [flags] plan.json")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	minimum, err := policy.ParseSeverity(*failOn)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	plan, err := policy.LoadPlan(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	var suppressions []policy.Suppression
+	if *suppressionsPath != "" {
+		suppressions, err = policy.LoadSuppressions(*suppressionsPath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+
+	options := iampolicy.Options{TrustedAccounts: plan.Accounts}
+	if *trustedAccounts != "" {
+		options.TrustedAccounts = append(options.TrustedAccounts, strings.Split(*trustedAccounts, ",")...)
+	}
+	rules := append(policy.SecurityRules(), iampolicy.Rules(options)...)
+	findings := policy.Evaluate(plan, rules, suppressions)
+
+	switch *format {
+	case "text":
+		for _, finding := range findings {
+			fmt.Fprintln(stdout, finding)
+		}
+		fmt.Fprintf(stdout, "%d resources checked, %d findings, %d suppressed\n", len(plan.Resources), len(findings), len(findings)-len(policy.Unsuppressed(findings, policy.SeverityLow)))
+	case "json":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(findings); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	default:
+		fmt.Fprintf(stderr, "unknown format %q, expected text or json\n", *format)
+		return 2
+	}
+
+	if len(policy.Unsuppressed(findings, minimum)) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	t.Parallel()
+
+	plan := filepath.Join("..", "..", "policy", "testdata", "plan.json")
+	suppressions := filepath.Join("..", "..", "policy", "testdata", "suppressions.json")
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"findings at high", []string{plan}, 1},
+		{"only critical fails", []string{"-fail-on", "critical", "-suppressions", suppressions, plan}, 1},
+		{"json output", []string{"-format", "json", plan}, 1},
+		{"missing plan", []string{filepath.Join(t.TempDir(), "plan.json")}, 2},
+		{"no arguments", []string{}, 2},
+		{"bad severity", []string{"-fail-on", "urgent", plan}, 2},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, testCase.expected, run(testCase.args, &stdout, &stderr), stderr.String())
+		})
+	}
+}
+
+func TestRunReportsSuppressedFindings(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{
+		"-suppressions", filepath.Join("..", "..", "policy", "testdata", "suppressions.json"),
+		filepath.Join("..", "..", "policy", "testdata", "plan.json"),
+	}, &stdout, &stderr)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "(suppressed: Public website bucket)")
+	assert.Contains(t, stdout.String(), "9 resources checked, 8 findings, 3 suppressed")
+}
+
+func TestRunChecksIAMPolicies(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{
+		"-trusted-accounts", "123456789012,210987654321",
+		filepath.Join("..", "..", "iampolicy", "testdata", "plan.json"),
+	}, &stdout, &stderr)
+
+	assert.Equal(t, 1, code, stderr.String())
+	assert.Contains(t, stdout.String(), `IAM001 module.sfn.aws_iam_role_policy.sfn_custom[0]: policy statement "Admin": Action "*" allows every action`)
+	assert.Contains(t, stdout.String(), "principal 345678901234 is in untrusted account 345678901234")
+	assert.NotContains(t, stdout.String(), "untrusted account 210987654321")
+	assert.Contains(t, stdout.String(), "8 resources checked, 7 findings, 0 suppressed")
+}
+
+func TestRunTrustsThePlanAccount(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{filepath.Join("..", "..", "iampolicy", "testdata", "plan.json")}, &stdout, &stderr)
+
+	assert.Equal(t, 1, code, stderr.String())
+	assert.Contains(t, stdout.String(), "principal arn:aws:iam::210987654321:role/ci is in untrusted account 210987654321")
+	assert.Contains(t, stdout.String(), "principal 345678901234 is in untrusted account 345678901234")
+	assert.NotContains(t, stdout.String(), "untrusted account 123456789012")
+}
@@ -0,0 +1,199 @@
+// Command sgexposure builds the security group graph of a Terraform plan,
+// or of live security groups, and reports which ports are open to the
+// internet. With -from and -to it also reports whether one group can reach
+// another.
+//
+//	terraform plan -out tfplan && terraform show -json tfplan > plan.json
+//	go run ./cmd/sgexposure -allow-ports 80,443 plan.json
+//	go run ./cmd/sgexposure -from module.eks.aws_security_group.node -to module.eks.aws_security_group.cluster -port 443 plan.json
+//	go run ./cmd/sgexposure -live -group-ids sg-0123456789abcdef0,sg-0fedcba9876543210
+//
+// Groups are keyed by their address in the plan and by their ID otherwise.
+// With -live, the groups a plan only references by ID are described through
+// the EC2 API. It exits with status 1 when a world-open port is not in
+// -allow-ports or the requested path doesn't exist, and 2 on usage or input
+// errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/jaaparjazzery/aws-terraform-tests/policy"
+	"github.com/jaaparjazzery/aws-terraform-tests/secgroup"
+)
+
+// report is the JSON output
+type report struct {
+	Exposures []exposure      `json:"exposures"`
+	Path      *pathReport     `json:"path,omitempty"`
+	Graph     *secgroup.Graph `json:"graph"`
+}
+
+type exposure struct {
+	secgroup.Exposure
+	Allowed bool `json:"allowed"`
+}
+
+type pathReport struct {
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	Protocol  string         `json:"protocol"`
+	Port      int            `json:"port"`
+	Reachable bool           `json:"reachable"`
+	Rules     *secgroup.Path `json:"rules,omitempty"`
+}
+
+// newEC2Client returns the client used by -live. Tests replace it.
+var newEC2Client = func(region string) (ec2iface.EC2API, error) {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+	if err != nil {
+		return nil, err
+	}
+	return ec2.New(sess), nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("sgexposure", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	from := flags.String("from", "", "group to check connections from")
+	to := flags.String("to", "", "group to check connections to")
+	protocol := flags.String("protocol", "tcp", "protocol of the connection checked with -from and -to")
+	port := flags.Int("port", 443, "port of the connection checked with -from and -to")
+	allowPorts := flags.String("allow-ports", "", "comma-separated TCP ports that may be open to the internet, such as 80,443")
+	live := flags.Bool("live", false, "describe groups through the EC2 API")
+	groupIDs := flags.String("group-ids", "", "comma-separated security group IDs to describe with -live")
+	region := flags.String("region", "us-east-1", "AWS region for -live")
+	format := flags.String("format", "text", "output format: text or json")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: sgexposure [flags] [plan.json]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 || (flags.NArg() == 0 && *groupIDs == "") {
+		flags.Usage()
+		return 2
+	}
+	if (*from == "") != (*to == "") {
+		fmt.Fprintln(stderr, "-from and -to must be given together")
+		return 2
+	}
+	if *groupIDs != "" && !*live {
+		fmt.Fprintln(stderr, "-group-ids needs -live")
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "unknown format %q, expected text or json\n", *format)
+		return 2
+	}
+
+	allowed, err := secgroup.ParsePorts(*allowPorts)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	graph := secgroup.NewGraph()
+	if flags.NArg() == 1 {
+		plan, err := policy.LoadPlan(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		graph = secgroup.FromPlan(plan)
+	}
+
+	if *live {
+		client, err := newEC2Client(*region)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+
+		ids := graph.ExternalGroupIDs()
+		if *groupIDs != "" {
+			ids = append(ids, strings.Split(*groupIDs, ",")...)
+		}
+		if err := graph.LoadEC2(client, ids); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+
+	output := report{Exposures: []exposure{}, Graph: graph}
+	failed := false
+	for _, worldOpen := range graph.WorldOpen() {
+		isAllowed := worldOpen.Covers("tcp", allowed)
+		failed = failed || !isAllowed
+		output.Exposures = append(output.Exposures, exposure{Exposure: worldOpen, Allowed: isAllowed})
+	}
+
+	if *from != "" {
+		for _, group := range []string{*from, *to} {
+			if graph.Group(group) == nil {
+				fmt.Fprintf(stderr, "unknown security group %q, expected one of: %s\n", group, strings.Join(graph.GroupIDs(), ", "))
+				return 2
+			}
+		}
+
+		path, reachable := graph.CanReach(*from, *to, *protocol, *port)
+		output.Path = &pathReport{From: *from, To: *to, Protocol: secgroup.NormalizeProtocol(*protocol), Port: *port, Reachable: reachable}
+		if reachable {
+			output.Path.Rules = &path
+		}
+		failed = failed || !reachable
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(output); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	} else {
+		printText(stdout, graph, output)
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func printText(w io.Writer, graph *secgroup.Graph, output report) {
+	for _, worldOpen := range output.Exposures {
+		line := worldOpen.String()
+		if worldOpen.Allowed {
+			line += " (allowed)"
+		}
+		if attached := graph.Group(worldOpen.Group).AttachedTo; len(attached) > 0 {
+			line += ", attached to " + strings.Join(attached, ", ")
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintf(w, "%d security groups, %d world-open rules\n", len(graph.Groups), len(output.Exposures))
+
+	if path := output.Path; path != nil {
+		if path.Reachable {
+			fmt.Fprintf(w, "%s can reach %s on %s/%d: %s\n", path.From, path.To, path.Protocol, path.Port, path.Rules)
+		} else {
+			fmt.Fprintf(w, "%s cannot reach %s on %s/%d\n", path.From, path.To, path.Protocol, path.Port)
+		}
+	}
+}
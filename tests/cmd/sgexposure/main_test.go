@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	t.Parallel()
+
+	plan := filepath.Join("..", "..", "secgroup", "testdata", "plan.json")
+	node := "module.eks.aws_security_group.node"
+	cluster := "module.eks.aws_security_group.cluster"
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"ssh open to the world", []string{"-allow-ports", "80,443", plan}, 1},
+		{"every world-open port allowed", []string{"-allow-ports", "22,80,443", plan}, 0},
+		{"reachable path", []string{"-allow-ports", "22,80,443", "-from", node, "-to", cluster, "-port", "443", plan}, 0},
+		{"unreachable path", []string{"-allow-ports", "22,80,443", "-from", cluster, "-to", node, "-port", "443", plan}, 1},
+		{"json output", []string{"-format", "json", plan}, 1},
+		{"unknown group", []string{"-from", "aws_security_group.missing", "-to", cluster, plan}, 2},
+		{"from without to", []string{"-from", node, plan}, 2},
+		{"group ids without live", []string{"-group-ids", "sg-0a1b2c3d4e5f60009"}, 2},
+		{"bad port list", []string{"-allow-ports", "https", plan}, 2},
+		{"missing plan", []string{filepath.Join(t.TempDir(), "plan.json")}, 2},
+		{"no arguments", []string{}, 2},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, testCase.expected, run(testCase.args, &stdout, &stderr), stderr.String())
+		})
+	}
+}
+
+func TestRunReportsExposuresAndPath(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{
+		"-allow-ports", "80,443",
+		"-from", "module.eks.aws_security_group.node",
+		"-to", "module.eks.aws_security_group.cluster",
+		filepath.Join("..", "..", "secgroup", "testdata", "plan.json"),
+	}, &stdout, &stderr)
+
+	assert.Equal(t, 1, code, stderr.String())
+	assert.Equal(t, `aws_security_group.alb: tcp/80 open to 0.0.0.0/0 (aws_security_group.alb.ingress[1]) (allowed), attached to aws_lb.web
+aws_security_group.alb: tcp/443 open to 0.0.0.0/0 (aws_security_group.alb.ingress[0]) (allowed), attached to aws_lb.web
+sg-0a1b2c3d4e5f60009: tcp/22 open to 0.0.0.0/0 (aws_vpc_security_group_ingress_rule.ssh), attached to aws_instance.app
+4 security groups, 3 world-open rules
+module.eks.aws_security_group.node can reach module.eks.aws_security_group.cluster on tcp/443: module.eks.aws_security_group.node -> module.eks.aws_security_group.cluster: egress all (module.eks.aws_security_group_rule.node_egress), ingress tcp/443 (module.eks.aws_security_group_rule.cluster_ingress_node_https)
+`, stdout.String())
+}
+
+func TestRunLiveDescribesGroups(t *testing.T) {
+	client := &fakeEC2{groups: []*ec2.SecurityGroup{{
+		GroupId:   aws.String("sg-0a1b2c3d4e5f60009"),
+		GroupName: aws.String("orders-app"),
+		IpPermissions: []*ec2.IpPermission{{
+			IpProtocol: aws.String("tcp"),
+			FromPort:   aws.Int64(3389),
+			ToPort:     aws.Int64(3389),
+			IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+		}},
+	}}}
+	newEC2Client = func(string) (ec2iface.EC2API, error) { return client, nil }
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-live", "-group-ids", "sg-0a1b2c3d4e5f60009", "-format", "json"}, &stdout, &stderr)
+	assert.Equal(t, 1, code, stderr.String())
+
+	var output struct {
+		Exposures []struct {
+			Group   string
+			CIDR    string
+			Allowed bool
+		}
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &output))
+	require.Len(t, output.Exposures, 1)
+	assert.Equal(t, "sg-0a1b2c3d4e5f60009", output.Exposures[0].Group)
+	assert.Equal(t, "0.0.0.0/0", output.Exposures[0].CIDR)
+	assert.False(t, output.Exposures[0].Allowed)
+}
+
+// fakeEC2 serves DescribeSecurityGroupsPages from a fixed list of groups
+type fakeEC2 struct {
+	ec2iface.EC2API
+	groups []*ec2.SecurityGroup
+}
+
+func (f *fakeEC2) DescribeSecurityGroupsPages(input *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool) error {
+	fn(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: f.groups}, true)
+	return nil
+}
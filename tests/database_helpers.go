@@ -0,0 +1,233 @@
+package test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// databaseConnection describes how to reach a database created by the rds
+// module, or a stand-in container that mimics it
+type databaseConnection struct {
+	Engine   string
+	Endpoint string
+	Username string
+	Password string
+	Database string
+
+	// RequireTLS makes the helper insist on an encrypted session and check
+	// that the server actually negotiated TLS
+	RequireTLS bool
+
+	// CACertificates are PEM certificates the server certificate must chain
+	// to, with the endpoint host matching it. Without them a TLS session is
+	// encrypted but the server is not verified.
+	CACertificates []byte
+}
+
+// rdsCACommonNames are the subject common names, after "Amazon RDS <region>",
+// of the certificates in a regional RDS CA bundle by ca_cert_identifier
+var rdsCACommonNames = map[string]string{
+	"rds-ca-2019":       "2019 CA",
+	"rds-ca-ecc384-g1":  "Root CA ECC384 G1",
+	"rds-ca-rsa2048-g1": "Root CA RSA2048 G1",
+	"rds-ca-rsa4096-g1": "Root CA RSA4096 G1",
+}
+
+// checkDatabaseConnection opens a SQL connection to a database, runs a trivial
+// query and checks that the session belongs to the configured user and
+// database, and is encrypted when TLS is required
+func checkDatabaseConnection(t *testing.T, conn databaseConnection, timeout time.Duration) {
+	db := openDatabase(t, conn)
+	defer db.Close()
+
+	waitForDatabase(t, db, conn.Endpoint, timeout)
+
+	var one int
+	require.NoError(t, db.QueryRow("SELECT 1").Scan(&one))
+	assert.Equal(t, 1, one)
+
+	var user, database string
+	switch conn.Engine {
+	case "postgres":
+		require.NoError(t, db.QueryRow("SELECT current_user, current_database()").Scan(&user, &database))
+	case "mysql":
+		require.NoError(t, db.QueryRow("SELECT SUBSTRING_INDEX(CURRENT_USER(), '@', 1), DATABASE()").Scan(&user, &database))
+	}
+	assert.Equal(t, conn.Username, user)
+	assert.Equal(t, conn.Database, database)
+
+	if conn.RequireTLS {
+		assert.True(t, isDatabaseSessionEncrypted(t, db, conn.Engine), "%s session to %s is not using TLS", conn.Engine, conn.Endpoint)
+	}
+}
+
+// openDatabase builds a driver DSN for the engine. TLS sessions verify the
+// server certificate and host name against CACertificates when they are set,
+// and are only encrypted otherwise, since stand-in containers use
+// self-signed certificates.
+func openDatabase(t *testing.T, conn databaseConnection) *sql.DB {
+	host, port, err := net.SplitHostPort(conn.Endpoint)
+	require.NoError(t, err, "endpoint %q should be host:port", conn.Endpoint)
+
+	var driver, dsn string
+	switch conn.Engine {
+	case "postgres":
+		query := url.Values{"sslmode": {"disable"}, "connect_timeout": {"10"}}
+		if conn.RequireTLS {
+			query.Set("sslmode", "require")
+		}
+		if conn.RequireTLS && conn.CACertificates != nil {
+			rootCert := filepath.Join(t.TempDir(), "ca.pem")
+			require.NoError(t, os.WriteFile(rootCert, conn.CACertificates, 0o600))
+			query.Set("sslmode", "verify-full")
+			query.Set("sslrootcert", rootCert)
+		}
+		dsn = (&url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(conn.Username, conn.Password),
+			Host:     net.JoinHostPort(host, port),
+			Path:     "/" + conn.Database,
+			RawQuery: query.Encode(),
+		}).String()
+		driver = "postgres"
+	case "mysql":
+		config := mysql.NewConfig()
+		config.User = conn.Username
+		config.Passwd = conn.Password
+		config.Net = "tcp"
+		config.Addr = net.JoinHostPort(host, port)
+		config.DBName = conn.Database
+		config.Timeout = 10 * time.Second
+		if conn.RequireTLS {
+			config.TLSConfig = "skip-verify"
+		}
+		if conn.RequireTLS && conn.CACertificates != nil {
+			roots := x509.NewCertPool()
+			require.True(t, roots.AppendCertsFromPEM(conn.CACertificates), "no certificates in CACertificates")
+			config.TLSConfig = "verify-" + conn.Endpoint
+			require.NoError(t, mysql.RegisterTLSConfig(config.TLSConfig, &tls.Config{RootCAs: roots, ServerName: host}))
+		}
+		dsn = config.FormatDSN()
+		driver = "mysql"
+	default:
+		t.Fatalf("Unsupported database engine %s", conn.Engine)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	require.NoError(t, err)
+
+	return db
+}
+
+// waitForDatabase pings a database until it accepts connections or the
+// timeout expires. A freshly created instance or container can take a while to
+// accept logins after its endpoint resolves.
+func waitForDatabase(t *testing.T, db *sql.DB, endpoint string, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err := db.Ping()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Database at %s did not accept connections after %s: %v", endpoint, timeout, err)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// isDatabaseSessionEncrypted asks the server whether the session was
+// negotiated over TLS. Every pooled connection shares the same DSN, so any
+// one of them answers for all.
+func isDatabaseSessionEncrypted(t *testing.T, db *sql.DB, engine string) bool {
+	switch engine {
+	case "postgres":
+		var ssl bool
+		require.NoError(t, db.QueryRow("SELECT ssl FROM pg_stat_ssl WHERE pid = pg_backend_pid()").Scan(&ssl))
+		return ssl
+	case "mysql":
+		var name, cipher string
+		require.NoError(t, db.QueryRow("SHOW SESSION STATUS LIKE 'Ssl_cipher'").Scan(&name, &cipher))
+		return cipher != ""
+	}
+
+	return false
+}
+
+// getRDSCACertificates returns the certificates of the regional RDS CA bundle
+// that belong to one ca_cert_identifier, as PEM. A session verified against
+// them fails if the instance presents a certificate from any other CA. The
+// bundle is read from RDS_CA_BUNDLE, or downloaded from the RDS trust store.
+func getRDSCACertificates(t *testing.T, region string, identifier string) []byte {
+	source := os.Getenv("RDS_CA_BUNDLE")
+	var bundle []byte
+	if source != "" {
+		data, err := os.ReadFile(source)
+		require.NoError(t, err)
+		bundle = data
+	} else {
+		source = fmt.Sprintf("https://truststore.pki.rds.amazonaws.com/%s/%s-bundle.pem", region, region)
+		response, err := http.Get(source)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		require.Equal(t, http.StatusOK, response.StatusCode, "downloading %s", source)
+		bundle, err = io.ReadAll(response.Body)
+		require.NoError(t, err)
+	}
+
+	suffix, ok := rdsCACommonNames[identifier]
+	require.True(t, ok, "unknown CA certificate identifier %s", identifier)
+	commonName := fmt.Sprintf("Amazon RDS %s %s", region, suffix)
+
+	certificates := []byte{}
+	for block, rest := pem.Decode(bundle); block != nil; block, rest = pem.Decode(rest) {
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		require.NoError(t, err)
+		if certificate.Subject.CommonName == commonName {
+			certificates = append(certificates, pem.EncodeToMemory(block)...)
+		}
+	}
+	require.NotEmpty(t, certificates, "%s has no certificate for %s", source, commonName)
+	return certificates
+}
+
+// getStandInDatabaseEndpoint returns the endpoint of a local container standing
+// in for an RDS instance from the given environment variable (or the default)
+// and skips the test when nothing is listening there
+func getStandInDatabaseEndpoint(t *testing.T, envVar string, defaultEndpoint string) string {
+	endpoint := getEnvOrDefault(envVar, defaultEndpoint)
+
+	conn, err := net.DialTimeout("tcp", endpoint, 5*time.Second)
+	if err != nil {
+		t.Skipf("No stand-in database is listening at %s (set %s): %v", endpoint, envVar, err)
+	}
+	conn.Close()
+
+	return endpoint
+}
+
+// getEnvOrDefault returns the value of an environment variable, or
+// defaultValue when it is unset
+func getEnvOrDefault(name string, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
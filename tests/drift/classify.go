@@ -0,0 +1,189 @@
+package drift
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Class is the kind of an attribute change
+type Class string
+
+const (
+	// ClassReplacement changes force terraform to replace the resource to
+	// bring it back in line, and deleted resources are created again
+	ClassReplacement Class = "replacement"
+
+	// ClassSecurity changes touch network exposure, access policies or
+	// encryption
+	ClassSecurity Class = "security"
+
+	// ClassTags changes only touch tags
+	ClassTags Class = "tags"
+
+	// ClassOther is every other change
+	ClassOther Class = "other"
+)
+
+// Classes lists the classes from the most to the least urgent
+var Classes = []Class{ClassReplacement, ClassSecurity, ClassTags, ClassOther}
+
+// tagAttributes are the attributes that hold tags
+var tagAttributes = map[string]bool{"tags": true, "tags_all": true}
+
+// securityAttributes are attributes, at any depth, whose changes affect who
+// can reach or read a resource
+var securityAttributes = map[string]bool{
+	"acl":                                 true,
+	"assume_role_policy":                  true,
+	"at_rest_encryption_enabled":          true,
+	"auth_token":                          true,
+	"block_public_acls":                   true,
+	"block_public_policy":                 true,
+	"certificate_arn":                     true,
+	"cidr_blocks":                         true,
+	"deletion_protection":                 true,
+	"egress":                              true,
+	"encrypted":                           true,
+	"endpoint_public_access":              true,
+	"http_tokens":                         true,
+	"iam_database_authentication_enabled": true,
+	"iam_instance_profile":                true,
+	"ignore_public_acls":                  true,
+	"ingress":                             true,
+	"inline_policy":                       true,
+	"ipv6_cidr_blocks":                    true,
+	"kms_key_arn":                         true,
+	"kms_key_id":                          true,
+	"kms_master_key_id":                   true,
+	"managed_policy_arns":                 true,
+	"map_public_ip_on_launch":             true,
+	"password":                            true,
+	"policy":                              true,
+	"public_access_cidrs":                 true,
+	"publicly_accessible":                 true,
+	"restrict_public_buckets":             true,
+	"security_group_ids":                  true,
+	"security_groups":                     true,
+	"sse_algorithm":                       true,
+	"ssl_policy":                          true,
+	"storage_encrypted":                   true,
+	"transit_encryption_enabled":          true,
+	"vpc_security_group_ids":              true,
+}
+
+// forceNewAttributes are the attributes, as dotted paths without list
+// indexes, that the AWS provider can only set by replacing a resource. They
+// cover the resource types of the modules in this repository.
+var forceNewAttributes = map[string][]string{
+	"aws_db_instance":                   {"availability_zone", "character_set_name", "db_name", "engine", "kms_key_id", "storage_encrypted", "timezone", "username"},
+	"aws_db_parameter_group":            {"family", "name", "name_prefix"},
+	"aws_db_subnet_group":               {"name", "name_prefix"},
+	"aws_eip":                           {"domain"},
+	"aws_eks_cluster":                   {"name", "role_arn", "vpc_config.security_group_ids", "encryption_config"},
+	"aws_eks_node_group":                {"ami_type", "capacity_type", "cluster_name", "disk_size", "instance_types", "node_group_name", "node_role_arn", "subnet_ids"},
+	"aws_elasticache_replication_group": {"at_rest_encryption_enabled", "kms_key_id", "replication_group_id", "subnet_group_name"},
+	"aws_instance":                      {"ami", "associate_public_ip_address", "availability_zone", "ebs_block_device", "key_name", "placement_group", "private_ip", "subnet_id", "root_block_device.encrypted", "root_block_device.kms_key_id"},
+	"aws_lb":                            {"internal", "load_balancer_type", "name", "name_prefix"},
+	"aws_lb_target_group":               {"name", "name_prefix", "port", "protocol", "target_type", "vpc_id"},
+	"aws_nat_gateway":                   {"allocation_id", "connectivity_type", "subnet_id"},
+	"aws_route_table_association":       {"subnet_id"},
+	"aws_s3_bucket":                     {"bucket", "bucket_prefix"},
+	"aws_security_group":                {"description", "name", "name_prefix", "vpc_id"},
+	"aws_subnet":                        {"availability_zone", "availability_zone_id", "cidr_block", "vpc_id"},
+	"aws_vpc":                           {"cidr_block"},
+}
+
+// classify returns the classes of a change to the attribute at path of a
+// resource of the given type
+func classify(resourceType string, path []interface{}, replacePaths [][]interface{}) []Class {
+	classes := []Class{}
+	if forcesNew(resourceType, path) || inReplacePaths(path, replacePaths) {
+		classes = append(classes, ClassReplacement)
+	}
+	for _, step := range path {
+		if name, ok := step.(string); ok && securityAttributes[name] {
+			classes = append(classes, ClassSecurity)
+			break
+		}
+	}
+	if len(path) > 0 && tagAttributes[fmt.Sprint(path[0])] {
+		classes = append(classes, ClassTags)
+	}
+	if len(classes) == 0 {
+		classes = append(classes, ClassOther)
+	}
+	return classes
+}
+
+// forcesNew reports whether path is, or is inside, an attribute that forces
+// a new resource
+func forcesNew(resourceType string, path []interface{}) bool {
+	names := []string{}
+	for _, step := range path {
+		if name, ok := step.(string); ok {
+			names = append(names, name)
+		}
+	}
+	dotted := strings.Join(names, ".")
+
+	for _, attribute := range forceNewAttributes[resourceType] {
+		if dotted == attribute || strings.HasPrefix(dotted, attribute+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// inReplacePaths reports whether path is, or is inside, one of the
+// replace_paths of a proposed change
+func inReplacePaths(path []interface{}, replacePaths [][]interface{}) bool {
+	for _, replacePath := range replacePaths {
+		if len(replacePath) > len(path) {
+			continue
+		}
+		matches := true
+		for i, step := range replacePath {
+			if !sameStep(step, path[i]) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// sameStep compares a replace_paths step, where list indexes are JSON
+// numbers, with a path step
+func sameStep(replaceStep interface{}, step interface{}) bool {
+	if number, ok := replaceStep.(json.Number); ok {
+		index, err := number.Int64()
+		return err == nil && step == int(index)
+	}
+	return replaceStep == step
+}
+
+// resourceClasses returns the classes of all changes to a resource, in the
+// order of Classes
+func resourceClasses(resource Resource) []Class {
+	if resource.Action == ActionDelete {
+		return []Class{ClassReplacement}
+	}
+
+	seen := map[Class]bool{}
+	for _, attribute := range resource.Attributes {
+		for _, class := range attribute.Classes {
+			seen[class] = true
+		}
+	}
+	classes := []Class{}
+	for _, class := range Classes {
+		if seen[class] {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
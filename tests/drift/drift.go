@@ -0,0 +1,283 @@
+// Package drift reads the resource_drift of refresh-only plans, works out
+// which attributes of each resource changed outside terraform and classifies
+// the changes
+package drift
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Action is what happened to a resource outside terraform
+type Action string
+
+const (
+	// ActionUpdate means attributes of the resource changed
+	ActionUpdate Action = "update"
+
+	// ActionDelete means the resource no longer exists, so the next apply
+	// creates it again
+	ActionDelete Action = "delete"
+)
+
+// Attribute is an attribute that changed outside terraform. Before and After
+// are left empty for sensitive values.
+type Attribute struct {
+	Path      string      `json:"path"`
+	Before    interface{} `json:"before"`
+	After     interface{} `json:"after"`
+	Sensitive bool        `json:"sensitive,omitempty"`
+	Classes   []Class     `json:"classes"`
+}
+
+// Resource is a resource that changed outside terraform
+type Resource struct {
+	Address    string      `json:"address"`
+	Type       string      `json:"type"`
+	Action     Action      `json:"action"`
+	Classes    []Class     `json:"classes"`
+	Attributes []Attribute `json:"attributes"`
+}
+
+// HasClass reports whether any change to the resource is of the given class
+func (r Resource) HasClass(class Class) bool {
+	for _, c := range r.Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+type planJSON struct {
+	FormatVersion   string               `json:"format_version"`
+	ResourceDrift   []resourceChangeJSON `json:"resource_drift"`
+	ResourceChanges []resourceChangeJSON `json:"resource_changes"`
+}
+
+type resourceChangeJSON struct {
+	Address string `json:"address"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+	Change  struct {
+		Actions         []string        `json:"actions"`
+		Before          interface{}     `json:"before"`
+		After           interface{}     `json:"after"`
+		BeforeSensitive interface{}     `json:"before_sensitive"`
+		AfterSensitive  interface{}     `json:"after_sensitive"`
+		ReplacePaths    [][]interface{} `json:"replace_paths"`
+	} `json:"change"`
+}
+
+// identifierPattern matches map keys that can be written after a dot
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// Parse reads a plan in the format of terraform show -json and returns the
+// managed resources that drifted, sorted by address. Resources of a
+// refresh-only plan have no proposed changes, so replacement is judged from
+// the provider attributes that force a new resource. When the plan does
+// propose changes, their replace_paths count as well.
+func Parse(plan []byte) ([]Resource, error) {
+	decoder := json.NewDecoder(bytes.NewReader(plan))
+	decoder.UseNumber()
+
+	var document planJSON
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("parsing plan: %w", err)
+	}
+	if document.FormatVersion == "" {
+		return nil, fmt.Errorf("parsing plan: no format_version, expected the output of terraform show -json")
+	}
+
+	replacePaths := map[string][][]interface{}{}
+	for _, change := range document.ResourceChanges {
+		replacePaths[change.Address] = change.Change.ReplacePaths
+	}
+
+	resources := []Resource{}
+	for _, drift := range document.ResourceDrift {
+		if drift.Mode == "data" {
+			continue
+		}
+		resource := Resource{Address: drift.Address, Type: drift.Type, Attributes: []Attribute{}}
+
+		switch strings.Join(drift.Change.Actions, ",") {
+		case "update":
+			resource.Action = ActionUpdate
+			for _, change := range diffValues(nil, drift.Change.Before, drift.Change.After) {
+				resource.Attributes = append(resource.Attributes, newAttribute(drift, change, replacePaths[drift.Address]))
+			}
+			if len(resource.Attributes) == 0 {
+				continue
+			}
+		case "delete":
+			resource.Action = ActionDelete
+		default:
+			continue
+		}
+
+		resource.Classes = resourceClasses(resource)
+		resources = append(resources, resource)
+	}
+
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Address < resources[j].Address })
+	return resources, nil
+}
+
+// change is a value that differs between before and after, at a path of
+// map keys and list indexes
+type change struct {
+	path   []interface{}
+	before interface{}
+	after  interface{}
+}
+
+// diffValues returns the leaves that differ between before and after. Lists
+// are compared element by element, and an element or map entry that only
+// exists on one side is returned whole.
+func diffValues(path []interface{}, before interface{}, after interface{}) []change {
+	if reflect.DeepEqual(before, after) {
+		return nil
+	}
+
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		keys := map[string]bool{}
+		for key := range beforeMap {
+			keys[key] = true
+		}
+		for key := range afterMap {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		changes := []change{}
+		for _, key := range sorted {
+			changes = append(changes, diffValues(appendStep(path, key), beforeMap[key], afterMap[key])...)
+		}
+		return changes
+	}
+
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if beforeIsList && afterIsList {
+		changes := []change{}
+		for i := 0; i < len(beforeList) || i < len(afterList); i++ {
+			var beforeElement, afterElement interface{}
+			if i < len(beforeList) {
+				beforeElement = beforeList[i]
+			}
+			if i < len(afterList) {
+				afterElement = afterList[i]
+			}
+			changes = append(changes, diffValues(appendStep(path, i), beforeElement, afterElement)...)
+		}
+		return changes
+	}
+
+	return []change{{path: path, before: before, after: after}}
+}
+
+// newAttribute classifies a change and hides its values if either side is
+// sensitive
+func newAttribute(drift resourceChangeJSON, c change, replacePaths [][]interface{}) Attribute {
+	attribute := Attribute{
+		Path:    formatPath(c.path),
+		Before:  c.before,
+		After:   c.after,
+		Classes: classify(drift.Type, c.path, replacePaths),
+	}
+	if isSensitive(drift.Change.BeforeSensitive, c.path) || isSensitive(drift.Change.AfterSensitive, c.path) {
+		attribute.Before = nil
+		attribute.After = nil
+		attribute.Sensitive = true
+	}
+	return attribute
+}
+
+// isSensitive reports whether a sensitivity mask from a plan marks the value
+// at path, or anything inside it, as sensitive
+func isSensitive(mask interface{}, path []interface{}) bool {
+	for _, step := range path {
+		switch typed := mask.(type) {
+		case bool:
+			return typed
+		case map[string]interface{}:
+			key, ok := step.(string)
+			if !ok {
+				return false
+			}
+			mask = typed[key]
+		case []interface{}:
+			index, ok := step.(int)
+			if !ok || index >= len(typed) {
+				return false
+			}
+			mask = typed[index]
+		default:
+			return false
+		}
+	}
+	return containsTrue(mask)
+}
+
+// containsTrue reports whether a sensitivity mask holds true anywhere
+func containsTrue(mask interface{}) bool {
+	switch typed := mask.(type) {
+	case bool:
+		return typed
+	case map[string]interface{}:
+		for _, value := range typed {
+			if containsTrue(value) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, value := range typed {
+			if containsTrue(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// formatPath writes a path as an attribute reference, such as
+// ingress[0].cidr_blocks or tags["kubernetes.io/role/elb"]
+func formatPath(path []interface{}) string {
+	var b strings.Builder
+	for _, step := range path {
+		switch typed := step.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", typed)
+		case string:
+			if !identifierPattern.MatchString(typed) {
+				fmt.Fprintf(&b, "[%q]", typed)
+				continue
+			}
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(typed)
+		}
+	}
+	return b.String()
+}
+
+// appendStep returns a copy of path with step added, so sibling paths don't
+// share a backing array
+func appendStep(path []interface{}, step interface{}) []interface{} {
+	next := make([]interface{}, len(path), len(path)+1)
+	copy(next, path)
+	return append(next, step)
+}
@@ -0,0 +1,270 @@
+package drift
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRecordedPlan(t *testing.T) {
+	t.Parallel()
+
+	resources := parseFixture(t, "simple-web-app")
+
+	addresses := []string{}
+	for _, resource := range resources {
+		addresses = append(addresses, resource.Address)
+	}
+	assert.Equal(t, []string{
+		"aws_security_group.web",
+		"module.rds.aws_db_instance.main",
+		"module.vpc.aws_vpc.main",
+		"module.web_server[0].aws_instance.main[0]",
+		"module.web_server[1].aws_instance.main[0]",
+	}, addresses)
+
+	group := resources[0]
+	assert.Equal(t, ActionUpdate, group.Action)
+	assert.Equal(t, []Class{ClassSecurity}, group.Classes)
+	require.Len(t, group.Attributes, 1)
+	assert.Equal(t, "ingress[1]", group.Attributes[0].Path)
+	assert.Nil(t, group.Attributes[0].Before)
+	assert.Equal(t, json.Number("22"), group.Attributes[0].After.(map[string]interface{})["from_port"])
+
+	vpc := resources[2]
+	assert.Equal(t, []Class{ClassTags}, vpc.Classes)
+	assert.Equal(t, []string{
+		"tags.CostCenter",
+		`tags["kubernetes.io/role/elb"]`,
+		"tags_all.CostCenter",
+		`tags_all["kubernetes.io/role/elb"]`,
+	}, attributePaths(vpc))
+
+	instance := resources[3]
+	assert.Equal(t, []Class{ClassReplacement, ClassSecurity}, instance.Classes)
+	assert.Equal(t, []string{"associate_public_ip_address", "metadata_options[0].http_tokens"}, attributePaths(instance))
+	assert.Equal(t, "required", instance.Attributes[1].Before)
+	assert.Equal(t, "optional", instance.Attributes[1].After)
+
+	deleted := resources[4]
+	assert.Equal(t, ActionDelete, deleted.Action)
+	assert.Equal(t, []Class{ClassReplacement}, deleted.Classes)
+	assert.Empty(t, deleted.Attributes)
+}
+
+func TestParseRedactsSensitiveValues(t *testing.T) {
+	t.Parallel()
+
+	database := parseFixture(t, "simple-web-app")[1]
+	require.Equal(t, []string{"backup_retention_period", "password", "publicly_accessible"}, attributePaths(database))
+
+	password := database.Attributes[1]
+	assert.True(t, password.Sensitive)
+	assert.Nil(t, password.Before)
+	assert.Nil(t, password.After)
+	assert.Equal(t, []Class{ClassSecurity}, password.Classes)
+	assert.Equal(t, []Class{ClassOther}, database.Attributes[0].Classes)
+
+	var report bytes.Buffer
+	require.NoError(t, WriteJSON(&report, &Report{Stacks: []StackReport{{Resources: []Resource{database}}}}))
+	assert.NotContains(t, report.String(), "RecordedPassword1!")
+	assert.NotContains(t, report.String(), "ChangedInConsole2!")
+}
+
+func TestParseWithoutDrift(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, parseFixture(t, "data-processing-pipeline"))
+}
+
+func TestParseUsesReplacePaths(t *testing.T) {
+	t.Parallel()
+
+	plan := `{
+		"format_version": "1.2",
+		"resource_drift": [{
+			"address": "aws_launch_template.web",
+			"mode": "managed",
+			"type": "aws_launch_template",
+			"change": {
+				"actions": ["update"],
+				"before": {"placement": [{"tenancy": "default"}], "description": "web"},
+				"after": {"placement": [{"tenancy": "dedicated"}], "description": "web servers"}
+			}
+		}],
+		"resource_changes": [{
+			"address": "aws_launch_template.web",
+			"change": {"actions": ["delete", "create"], "replace_paths": [["placement", 0, "tenancy"]]}
+		}]
+	}`
+
+	resources, err := Parse([]byte(plan))
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, []string{"description", "placement[0].tenancy"}, attributePaths(resources[0]))
+	assert.Equal(t, []Class{ClassOther}, resources[0].Attributes[0].Classes)
+	assert.Equal(t, []Class{ClassReplacement}, resources[0].Attributes[1].Classes)
+}
+
+func TestParseRejectsOtherDocuments(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse([]byte(`{"resource_drift": []}`))
+	assert.ErrorContains(t, err, "no format_version")
+
+	_, err = Parse([]byte(`plan`))
+	assert.Error(t, err)
+}
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		resourceType string
+		path         []interface{}
+		expected     []Class
+	}{
+		{"tag", "aws_s3_bucket", []interface{}{"tags", "Owner"}, []Class{ClassTags}},
+		{"nested security attribute", "aws_security_group", []interface{}{"egress", 0, "cidr_blocks", 0}, []Class{ClassSecurity}},
+		{"force new attribute", "aws_subnet", []interface{}{"cidr_block"}, []Class{ClassReplacement}},
+		{"inside a force new block", "aws_instance", []interface{}{"root_block_device", 0, "encrypted"}, []Class{ClassReplacement, ClassSecurity}},
+		{"force new for another type", "aws_lb_listener", []interface{}{"port"}, []Class{ClassOther}},
+		{"unknown type", "aws_sqs_queue", []interface{}{"visibility_timeout_seconds"}, []Class{ClassOther}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, testCase.expected, classify(testCase.resourceType, testCase.path, nil))
+		})
+	}
+}
+
+func TestCheckRecordedStacks(t *testing.T) {
+	t.Parallel()
+
+	stacks := []Stack{
+		{Name: "simple-web-app", Dir: "examples/simple-web-app"},
+		{Name: "data-processing-pipeline", Dir: "examples/data-processing-pipeline"},
+		{Name: "microservices-platform", Dir: "examples/microservices-platform"},
+	}
+
+	report := Check(context.Background(), stacks, Recorded{Dir: filepath.Join("testdata", "plans")}, 2)
+
+	require.Len(t, report.Stacks, 3)
+	assert.Equal(t, stacks[0], report.Stacks[0].Stack)
+	assert.Len(t, report.Stacks[0].Resources, 5)
+	assert.Equal(t, 2, report.Stacks[0].Count(ClassReplacement))
+	assert.Equal(t, 3, report.Stacks[0].Count(ClassSecurity))
+	assert.Equal(t, 1, report.Stacks[0].Count(ClassTags))
+	assert.Empty(t, report.Stacks[1].Resources)
+	assert.Empty(t, report.Stacks[1].Error)
+	assert.Contains(t, report.Stacks[2].Error, "microservices-platform.json")
+	assert.True(t, report.Drifted())
+	assert.True(t, report.Failed())
+}
+
+func TestCheckLimitsParallelPlans(t *testing.T) {
+	t.Parallel()
+
+	planner := &countingPlanner{}
+	stacks := make([]Stack, 8)
+	for i := range stacks {
+		stacks[i] = Stack{Name: string(rune('a' + i))}
+	}
+
+	report := Check(context.Background(), stacks, planner, 3)
+
+	assert.LessOrEqual(t, planner.peak, 3)
+	assert.Greater(t, planner.peak, 1)
+	for i, stack := range report.Stacks {
+		assert.Equal(t, stacks[i].Name, stack.Name)
+		assert.Equal(t, "no plan for "+stacks[i].Name, stack.Error)
+	}
+	assert.False(t, report.Drifted())
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	t.Parallel()
+
+	report := Check(context.Background(), []Stack{
+		{Name: "simple-web-app", Dir: "examples/simple-web-app"},
+		{Name: "data-processing-pipeline", Dir: "examples/data-processing-pipeline"},
+		{Name: "microservices-platform", Dir: "examples/microservices-platform"},
+	}, Recorded{Dir: filepath.Join("testdata", "plans")}, 1)
+
+	var markdown bytes.Buffer
+	require.NoError(t, WriteMarkdown(&markdown, report))
+	text := markdown.String()
+
+	assert.Contains(t, text, "| simple-web-app | 5 | 2 | 3 | 1 |\n")
+	assert.Contains(t, text, "| data-processing-pipeline | 0 | 0 | 0 | 0 |\n")
+	assert.Contains(t, text, "| microservices-platform | failed | | | |\n")
+	assert.Contains(t, text, "### `module.web_server[0].aws_instance.main[0]`\n\nClasses: replacement, security\n")
+	assert.Contains(t, text, "| `metadata_options[0].http_tokens` | `\"required\"` | `\"optional\"` | security |\n")
+	assert.Contains(t, text, "| `password` | (sensitive) | (sensitive) | security |\n")
+	assert.Contains(t, text, "Deleted outside Terraform.")
+	assert.Contains(t, text, "## data-processing-pipeline\n\nDirectory: `examples/data-processing-pipeline`\n\nNo drift.\n")
+	assert.NotContains(t, text, "RecordedPassword1!")
+}
+
+func TestCodeSpanEscapesTableCells(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "`a\\|b`", codeSpan("a|b"))
+	assert.Equal(t, "`` echo `id` ``", codeSpan("echo `id`"))
+}
+
+// countingPlanner fails every plan and records how many plans ran at once
+type countingPlanner struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (p *countingPlanner) Plan(ctx context.Context, stack Stack) ([]byte, error) {
+	p.mu.Lock()
+	p.running++
+	if p.running > p.peak {
+		p.peak = p.running
+	}
+	p.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	p.mu.Lock()
+	p.running--
+	p.mu.Unlock()
+	return nil, errors.New("no plan for " + stack.Name)
+}
+
+// parseFixture parses a recorded plan from testdata/plans
+func parseFixture(t *testing.T, name string) []Resource {
+	plan, err := os.ReadFile(filepath.Join("testdata", "plans", name+".json"))
+	require.NoError(t, err)
+
+	resources, err := Parse(plan)
+	require.NoError(t, err)
+	return resources
+}
+
+// attributePaths returns the paths of a resource's changed attributes
+func attributePaths(resource Resource) []string {
+	paths := []string{}
+	for _, attribute := range resource.Attributes {
+		paths = append(paths, attribute.Path)
+	}
+	return paths
+}
@@ -0,0 +1,151 @@
+package drift
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxMarkdownValue is the length at which values are cut short in Markdown
+// reports. JSON reports hold them in full.
+const MaxMarkdownValue = 120
+
+// StackReport is the drift found in one stack
+type StackReport struct {
+	Stack
+
+	// Error is set when the stack could not be planned
+	Error string `json:"error,omitempty"`
+
+	Resources []Resource `json:"resources"`
+}
+
+// Count returns how many of the stack's drifted resources have a change of
+// the given class
+func (s StackReport) Count(class Class) int {
+	count := 0
+	for _, resource := range s.Resources {
+		if resource.HasClass(class) {
+			count++
+		}
+	}
+	return count
+}
+
+// Report is the drift found in a set of stacks
+type Report struct {
+	Stacks []StackReport `json:"stacks"`
+}
+
+// Drifted reports whether any resource of any stack drifted
+func (r *Report) Drifted() bool {
+	for _, stack := range r.Stacks {
+		if len(stack.Resources) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Failed reports whether any stack could not be planned
+func (r *Report) Failed() bool {
+	for _, stack := range r.Stacks {
+		if stack.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteJSON writes the report as indented JSON
+func WriteJSON(w io.Writer, report *Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// WriteMarkdown writes the report as a summary table followed by a table of
+// the changed attributes of every drifted resource
+func WriteMarkdown(w io.Writer, report *Report) error {
+	var b strings.Builder
+	b.WriteString("# Drift Report\n\n")
+	b.WriteString("| Stack | Drifted resources | Replacement | Security | Tags |\n")
+	b.WriteString("|-------|-------------------|-------------|----------|------|\n")
+	for _, stack := range report.Stacks {
+		if stack.Error != "" {
+			fmt.Fprintf(&b, "| %s | failed | | | |\n", stack.Name)
+			continue
+		}
+		fmt.Fprintf(&b, "| %s | %d | %d | %d | %d |\n", stack.Name, len(stack.Resources),
+			stack.Count(ClassReplacement), stack.Count(ClassSecurity), stack.Count(ClassTags))
+	}
+
+	for _, stack := range report.Stacks {
+		fmt.Fprintf(&b, "\n## %s\n\n", stack.Name)
+		fmt.Fprintf(&b, "Directory: `%s`\n\n", stack.Dir)
+		switch {
+		case stack.Error != "":
+			fmt.Fprintf(&b, "The refresh-only plan failed:\n\n```\n%s\n```\n", stack.Error)
+			continue
+		case len(stack.Resources) == 0:
+			b.WriteString("No drift.\n")
+			continue
+		}
+
+		for _, resource := range stack.Resources {
+			fmt.Fprintf(&b, "### `%s`\n\n", resource.Address)
+			fmt.Fprintf(&b, "Classes: %s\n\n", joinClasses(resource.Classes))
+			if resource.Action == ActionDelete {
+				b.WriteString("Deleted outside Terraform. The next apply creates it again.\n\n")
+				continue
+			}
+
+			b.WriteString("| Attribute | Before | After | Classes |\n")
+			b.WriteString("|-----------|--------|-------|---------|\n")
+			for _, attribute := range resource.Attributes {
+				before, after := "(sensitive)", "(sensitive)"
+				if !attribute.Sensitive {
+					before, after = markdownValue(attribute.Before), markdownValue(attribute.After)
+				}
+				fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", codeSpan(attribute.Path), before, after, joinClasses(attribute.Classes))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownValue formats a value as compact JSON in a code span, cut short at
+// MaxMarkdownValue
+func markdownValue(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return codeSpan(fmt.Sprint(value))
+	}
+	text := []rune(string(encoded))
+	if len(text) > MaxMarkdownValue {
+		return codeSpan(string(text[:MaxMarkdownValue]) + "...")
+	}
+	return codeSpan(string(text))
+}
+
+// codeSpan wraps text in a code span that is safe inside a table cell
+func codeSpan(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	if strings.Contains(text, "`") {
+		return "`` " + text + " ``"
+	}
+	return "`" + text + "`"
+}
+
+// joinClasses joins classes with commas
+func joinClasses(classes []Class) string {
+	names := make([]string, len(classes))
+	for i, class := range classes {
+		names[i] = string(class)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,123 @@
+package drift
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Stack is a terraform root module with deployed state, such as
+// examples/simple-web-app
+type Stack struct {
+	Name string `json:"name"`
+	Dir  string `json:"dir"`
+}
+
+// Planner returns a refresh-only plan of a stack in the format of terraform
+// show -json
+type Planner interface {
+	Plan(ctx context.Context, stack Stack) ([]byte, error)
+}
+
+// Terraform plans stacks with the terraform binary
+type Terraform struct {
+	// Binary is the terraform executable, terraform from PATH by default
+	Binary string
+}
+
+// Plan runs init, a refresh-only plan and show in the stack directory. The
+// plan takes no lock since it doesn't write state.
+func (t Terraform) Plan(ctx context.Context, stack Stack) ([]byte, error) {
+	planDir, err := os.MkdirTemp("", "drift-"+stack.Name+"-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(planDir)
+	planFile := filepath.Join(planDir, "drift.tfplan")
+
+	commands := [][]string{
+		{"init", "-input=false", "-no-color"},
+		{"plan", "-refresh-only", "-input=false", "-no-color", "-lock=false", "-out=" + planFile},
+	}
+	for _, args := range commands {
+		if _, err := t.run(ctx, stack.Dir, args...); err != nil {
+			return nil, err
+		}
+	}
+	return t.run(ctx, stack.Dir, "show", "-json", planFile)
+}
+
+// run runs a terraform command in dir and returns its standard output
+func (t Terraform) run(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	binary := t.Binary
+	if binary == "" {
+		binary = "terraform"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, append([]string{"-chdir=" + dir}, args...)...)
+	cmd.Env = append(os.Environ(), "TF_IN_AUTOMATION=1")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("terraform %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// Recorded replays plans recorded earlier with terraform show -json, read
+// from <Dir>/<stack name>.json
+type Recorded struct {
+	Dir string
+}
+
+// Plan reads the recorded plan of a stack
+func (r Recorded) Plan(ctx context.Context, stack Stack) ([]byte, error) {
+	return os.ReadFile(filepath.Join(r.Dir, stack.Name+".json"))
+}
+
+// Check plans the stacks with up to parallelism plans at a time and reports
+// their drift in the order of stacks. A stack that fails to plan is reported
+// with its error and doesn't stop the others.
+func Check(ctx context.Context, stacks []Stack, planner Planner, parallelism int) *Report {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	report := &Report{Stacks: make([]StackReport, len(stacks))}
+	slots := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, stack := range stacks {
+		wg.Add(1)
+		go func(i int, stack Stack) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			report.Stacks[i] = checkStack(ctx, stack, planner)
+		}(i, stack)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// checkStack plans one stack and parses its drift
+func checkStack(ctx context.Context, stack Stack, planner Planner) StackReport {
+	result := StackReport{Stack: stack, Resources: []Resource{}}
+
+	plan, err := planner.Plan(ctx, stack)
+	if err == nil {
+		result.Resources, err = Parse(plan)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.Resources = []Resource{}
+	}
+	return result
+}
@@ -0,0 +1,318 @@
+package test
+
+import (
+	"fmt"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEC2InstanceMetadataOptions(t *testing.T) {
+	t.Parallel()
+
+	instanceName := fmt.Sprintf("test-ec2-imds-%d", time.Now().Unix())
+
+	region := "us-east-1"
+	ec2Client := createEC2Client(t, region)
+	subnetID, securityGroupID := getDefaultSubnetAndSecurityGroup(t, ec2Client)
+
+	terraformOptions := createModuleOptions(t, "ec2-instance", region, map[string]interface{}{
+		"instance_name":        instanceName,
+		"subnet_id":            subnetID,
+		"security_group_ids":   []string{securityGroupID},
+		"require_imdsv2":       true,
+		"metadata_hop_limit":   2,
+		"enable_metadata_tags": true,
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	instanceID := terraform.Output(t, terraformOptions, "instance_id")
+	instance := getEC2Instance(t, ec2Client, instanceID)
+
+	require.NotNil(t, instance.MetadataOptions)
+	assert.Equal(t, "enabled", *instance.MetadataOptions.HttpEndpoint)
+	assert.Equal(t, "required", *instance.MetadataOptions.HttpTokens)
+	assert.Equal(t, int64(2), *instance.MetadataOptions.HttpPutResponseHopLimit)
+	assert.Equal(t, "enabled", *instance.MetadataOptions.InstanceMetadataTags)
+}
+
+func TestEC2InstanceEBSVolumes(t *testing.T) {
+	t.Parallel()
+
+	instanceName := fmt.Sprintf("test-ec2-ebs-%d", time.Now().Unix())
+
+	region := "us-east-1"
+	ec2Client := createEC2Client(t, region)
+	subnetID, securityGroupID := getDefaultSubnetAndSecurityGroup(t, ec2Client)
+
+	terraformOptions := createModuleOptions(t, "ec2-instance", region, map[string]interface{}{
+		"instance_name":         instanceName,
+		"subnet_id":             subnetID,
+		"security_group_ids":    []string{securityGroupID},
+		"root_volume_type":      "gp3",
+		"root_volume_size":      20,
+		"root_volume_encrypted": true,
+		"ebs_volumes": []map[string]interface{}{
+			{
+				"device_name":           "/dev/sdf",
+				"volume_type":           "gp3",
+				"volume_size":           10,
+				"throughput":            125,
+				"delete_on_termination": true,
+				"encrypted":             true,
+			},
+			{
+				"device_name":           "/dev/sdg",
+				"volume_type":           "gp2",
+				"volume_size":           8,
+				"delete_on_termination": true,
+				"encrypted":             true,
+			},
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	instanceID := terraform.Output(t, terraformOptions, "instance_id")
+	instance := getEC2Instance(t, ec2Client, instanceID)
+	volumes := getInstanceVolumes(t, ec2Client, instanceID)
+
+	expected := map[string]struct {
+		volumeType string
+		size       int64
+	}{
+		*instance.RootDeviceName: {"gp3", 20},
+		"/dev/sdf":               {"gp3", 10},
+		"/dev/sdg":               {"gp2", 8},
+	}
+
+	assert.Len(t, volumes, len(expected))
+	for deviceName, want := range expected {
+		volume, ok := volumes[deviceName]
+		if !assert.True(t, ok, "no volume attached at %s", deviceName) {
+			continue
+		}
+		assert.True(t, *volume.Encrypted, "volume at %s is not encrypted", deviceName)
+		assert.Equal(t, want.volumeType, *volume.VolumeType, "volume type at %s", deviceName)
+		assert.Equal(t, want.size, *volume.Size, "volume size at %s", deviceName)
+	}
+}
+
+func TestEC2InstanceElasticIP(t *testing.T) {
+	t.Parallel()
+
+	instanceName := fmt.Sprintf("test-ec2-eip-%d", time.Now().Unix())
+
+	region := "us-east-1"
+	ec2Client := createEC2Client(t, region)
+	subnetID, securityGroupID := getDefaultSubnetAndSecurityGroup(t, ec2Client)
+
+	terraformOptions := createModuleOptions(t, "ec2-instance", region, map[string]interface{}{
+		"instance_name":      instanceName,
+		"subnet_id":          subnetID,
+		"security_group_ids": []string{securityGroupID},
+		"create_eip":         true,
+		"tags": map[string]string{
+			"Environment": "test",
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	instanceID := terraform.Output(t, terraformOptions, "instance_id")
+	eipPublicIP := terraform.Output(t, terraformOptions, "eip_public_ip")
+	assert.NotEmpty(t, eipPublicIP)
+
+	result, err := ec2Client.DescribeAddresses(&ec2.DescribeAddressesInput{
+		PublicIps: []*string{aws.String(eipPublicIP)},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Addresses, 1)
+
+	address := result.Addresses[0]
+	assert.Equal(t, "vpc", *address.Domain)
+	assert.Equal(t, instanceID, aws.StringValue(address.InstanceId))
+	assert.NotEmpty(t, aws.StringValue(address.AssociationId))
+
+	tags := convertEC2TagsToMap(address.Tags)
+	assert.Equal(t, fmt.Sprintf("%s-eip", instanceName), tags["Name"])
+	assert.Equal(t, "test", tags["Environment"])
+}
+
+func TestEC2InstanceAMILookup(t *testing.T) {
+	t.Parallel()
+
+	instanceName := fmt.Sprintf("test-ec2-ami-%d", time.Now().Unix())
+	amiOwner := "137112412989" // Amazon Linux images are published from this account
+	amiNameFilter := "al2023-ami-2023.*-x86_64"
+
+	region := "us-east-1"
+	ec2Client := createEC2Client(t, region)
+	subnetID, securityGroupID := getDefaultSubnetAndSecurityGroup(t, ec2Client)
+
+	terraformOptions := createModuleOptions(t, "ec2-instance", region, map[string]interface{}{
+		"instance_name":      instanceName,
+		"subnet_id":          subnetID,
+		"security_group_ids": []string{securityGroupID},
+		"ami_owner":          amiOwner,
+		"ami_name_filter":    amiNameFilter,
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	amiID := terraform.Output(t, terraformOptions, "ami_id")
+
+	result, err := ec2Client.DescribeImages(&ec2.DescribeImagesInput{
+		ImageIds: []*string{aws.String(amiID)},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Images, 1)
+
+	image := result.Images[0]
+	assert.Equal(t, amiOwner, *image.OwnerId)
+	assert.Equal(t, "hvm", *image.VirtualizationType)
+
+	matched, err := path.Match(amiNameFilter, *image.Name)
+	require.NoError(t, err)
+	assert.True(t, matched, "AMI name %s does not match filter %s", *image.Name, amiNameFilter)
+
+	instanceID := terraform.Output(t, terraformOptions, "instance_id")
+	instance := getEC2Instance(t, ec2Client, instanceID)
+	assert.Equal(t, amiID, *instance.ImageId)
+}
+
+// TestEC2InstanceIgnoreAMIChanges plans the module with and without
+// ignore_ami_changes, which picks which of the two instance resources exists
+func TestEC2InstanceIgnoreAMIChanges(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		ignoreAMI bool
+		planned   string
+		skipped   string
+	}{
+		{"ami changes replace the instance", false, "aws_instance.main[0]", "aws_instance.ami_ignored[0]"},
+		{"ami changes ignored", true, "aws_instance.ami_ignored[0]", "aws_instance.main[0]"},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			name := fmt.Sprintf("test-ec2-ignore-ami-%d", time.Now().Unix())
+			plan := planModule(t, "ec2-instance", "us-east-1", minimalPlanVars(t, "ec2-instance", name, map[string]interface{}{
+				"ignore_ami_changes": testCase.ignoreAMI,
+				"create_eip":         true,
+			}))
+
+			assert.True(t, isResourcePlanned(plan, testCase.planned), "%s is not planned", testCase.planned)
+			assert.False(t, isResourcePlanned(plan, testCase.skipped), "%s is planned", testCase.skipped)
+			assert.True(t, isResourcePlanned(plan, "aws_eip.main[0]"))
+		})
+	}
+}
+
+func getEC2Instance(t *testing.T, client *ec2.EC2, instanceID string) *ec2.Instance {
+	input := &ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(instanceID)},
+	}
+
+	result, err := client.DescribeInstances(input)
+	require.NoError(t, err)
+	require.Len(t, result.Reservations, 1)
+	require.Len(t, result.Reservations[0].Instances, 1)
+
+	return result.Reservations[0].Instances[0]
+}
+
+// getInstanceVolumes returns the volumes attached to an instance keyed by
+// device name
+func getInstanceVolumes(t *testing.T, client *ec2.EC2, instanceID string) map[string]*ec2.Volume {
+	input := &ec2.DescribeVolumesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("attachment.instance-id"),
+				Values: []*string{aws.String(instanceID)},
+			},
+		},
+	}
+
+	result, err := client.DescribeVolumes(input)
+	require.NoError(t, err)
+
+	volumes := make(map[string]*ec2.Volume)
+	for _, volume := range result.Volumes {
+		for _, attachment := range volume.Attachments {
+			if aws.StringValue(attachment.InstanceId) == instanceID {
+				volumes[aws.StringValue(attachment.Device)] = volume
+			}
+		}
+	}
+
+	return volumes
+}
+
+// getDefaultSubnetAndSecurityGroup returns a default subnet and the default
+// security group of the region's default VPC to launch test instances into
+func getDefaultSubnetAndSecurityGroup(t *testing.T, client *ec2.EC2) (string, string) {
+	vpcs, err := client.DescribeVpcs(&ec2.DescribeVpcsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("is-default"),
+				Values: []*string{aws.String("true")},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, vpcs.Vpcs, "region has no default VPC")
+	vpcID := vpcs.Vpcs[0].VpcId
+
+	subnets, err := client.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{vpcID},
+			},
+			{
+				Name:   aws.String("default-for-az"),
+				Values: []*string{aws.String("true")},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, subnets.Subnets, "default VPC has no default subnets")
+
+	securityGroups, err := client.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{vpcID},
+			},
+			{
+				Name:   aws.String("group-name"),
+				Values: []*string{aws.String("default")},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, securityGroups.SecurityGroups, 1)
+
+	return *subnets.Subnets[0].SubnetId, *securityGroups.SecurityGroups[0].GroupId
+}
@@ -1,16 +1,28 @@
 package test
 
 import (
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/hex"
 	"fmt"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// eksTestClusterVersion is the Kubernetes version used by tests that run
+// against modules/eks
+const eksTestClusterVersion = "1.33"
+
 func TestEKSClusterCreation(t *testing.T) {
 	t.Parallel()
 
@@ -23,10 +35,6 @@ func TestEKSClusterCreation(t *testing.T) {
 			"cluster_version":    "1.28",
 			"environment":        "test",
 			"availability_zones": []string{"us-east-1a", "us-east-1b"},
-			"tags": map[string]string{
-				"Project": "Infrastructure-Test",
-				"Owner":   "DevOps-Team",
-			},
 		},
 	})
 
@@ -35,75 +43,161 @@ func TestEKSClusterCreation(t *testing.T) {
 	terraform.InitAndApply(t, terraformOptions)
 
 	clusterID := terraform.Output(t, terraformOptions, "cluster_id")
+	assert.Equal(t, clusterName, clusterID)
+
+	clusterEndpoint := terraform.Output(t, terraformOptions, "cluster_endpoint")
+	assert.NotEmpty(t, clusterEndpoint)
+
 	region := "us-east-1"
 	eksClient := createEKSClient(t, region)
 
-	cluster := getEKSCluster(t, eksClient, clusterID)
-	assert.Equal(t, "test", cluster.Tags["Environment"])
-	assert.Equal(t, "Infrastructure-Test", cluster.Tags["Project"])
-	assert.Equal(t, "DevOps-Team", cluster.Tags["Owner"])
+	cluster := getEKSCluster(t, eksClient, clusterName)
+	assert.Equal(t, "ACTIVE", *cluster.Status)
+	assert.Equal(t, "1.28", *cluster.Version)
 }
 
-func TestEKSPublicAndPrivateAccess(t *testing.T) {
+func TestEKSNodeGroup(t *testing.T) {
 	t.Parallel()
 
-	clusterName := fmt.Sprintf("test-eks-access-%d", time.Now().Unix())
-
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../terraform/eks",
-		Vars: map[string]interface{}{
-			"cluster_name":                  clusterName,
-			"cluster_version":               "1.28",
-			"endpoint_public_access":        true,
-			"endpoint_private_access":       true,
-			"public_access_cidrs":           []string{"10.0.0.0/8"},
-			"environment":                   "test",
-			"availability_zones":            []string{"us-east-1a", "us-east-1b"},
-		},
-	})
-
-	defer terraform.Destroy(t, terraformOptions)
-
-	terraform.InitAndApply(t, terraformOptions)
+	clusterName := fmt.Sprintf("test-eks-ng-%d", time.Now().Unix())
 
-	clusterID := terraform.Output(t, terraformOptions, "cluster_id")
 	region := "us-east-1"
 	eksClient := createEKSClient(t, region)
+	ec2Client := createEC2Client(t, region)
+
+	// Nodes in private subnets reach the API server and ECR through NAT
+	networkOptions := createEKSTestNetwork(t, clusterName, region, true)
+	defer terraform.Destroy(t, networkOptions)
+	terraform.InitAndApply(t, networkOptions)
+
+	vpcID := terraform.Output(t, networkOptions, "vpc_id")
+	publicSubnets := terraform.OutputList(t, networkOptions, "public_subnet_ids")
+	privateSubnets := terraform.OutputList(t, networkOptions, "private_subnet_ids")
+
+	launchTemplate, err := ec2Client.CreateLaunchTemplate(&ec2.CreateLaunchTemplateInput{
+		LaunchTemplateName: aws.String(fmt.Sprintf("%s-templated", clusterName)),
+		LaunchTemplateData: &ec2.RequestLaunchTemplateData{
+			MetadataOptions: &ec2.LaunchTemplateInstanceMetadataOptionsRequest{
+				HttpTokens:              aws.String("required"),
+				HttpPutResponseHopLimit: aws.Int64(2),
+			},
+			BlockDeviceMappings: []*ec2.LaunchTemplateBlockDeviceMappingRequest{
+				{
+					DeviceName: aws.String("/dev/xvda"),
+					Ebs: &ec2.LaunchTemplateEbsBlockDeviceRequest{
+						VolumeSize: aws.Int64(30),
+						VolumeType: aws.String("gp3"),
+						Encrypted:  aws.Bool(true),
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	launchTemplateID := aws.StringValue(launchTemplate.LaunchTemplate.LaunchTemplateId)
+	launchTemplateVersion := fmt.Sprintf("%d", aws.Int64Value(launchTemplate.LaunchTemplate.LatestVersionNumber))
+	defer ec2Client.DeleteLaunchTemplate(&ec2.DeleteLaunchTemplateInput{
+		LaunchTemplateId: aws.String(launchTemplateID),
+	})
 
-	cluster := getEKSCluster(t, eksClient, clusterID)
-	assert.True(t, *cluster.ResourcesVpcConfig.EndpointPublicAccess)
-	assert.True(t, *cluster.ResourcesVpcConfig.EndpointPrivateAccess)
-}
-
-func createEKSClient(t *testing.T, region string) *eks.EKS {
-	sess := createAWSSession(t, region)
-	return eks.New(sess)
-}
-
-func getEKSCluster(t *testing.T, client *eks.EKS, clusterName string) *eks.Cluster {
-	input := &eks.DescribeClusterInput{
-		Name: aws.String(clusterName),
+	type expectedTaint struct {
+		key    string
+		value  string
+		effect string
 	}
 
-	result, err := client.DescribeCluster(input)
-	assert.NoError(t, err)
-	assert.NotNil(t, result.Cluster)
-
-	return result.Cluster
-}
-
-func getEKSNodeGroup(t *testing.T, client *eks.EKS, clusterName, nodeGroupName string) *eks.Nodegroup {
-	input := &eks.DescribeNodegroupInput{
-		ClusterName:   aws.String(clusterName),
-		NodegroupName: aws.String(nodeGroupName),
+	expected := map[string]struct {
+		capacityType      string
+		instanceTypes     []string
+		subnets           []string
+		labels            map[string]string
+		taints            []expectedTaint
+		maxUnavailablePct int64
+		desired, min, max int64
+	}{
+		"general": {
+			capacityType:      "ON_DEMAND",
+			instanceTypes:     []string{"t3.medium"},
+			subnets:           privateSubnets,
+			labels:            map[string]string{"role": "general"},
+			maxUnavailablePct: 50,
+			desired:           2, min: 1, max: 3,
+		},
+		"spot": {
+			capacityType:  "SPOT",
+			instanceTypes: []string{"t3.medium", "t3a.medium"},
+			subnets:       privateSubnets[:1],
+			labels:        map[string]string{"role": "batch", "lifecycle": "spot"},
+			taints: []expectedTaint{
+				{"dedicated", "batch", "NO_SCHEDULE"},
+				{"spot", "true", "PREFER_NO_SCHEDULE"},
+			},
+			maxUnavailablePct: 33,
+			desired:           1, min: 0, max: 2,
+		},
+		"templated": {
+			capacityType:      "ON_DEMAND",
+			instanceTypes:     []string{"t3.small"},
+			subnets:           publicSubnets,
+			labels:            map[string]string{"role": "edge"},
+			taints:            []expectedTaint{{"edge", "true", "NO_EXECUTE"}},
+			maxUnavailablePct: 100,
+			desired:           1, min: 1, max: 1,
+		},
 	}
 
-	result, err := client.DescribeNodegroup(input)
-	assert.NoError(t, err)
-	assert.NotNil(t, result.Nodegroup)
-
-	return result.Nodegroup
-}
+	keyArn := createKMSKey(t, region, fmt.Sprintf("Secrets encryption for %s", clusterName))
+
+	terraformOptions := createModuleOptions(t, "eks", region, map[string]interface{}{
+		"cluster_name":               clusterName,
+		"cluster_version":            eksTestClusterVersion,
+		"vpc_id":                     vpcID,
+		"subnet_ids":                 append(append([]string{}, publicSubnets...), privateSubnets...),
+		"cluster_encryption_key_arn": keyArn,
+		"node_groups": map[string]interface{}{
+			"general": map[string]interface{}{
+				"desired_size":               2,
+				"min_size":                   1,
+				"max_size":                   3,
+				"instance_types":             []string{"t3.medium"},
+				"capacity_type":              "ON_DEMAND",
+				"disk_size":                  20,
+				"ami_type":                   "AL2023_x86_64_STANDARD",
+				"max_unavailable_percentage": 50,
+				"subnet_ids":                 privateSubnets,
+				"labels":                     map[string]string{"role": "general"},
+			},
+			"spot": map[string]interface{}{
+				"desired_size":   1,
+				"min_size":       0,
+				"max_size":       2,
+				"instance_types": []string{"t3.medium", "t3a.medium"},
+				"capacity_type":  "SPOT",
+				"disk_size":      20,
+				"ami_type":       "AL2023_x86_64_STANDARD",
+				"subnet_ids":     privateSubnets[:1],
+				"labels":         map[string]string{"role": "batch", "lifecycle": "spot"},
+				"taints": []map[string]string{
+					{"key": "dedicated", "value": "batch", "effect": "NO_SCHEDULE"},
+					{"key": "spot", "value": "true", "effect": "PREFER_NO_SCHEDULE"},
+				},
+			},
+			"templated": map[string]interface{}{
+				"desired_size":               1,
+				"min_size":                   1,
+				"max_size":                   1,
+				"instance_types":             []string{"t3.small"},
+				"capacity_type":              "ON_DEMAND",
+				"ami_type":                   "AL2023_x86_64_STANDARD",
+				"max_unavailable_percentage": 100,
+				"subnet_ids":                 publicSubnets,
+				"launch_template_id":         launchTemplateID,
+				"launch_template_version":    launchTemplateVersion,
+				"labels":                     map[string]string{"role": "edge"},
+				"taints": []map[string]string{
+					{"key": "edge", "value": "true", "effect": "NO_EXECUTE"},
+				},
+			},
 		},
 	})
 
@@ -111,54 +205,47 @@ func getEKSNodeGroup(t *testing.T, client *eks.EKS, clusterName, nodeGroupName s
 
 	terraform.InitAndApply(t, terraformOptions)
 
-	clusterID := terraform.Output(t, terraformOptions, "cluster_id")
-	assert.Equal(t, clusterName, clusterID)
+	for nodeGroupName, want := range expected {
+		nodeGroup := getEKSNodeGroup(t, eksClient, clusterName, nodeGroupName)
 
-	clusterEndpoint := terraform.Output(t, terraformOptions, "cluster_endpoint")
-	assert.NotEmpty(t, clusterEndpoint)
+		assert.Equal(t, "ACTIVE", *nodeGroup.Status, "status of %s", nodeGroupName)
+		assert.Equal(t, "AL2023_x86_64_STANDARD", aws.StringValue(nodeGroup.AmiType), "AMI type of %s", nodeGroupName)
+		assert.Equal(t, want.capacityType, aws.StringValue(nodeGroup.CapacityType), "capacity type of %s", nodeGroupName)
+		assert.ElementsMatch(t, want.instanceTypes, aws.StringValueSlice(nodeGroup.InstanceTypes), "instance types of %s", nodeGroupName)
+		assert.ElementsMatch(t, want.subnets, aws.StringValueSlice(nodeGroup.Subnets), "subnets of %s", nodeGroupName)
 
-	region := "us-east-1"
-	eksClient := createEKSClient(t, region)
+		assert.Equal(t, want.desired, *nodeGroup.ScalingConfig.DesiredSize, "desired size of %s", nodeGroupName)
+		assert.Equal(t, want.min, *nodeGroup.ScalingConfig.MinSize, "min size of %s", nodeGroupName)
+		assert.Equal(t, want.max, *nodeGroup.ScalingConfig.MaxSize, "max size of %s", nodeGroupName)
 
-	cluster := getEKSCluster(t, eksClient, clusterName)
-	assert.Equal(t, "ACTIVE", *cluster.Status)
-	assert.Equal(t, "1.28", *cluster.Version)
-}
-
-func TestEKSNodeGroup(t *testing.T) {
-	t.Parallel()
-
-	clusterName := fmt.Sprintf("test-eks-ng-%d", time.Now().Unix())
-	nodeGroupName := fmt.Sprintf("%s-ng", clusterName)
-
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../terraform/eks",
-		Vars: map[string]interface{}{
-			"cluster_name":       clusterName,
-			"cluster_version":    "1.28",
-			"node_group_name":    nodeGroupName,
-			"node_instance_types": []string{"t3.medium"},
-			"desired_size":       2,
-			"min_size":           1,
-			"max_size":           3,
-			"environment":        "test",
-			"availability_zones": []string{"us-east-1a", "us-east-1b"},
-		},
-	})
+		if assert.NotNil(t, nodeGroup.UpdateConfig, "update config of %s", nodeGroupName) {
+			assert.Equal(t, want.maxUnavailablePct, aws.Int64Value(nodeGroup.UpdateConfig.MaxUnavailablePercentage), "max unavailable percentage of %s", nodeGroupName)
+		}
 
-	defer terraform.Destroy(t, terraformOptions)
+		assert.Equal(t, want.labels, aws.StringValueMap(nodeGroup.Labels), "labels of %s", nodeGroupName)
 
-	terraform.InitAndApply(t, terraformOptions)
+		taints := []expectedTaint{}
+		for _, taint := range nodeGroup.Taints {
+			taints = append(taints, expectedTaint{aws.StringValue(taint.Key), aws.StringValue(taint.Value), aws.StringValue(taint.Effect)})
+		}
+		if want.taints == nil {
+			want.taints = []expectedTaint{}
+		}
+		assert.ElementsMatch(t, want.taints, taints, "taints of %s", nodeGroupName)
+	}
 
-	clusterID := terraform.Output(t, terraformOptions, "cluster_id")
-	region := "us-east-1"
-	eksClient := createEKSClient(t, region)
+	// Storage for the templated group comes from the launch template, so the
+	// module must not pass disk_size alongside it
+	templated := getEKSNodeGroup(t, eksClient, clusterName, "templated")
+	if assert.NotNil(t, templated.LaunchTemplate) {
+		assert.Equal(t, launchTemplateID, aws.StringValue(templated.LaunchTemplate.Id))
+		assert.Equal(t, launchTemplateVersion, aws.StringValue(templated.LaunchTemplate.Version))
+	}
+	assert.Nil(t, templated.DiskSize)
 
-	nodeGroup := getEKSNodeGroup(t, eksClient, clusterID, nodeGroupName)
-	assert.Equal(t, "ACTIVE", *nodeGroup.Status)
-	assert.Equal(t, int64(2), *nodeGroup.ScalingConfig.DesiredSize)
-	assert.Equal(t, int64(1), *nodeGroup.ScalingConfig.MinSize)
-	assert.Equal(t, int64(3), *nodeGroup.ScalingConfig.MaxSize)
+	general := getEKSNodeGroup(t, eksClient, clusterName, "general")
+	assert.Nil(t, general.LaunchTemplate)
+	assert.Equal(t, int64(20), aws.Int64Value(general.DiskSize))
 }
 
 func TestEKSClusterLogging(t *testing.T) {
@@ -186,7 +273,7 @@ func TestEKSClusterLogging(t *testing.T) {
 	eksClient := createEKSClient(t, region)
 
 	cluster := getEKSCluster(t, eksClient, clusterID)
-	
+
 	enabledTypes := make([]string, 0)
 	if cluster.Logging != nil && len(cluster.Logging.ClusterLogging) > 0 {
 		for _, logSetup := range cluster.Logging.ClusterLogging {
@@ -244,3 +331,313 @@ func TestEKSClusterTags(t *testing.T) {
 			"cluster_version":    "1.28",
 			"environment":        "test",
 			"availability_zones": []string{"us-east-1a", "us-east-1b"},
+			"tags": map[string]string{
+				"Project": "Infrastructure-Test",
+				"Owner":   "DevOps-Team",
+			},
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	clusterID := terraform.Output(t, terraformOptions, "cluster_id")
+	region := "us-east-1"
+	eksClient := createEKSClient(t, region)
+
+	cluster := getEKSCluster(t, eksClient, clusterID)
+	assert.Equal(t, "test", cluster.Tags["Environment"])
+	assert.Equal(t, "Infrastructure-Test", cluster.Tags["Project"])
+	assert.Equal(t, "DevOps-Team", cluster.Tags["Owner"])
+}
+
+func TestEKSPublicAndPrivateAccess(t *testing.T) {
+	t.Parallel()
+
+	clusterName := fmt.Sprintf("test-eks-access-%d", time.Now().Unix())
+
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: "../terraform/eks",
+		Vars: map[string]interface{}{
+			"cluster_name":            clusterName,
+			"cluster_version":         "1.28",
+			"endpoint_public_access":  true,
+			"endpoint_private_access": true,
+			"public_access_cidrs":     []string{"10.0.0.0/8"},
+			"environment":             "test",
+			"availability_zones":      []string{"us-east-1a", "us-east-1b"},
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	clusterID := terraform.Output(t, terraformOptions, "cluster_id")
+	region := "us-east-1"
+	eksClient := createEKSClient(t, region)
+
+	cluster := getEKSCluster(t, eksClient, clusterID)
+	assert.True(t, *cluster.ResourcesVpcConfig.EndpointPublicAccess)
+	assert.True(t, *cluster.ResourcesVpcConfig.EndpointPrivateAccess)
+}
+
+func TestEKSIRSAAddonsAndFargate(t *testing.T) {
+	t.Parallel()
+
+	clusterName := fmt.Sprintf("test-eks-irsa-%d", time.Now().Unix())
+
+	region := "us-east-1"
+	eksClient := createEKSClient(t, region)
+
+	networkOptions := createEKSTestNetwork(t, clusterName, region, false)
+	defer terraform.Destroy(t, networkOptions)
+	terraform.InitAndApply(t, networkOptions)
+
+	vpcID := terraform.Output(t, networkOptions, "vpc_id")
+	publicSubnets := terraform.OutputList(t, networkOptions, "public_subnet_ids")
+	privateSubnets := terraform.OutputList(t, networkOptions, "private_subnet_ids")
+
+	// Pin each addon to the default version for the cluster version so the
+	// test asserts a version EKS actually offers
+	addonVersions := map[string]string{
+		"vpc-cni":    getDefaultEKSAddonVersion(t, eksClient, "vpc-cni", eksTestClusterVersion),
+		"kube-proxy": getDefaultEKSAddonVersion(t, eksClient, "kube-proxy", eksTestClusterVersion),
+	}
+	clusterAddons := map[string]interface{}{}
+	for addonName, version := range addonVersions {
+		clusterAddons[addonName] = map[string]interface{}{"addon_version": version}
+	}
+
+	fargateSelectors := map[string][]map[string]interface{}{
+		"system": {
+			{"namespace": "kube-system", "labels": map[string]string{"k8s-app": "kube-dns"}},
+		},
+		"apps": {
+			{"namespace": "apps"},
+			{"namespace": "batch", "labels": map[string]string{"tier": "jobs"}},
+		},
+	}
+	fargateProfiles := map[string]interface{}{}
+	for profileName, selectors := range fargateSelectors {
+		fargateProfiles[profileName] = map[string]interface{}{
+			"subnet_ids": privateSubnets,
+			"selectors":  selectors,
+		}
+	}
+
+	keyArn := createKMSKey(t, region, fmt.Sprintf("Secrets encryption for %s", clusterName))
+
+	terraformOptions := createModuleOptions(t, "eks", region, map[string]interface{}{
+		"cluster_name":               clusterName,
+		"cluster_version":            eksTestClusterVersion,
+		"vpc_id":                     vpcID,
+		"subnet_ids":                 append(append([]string{}, publicSubnets...), privateSubnets...),
+		"cluster_encryption_key_arn": keyArn,
+		"enable_irsa":                true,
+		"cluster_addons":             clusterAddons,
+		"fargate_profiles":           fargateProfiles,
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	cluster := getEKSCluster(t, eksClient, clusterName)
+	require.NotNil(t, cluster.Identity)
+	require.NotNil(t, cluster.Identity.Oidc)
+	issuer := aws.StringValue(cluster.Identity.Oidc.Issuer)
+
+	t.Run("IRSA", func(t *testing.T) {
+		iamClient := iam.New(createAWSSession(t, region))
+
+		issuerHost := strings.TrimPrefix(issuer, "https://")
+		assert.Equal(t, issuerHost, terraform.Output(t, terraformOptions, "oidc_provider_url"))
+
+		providerArn := terraform.Output(t, terraformOptions, "oidc_provider_arn")
+		assert.True(t, strings.HasSuffix(providerArn, ":oidc-provider/"+issuerHost), "provider ARN %s does not name issuer %s", providerArn, issuerHost)
+
+		provider, err := iamClient.GetOpenIDConnectProvider(&iam.GetOpenIDConnectProviderInput{
+			OpenIDConnectProviderArn: aws.String(providerArn),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, issuerHost, aws.StringValue(provider.Url))
+		assert.Equal(t, []string{"sts.amazonaws.com"}, aws.StringValueSlice(provider.ClientIDList))
+
+		thumbprints := []string{}
+		for _, thumbprint := range provider.ThumbprintList {
+			thumbprints = append(thumbprints, strings.ToLower(aws.StringValue(thumbprint)))
+		}
+		assert.Contains(t, thumbprints, getOIDCIssuerThumbprint(t, issuer))
+	})
+
+	t.Run("Addons", func(t *testing.T) {
+		for addonName, version := range addonVersions {
+			addon := waitForEKSAddonStatus(t, eksClient, clusterName, addonName, eks.AddonStatusActive, 10*time.Minute)
+			assert.Equal(t, version, aws.StringValue(addon.AddonVersion), "version of addon %s", addonName)
+		}
+	})
+
+	t.Run("FargateProfiles", func(t *testing.T) {
+		podExecutionRoleArn := terraform.Output(t, terraformOptions, "fargate_pod_execution_role_arn")
+		require.NotEmpty(t, podExecutionRoleArn)
+
+		for profileName, selectors := range fargateSelectors {
+			profile := getEKSFargateProfile(t, eksClient, clusterName, profileName)
+			assert.Equal(t, eks.FargateProfileStatusActive, aws.StringValue(profile.Status), "status of profile %s", profileName)
+			assert.Equal(t, podExecutionRoleArn, aws.StringValue(profile.PodExecutionRoleArn), "pod execution role of profile %s", profileName)
+			assert.ElementsMatch(t, privateSubnets, aws.StringValueSlice(profile.Subnets), "subnets of profile %s", profileName)
+
+			expected := map[string]map[string]string{}
+			for _, selector := range selectors {
+				labels, _ := selector["labels"].(map[string]string)
+				if labels == nil {
+					labels = map[string]string{}
+				}
+				expected[selector["namespace"].(string)] = labels
+			}
+
+			actual := map[string]map[string]string{}
+			for _, selector := range profile.Selectors {
+				actual[aws.StringValue(selector.Namespace)] = aws.StringValueMap(selector.Labels)
+			}
+			assert.Equal(t, expected, actual, "selectors of profile %s", profileName)
+		}
+
+		iamClient := iam.New(createAWSSession(t, region))
+		roleName := podExecutionRoleArn[strings.LastIndex(podExecutionRoleArn, "/")+1:]
+
+		role, err := iamClient.GetRole(&iam.GetRoleInput{RoleName: aws.String(roleName)})
+		require.NoError(t, err)
+		trustPolicy, err := url.QueryUnescape(aws.StringValue(role.Role.AssumeRolePolicyDocument))
+		require.NoError(t, err)
+		assert.Contains(t, trustPolicy, "eks-fargate-pods.amazonaws.com")
+
+		attached, err := iamClient.ListAttachedRolePolicies(&iam.ListAttachedRolePoliciesInput{RoleName: aws.String(roleName)})
+		require.NoError(t, err)
+		policyNames := []string{}
+		for _, policy := range attached.AttachedPolicies {
+			policyNames = append(policyNames, aws.StringValue(policy.PolicyName))
+		}
+		assert.Contains(t, policyNames, "AmazonEKSFargatePodExecutionRolePolicy")
+	})
+}
+
+func createEKSClient(t *testing.T, region string) *eks.EKS {
+	sess := createAWSSession(t, region)
+	return eks.New(sess)
+}
+
+func getEKSCluster(t *testing.T, client *eks.EKS, clusterName string) *eks.Cluster {
+	input := &eks.DescribeClusterInput{
+		Name: aws.String(clusterName),
+	}
+
+	result, err := client.DescribeCluster(input)
+	assert.NoError(t, err)
+	assert.NotNil(t, result.Cluster)
+
+	return result.Cluster
+}
+
+func getEKSNodeGroup(t *testing.T, client *eks.EKS, clusterName, nodeGroupName string) *eks.Nodegroup {
+	input := &eks.DescribeNodegroupInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodeGroupName),
+	}
+
+	result, err := client.DescribeNodegroup(input)
+	assert.NoError(t, err)
+	assert.NotNil(t, result.Nodegroup)
+
+	return result.Nodegroup
+}
+
+func getEKSFargateProfile(t *testing.T, client *eks.EKS, clusterName, profileName string) *eks.FargateProfile {
+	input := &eks.DescribeFargateProfileInput{
+		ClusterName:        aws.String(clusterName),
+		FargateProfileName: aws.String(profileName),
+	}
+
+	result, err := client.DescribeFargateProfile(input)
+	require.NoError(t, err)
+	require.NotNil(t, result.FargateProfile)
+
+	return result.FargateProfile
+}
+
+// waitForEKSAddonStatus polls an addon until it reaches status or the timeout
+// expires, and returns the last description
+func waitForEKSAddonStatus(t *testing.T, client *eks.EKS, clusterName, addonName, status string, timeout time.Duration) *eks.Addon {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		result, err := client.DescribeAddon(&eks.DescribeAddonInput{
+			ClusterName: aws.String(clusterName),
+			AddonName:   aws.String(addonName),
+		})
+		require.NoError(t, err)
+
+		if aws.StringValue(result.Addon.Status) == status {
+			return result.Addon
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Addon %s is %s, not %s, after %s", addonName, aws.StringValue(result.Addon.Status), status, timeout)
+		}
+		time.Sleep(15 * time.Second)
+	}
+}
+
+// getDefaultEKSAddonVersion returns the version EKS installs by default for an
+// addon on the given Kubernetes version
+func getDefaultEKSAddonVersion(t *testing.T, client *eks.EKS, addonName, kubernetesVersion string) string {
+	result, err := client.DescribeAddonVersions(&eks.DescribeAddonVersionsInput{
+		AddonName:         aws.String(addonName),
+		KubernetesVersion: aws.String(kubernetesVersion),
+	})
+	require.NoError(t, err)
+
+	for _, addon := range result.Addons {
+		for _, version := range addon.AddonVersions {
+			for _, compatibility := range version.Compatibilities {
+				if aws.BoolValue(compatibility.DefaultVersion) {
+					return aws.StringValue(version.AddonVersion)
+				}
+			}
+		}
+	}
+
+	t.Fatalf("No default %s version for Kubernetes %s", addonName, kubernetesVersion)
+	return ""
+}
+
+// getOIDCIssuerThumbprint returns the SHA-1 fingerprint of the top certificate
+// in the chain served by an OIDC issuer, which is what IAM expects as the
+// provider thumbprint
+func getOIDCIssuerThumbprint(t *testing.T, issuer string) string {
+	issuerURL, err := url.Parse(issuer)
+	require.NoError(t, err)
+
+	conn, err := tls.Dial("tcp", issuerURL.Hostname()+":443", &tls.Config{ServerName: issuerURL.Hostname()})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	certificates := conn.ConnectionState().PeerCertificates
+	require.NotEmpty(t, certificates)
+
+	fingerprint := sha1.Sum(certificates[len(certificates)-1].Raw)
+	return hex.EncodeToString(fingerprint[:])
+}
+
+// createEKSTestNetwork returns options for a two-AZ VPC built with modules/vpc
+// for EKS tests to launch clusters into. The caller applies and destroys it.
+func createEKSTestNetwork(t *testing.T, name string, region string, enableNATGateway bool) *terraform.Options {
+	vars := createVPCVars(t, name, "10.0.0.0/16", []string{region + "a", region + "b"})
+	vars["enable_nat_gateway"] = enableNATGateway
+	vars["tags"] = map[string]string{
+		"kubernetes.io/cluster/" + name: "shared",
+	}
+
+	return createModuleOptions(t, "vpc", region, vars)
+}
@@ -0,0 +1,187 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElastiCacheRedisReplicationGroup(t *testing.T) {
+	t.Parallel()
+
+	replicationGroupID := fmt.Sprintf("test-redis-%d", time.Now().Unix())
+
+	region := "us-east-1"
+	plan := planModule(t, "elasticache", region, map[string]interface{}{
+		"engine":                     "redis",
+		"replication_group_id":       replicationGroupID,
+		"description":                "Terratest Redis replication group",
+		"subnet_group_name":          replicationGroupID,
+		"subnet_ids":                 []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+		"parameter_group_name":       replicationGroupID,
+		"num_cache_clusters":         2,
+		"transit_encryption_enabled": true,
+		"auth_token":                 "TestAuthToken1234567890!",
+	})
+
+	redis := getPlannedAttributes(t, plan, "aws_elasticache_replication_group.redis[0]")
+	assert.Equal(t, replicationGroupID, redis["replication_group_id"])
+	assert.Equal(t, "redis", redis["engine"])
+	assert.EqualValues(t, 2, redis["num_cache_clusters"])
+	assert.Equal(t, true, redis["transit_encryption_enabled"])
+	assert.Equal(t, true, redis["at_rest_encryption_enabled"])
+	assert.Equal(t, replicationGroupID, redis["subnet_group_name"])
+	assert.Equal(t, replicationGroupID, redis["parameter_group_name"])
+
+	parameterGroup := getPlannedAttributes(t, plan, "aws_elasticache_parameter_group.this[0]")
+	assert.Equal(t, "redis7", parameterGroup["family"])
+
+	assert.False(t, isResourcePlanned(plan, "aws_elasticache_cluster.memcached[0]"))
+	assert.False(t, isResourcePlanned(plan, "aws_cloudwatch_metric_alarm.cpu[0]"))
+	assert.False(t, isResourcePlanned(plan, "aws_cloudwatch_metric_alarm.memory[0]"))
+}
+
+func TestElastiCacheMemcachedCluster(t *testing.T) {
+	t.Parallel()
+
+	clusterID := fmt.Sprintf("test-mc-%d", time.Now().Unix())
+
+	region := "us-east-1"
+	plan := planModule(t, "elasticache", region, map[string]interface{}{
+		"engine":                       "memcached",
+		"cluster_id":                   clusterID,
+		"engine_version":               "1.6.22",
+		"num_cache_nodes":              3,
+		"az_mode":                      "cross-az",
+		"preferred_availability_zones": []string{"us-east-1a", "us-east-1b", "us-east-1c"},
+		"subnet_group_name":            clusterID,
+		"subnet_ids":                   []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+		"parameter_group_name":         clusterID,
+		"parameter_group_family":       "memcached1.6",
+		"create_alarms":                true,
+		"user_group_id":                "ignored-for-memcached",
+	})
+
+	memcached := getPlannedAttributes(t, plan, "aws_elasticache_cluster.memcached[0]")
+	assert.Equal(t, clusterID, memcached["cluster_id"])
+	assert.Equal(t, "memcached", memcached["engine"])
+	assert.EqualValues(t, 3, memcached["num_cache_nodes"])
+	assert.Equal(t, "cross-az", memcached["az_mode"])
+
+	assert.False(t, isResourcePlanned(plan, "aws_elasticache_replication_group.redis[0]"))
+	assert.False(t, isResourcePlanned(plan, "aws_elasticache_user_group.this[0]"))
+
+	// Only the CPU alarm applies to Memcached, keyed on the cluster ID
+	cpuAlarm := getPlannedAttributes(t, plan, "aws_cloudwatch_metric_alarm.cpu[0]")
+	assert.Equal(t, map[string]interface{}{"CacheClusterId": clusterID}, cpuAlarm["dimensions"])
+	assert.False(t, isResourcePlanned(plan, "aws_cloudwatch_metric_alarm.memory[0]"))
+}
+
+func TestElastiCacheTransitEncryptionRequiresAuth(t *testing.T) {
+	t.Parallel()
+
+	replicationGroupID := fmt.Sprintf("test-redis-tls-%d", time.Now().Unix())
+
+	region := "us-east-1"
+	err := planModuleE(t, "elasticache", region, map[string]interface{}{
+		"engine":                     "redis",
+		"replication_group_id":       replicationGroupID,
+		"subnet_group_name":          replicationGroupID,
+		"subnet_ids":                 []string{"subnet-0a1b2c3d4e5f60001"},
+		"parameter_group_name":       replicationGroupID,
+		"transit_encryption_enabled": true,
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Encryption in transit requires either auth_token or user_group_id")
+
+	userGroupID := fmt.Sprintf("%s-users", replicationGroupID)
+	plan := planModule(t, "elasticache", region, map[string]interface{}{
+		"engine":                     "redis",
+		"replication_group_id":       replicationGroupID,
+		"subnet_group_name":          replicationGroupID,
+		"subnet_ids":                 []string{"subnet-0a1b2c3d4e5f60001"},
+		"parameter_group_name":       replicationGroupID,
+		"transit_encryption_enabled": true,
+		"user_group_id":              userGroupID,
+		"user_ids":                   []string{"default"},
+	})
+
+	redis := getPlannedAttributes(t, plan, "aws_elasticache_replication_group.redis[0]")
+	assert.Equal(t, true, redis["transit_encryption_enabled"])
+	assert.Equal(t, []interface{}{userGroupID}, redis["user_group_ids"])
+
+	userGroup := getPlannedAttributes(t, plan, "aws_elasticache_user_group.this[0]")
+	assert.Equal(t, "REDIS", userGroup["engine"])
+	assert.Equal(t, userGroupID, userGroup["user_group_id"])
+}
+
+func TestElastiCacheClusterModeShards(t *testing.T) {
+	t.Parallel()
+
+	replicationGroupID := fmt.Sprintf("test-redis-cm-%d", time.Now().Unix())
+
+	region := "us-east-1"
+	plan := planModule(t, "elasticache", region, map[string]interface{}{
+		"engine":                  "redis",
+		"replication_group_id":    replicationGroupID,
+		"subnet_group_name":       replicationGroupID,
+		"subnet_ids":              []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"},
+		"parameter_group_name":    replicationGroupID,
+		"parameter_group_family":  "redis7",
+		"parameters":              []map[string]string{{"name": "cluster-enabled", "value": "yes"}},
+		"cluster_mode_enabled":    true,
+		"num_cache_clusters":      5,
+		"num_node_groups":         3,
+		"replicas_per_node_group": 2,
+		"auth_token":              "TestAuthToken1234567890!",
+	})
+
+	redis := getPlannedAttributes(t, plan, "aws_elasticache_replication_group.redis[0]")
+	assert.EqualValues(t, 3, redis["num_node_groups"])
+	assert.EqualValues(t, 2, redis["replicas_per_node_group"])
+
+	// num_cache_clusters is derived from the shard layout in cluster mode, so
+	// the module passes null and AWS computes 3 x (2 + 1) after apply
+	assert.Nil(t, redis["num_cache_clusters"])
+}
+
+func TestElastiCacheAlarms(t *testing.T) {
+	t.Parallel()
+
+	replicationGroupID := fmt.Sprintf("test-redis-alarms-%d", time.Now().Unix())
+	alarmTopics := []string{
+		"arn:aws:sns:us-east-1:123456789012:cache-alerts",
+		"arn:aws:sns:us-east-1:123456789012:oncall",
+	}
+
+	region := "us-east-1"
+	plan := planModule(t, "elasticache", region, map[string]interface{}{
+		"engine":                 "redis",
+		"replication_group_id":   replicationGroupID,
+		"subnet_group_name":      replicationGroupID,
+		"subnet_ids":             []string{"subnet-0a1b2c3d4e5f60001"},
+		"parameter_group_name":   replicationGroupID,
+		"auth_token":             "TestAuthToken1234567890!",
+		"create_alarms":          true,
+		"cpu_alarm_threshold":    80,
+		"memory_alarm_threshold": 15,
+		"alarm_sns_topic_arns":   alarmTopics,
+	})
+
+	expectedActions := []interface{}{alarmTopics[0], alarmTopics[1]}
+	expectedDimensions := map[string]interface{}{"ReplicationGroupId": replicationGroupID}
+
+	cpuAlarm := getPlannedAttributes(t, plan, "aws_cloudwatch_metric_alarm.cpu[0]")
+	assert.Equal(t, fmt.Sprintf("%s-cpu-utilization", replicationGroupID), cpuAlarm["alarm_name"])
+	assert.ElementsMatch(t, expectedActions, cpuAlarm["alarm_actions"])
+	assert.EqualValues(t, 80, cpuAlarm["threshold"])
+	assert.Equal(t, expectedDimensions, cpuAlarm["dimensions"])
+
+	memoryAlarm := getPlannedAttributes(t, plan, "aws_cloudwatch_metric_alarm.memory[0]")
+	assert.Equal(t, fmt.Sprintf("%s-memory", replicationGroupID), memoryAlarm["alarm_name"])
+	assert.ElementsMatch(t, expectedActions, memoryAlarm["alarm_actions"])
+	assert.EqualValues(t, 15, memoryAlarm["threshold"])
+	assert.Equal(t, expectedDimensions, memoryAlarm["dimensions"])
+}
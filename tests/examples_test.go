@@ -0,0 +1,468 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// exampleDBPassword replaces the placeholder password from
+// terraform.tfvars.example
+const exampleDBPassword = "ExampleTestPassword123!"
+
+func TestExampleSimpleWebApp(t *testing.T) {
+	t.Parallel()
+
+	projectName := fmt.Sprintf("test-swa-%d", time.Now().Unix())
+	region := "us-east-1"
+	webServerCount := 2
+
+	terraformOptions := createExampleOptions(t, "simple-web-app", map[string]cty.Value{
+		"aws_region":        cty.StringVal(region),
+		"project_name":      cty.StringVal(projectName),
+		"db_password":       cty.StringVal(exampleDBPassword),
+		"web_server_count":  cty.NumberIntVal(int64(webServerCount)),
+		"web_instance_type": cty.StringVal("t3.micro"),
+	})
+
+	if isExamplePlanOnly() {
+		plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+
+		for i := 1; i <= webServerCount; i++ {
+			attachment := getPlannedAttributes(t, plan, fmt.Sprintf(`module.alb.aws_lb_target_group_attachment.static["web-%d"]`, i))
+			assert.EqualValues(t, 80, attachment["port"])
+		}
+
+		listener := getPlannedAttributes(t, plan, "module.alb.aws_lb_listener.http[0]")
+		assert.EqualValues(t, 80, listener["port"])
+		assert.Equal(t, "forward", listener["default_action"].([]interface{})[0].(map[string]interface{})["type"])
+		assert.False(t, isResourcePlanned(plan, "module.alb.aws_lb_listener.https[0]"))
+
+		for i := 0; i < webServerCount; i++ {
+			instance := getPlannedAttributes(t, plan, fmt.Sprintf("module.web_server[%d].aws_instance.main[0]", i))
+			assert.Equal(t, "t3.micro", instance["instance_type"])
+			assert.Equal(t, "required", instance["metadata_options"].([]interface{})[0].(map[string]interface{})["http_tokens"])
+		}
+
+		database := getPlannedAttributes(t, plan, "module.rds.aws_db_instance.main")
+		assert.Equal(t, projectName+"-db", database["identifier"])
+		assert.Equal(t, false, database["publicly_accessible"])
+		assert.Equal(t, true, database["storage_encrypted"])
+
+		versioning := getPlannedAttributes(t, plan, "module.assets_bucket.aws_s3_bucket_versioning.main")
+		assert.Equal(t, "Enabled", versioning["versioning_configuration"].([]interface{})[0].(map[string]interface{})["status"])
+
+		assertExampleReferences(t, "simple-web-app", map[string]string{
+			"module.rds.subnet_ids":         "module.vpc.private_subnet_ids",
+			"module.web_server.subnet_id":   "module.vpc.private_subnet_ids",
+			"module.alb.subnet_ids":         "module.vpc.public_subnet_ids",
+			"module.alb.target_attachments": "module.web_server",
+		})
+		return
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
+	publicSubnets := terraform.OutputList(t, terraformOptions, "public_subnet_ids")
+	privateSubnets := terraform.OutputList(t, terraformOptions, "private_subnet_ids")
+	instanceIDs := terraform.OutputList(t, terraformOptions, "web_server_instance_ids")
+	targetGroupArn := terraform.Output(t, terraformOptions, "web_target_group_arn")
+	albArn := terraform.Output(t, terraformOptions, "alb_arn")
+	require.Len(t, instanceIDs, webServerCount)
+
+	elbClient := createELBv2Client(t, region)
+
+	loadBalancer := getLoadBalancer(t, elbClient, albArn)
+	assert.Equal(t, vpcID, aws.StringValue(loadBalancer.VpcId))
+	albSubnets := []string{}
+	for _, zone := range loadBalancer.AvailabilityZones {
+		albSubnets = append(albSubnets, aws.StringValue(zone.SubnetId))
+	}
+	assert.ElementsMatch(t, publicSubnets, albSubnets)
+
+	listeners := getLoadBalancerListeners(t, elbClient, albArn)
+	require.Len(t, listeners, 1, "only the HTTP listener should exist")
+	assert.Equal(t, int64(80), aws.Int64Value(listeners[0].Port))
+	require.Len(t, listeners[0].DefaultActions, 1)
+	assert.Equal(t, "forward", aws.StringValue(listeners[0].DefaultActions[0].Type))
+	assert.Equal(t, targetGroupArn, aws.StringValue(listeners[0].DefaultActions[0].TargetGroupArn))
+
+	targets := getTargetGroupTargets(t, elbClient, targetGroupArn)
+	assert.Len(t, targets, webServerCount)
+	for _, instanceID := range instanceIDs {
+		port, ok := targets[instanceID]
+		if assert.True(t, ok, "web server %s is not registered with the web target group", instanceID) {
+			assert.Equal(t, int64(80), port)
+		}
+	}
+
+	ec2Client := createEC2Client(t, region)
+	for _, instanceID := range instanceIDs {
+		instance := getEC2Instance(t, ec2Client, instanceID)
+		assert.Equal(t, vpcID, aws.StringValue(instance.VpcId))
+		assert.Contains(t, privateSubnets, aws.StringValue(instance.SubnetId), "web server %s is not in a private subnet", instanceID)
+		assert.Nil(t, instance.PublicIpAddress, "web server %s has a public IP", instanceID)
+	}
+
+	assertExampleDatabaseInPrivateSubnets(t, region, projectName+"-db", vpcID, privateSubnets)
+
+	bucketName := terraform.Output(t, terraformOptions, "assets_bucket_name")
+	s3Client := createS3Client(t, region)
+	assert.Equal(t, "Enabled", getBucketVersioning(t, s3Client, bucketName))
+	assert.NotNil(t, getBucketEncryption(t, s3Client, bucketName))
+	assertBucketBlocksPublicAccess(t, s3Client, bucketName)
+}
+
+func TestExampleMicroservicesPlatform(t *testing.T) {
+	t.Parallel()
+
+	projectName := fmt.Sprintf("test-msp-%d", time.Now().Unix())
+	region := "us-east-1"
+
+	// The example's node group uses AL2 AMIs, which EKS does not publish
+	// past Kubernetes 1.32
+	terraformOptions := createExampleOptions(t, "microservices-platform", map[string]cty.Value{
+		"aws_region":          cty.StringVal(region),
+		"project_name":        cty.StringVal(projectName),
+		"db_password":         cty.StringVal(exampleDBPassword),
+		"db_instance_class":   cty.StringVal("db.t3.micro"),
+		"eks_cluster_version": cty.StringVal("1.32"),
+	})
+
+	if isExamplePlanOnly() {
+		plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+
+		cluster := getPlannedAttributes(t, plan, "module.eks.aws_eks_cluster.main")
+		assert.Equal(t, projectName+"-eks", cluster["name"])
+		assert.Equal(t, "1.32", cluster["version"])
+
+		nodeGroup := getPlannedAttributes(t, plan, `module.eks.aws_eks_node_group.main["general"]`)
+		assert.Equal(t, "ON_DEMAND", nodeGroup["capacity_type"])
+
+		database := getPlannedAttributes(t, plan, "module.rds.aws_db_instance.main")
+		assert.Equal(t, false, database["publicly_accessible"])
+		assert.Equal(t, true, database["storage_encrypted"])
+
+		assertExampleReferences(t, "microservices-platform", map[string]string{
+			"module.eks.vpc_id":                   "module.vpc.vpc_id",
+			"module.eks.subnet_ids":               "module.vpc.private_subnet_ids",
+			"module.rds.subnet_ids":               "module.vpc.private_subnet_ids",
+			"aws_security_group.database.ingress": "module.eks.node_security_group_id",
+		})
+		return
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
+	privateSubnets := terraform.OutputList(t, terraformOptions, "private_subnet_ids")
+	clusterName := terraform.Output(t, terraformOptions, "eks_cluster_id")
+
+	eksClient := createEKSClient(t, region)
+	cluster := getEKSCluster(t, eksClient, clusterName)
+	assert.Equal(t, "ACTIVE", aws.StringValue(cluster.Status))
+	assert.Equal(t, vpcID, aws.StringValue(cluster.ResourcesVpcConfig.VpcId))
+	assert.ElementsMatch(t, privateSubnets, aws.StringValueSlice(cluster.ResourcesVpcConfig.SubnetIds))
+
+	nodeGroup := getEKSNodeGroup(t, eksClient, clusterName, "general")
+	assert.Equal(t, "ACTIVE", aws.StringValue(nodeGroup.Status))
+	assert.ElementsMatch(t, privateSubnets, aws.StringValueSlice(nodeGroup.Subnets))
+
+	database := assertExampleDatabaseInPrivateSubnets(t, region, projectName+"-db", vpcID, privateSubnets)
+
+	// The database only admits traffic from the EKS nodes
+	ec2Client := createEC2Client(t, region)
+	nodeSecurityGroup := getSecurityGroupByName(t, ec2Client, vpcID, clusterName+"-eks-node-sg")
+	databaseSecurityGroup := getSecurityGroupByName(t, ec2Client, vpcID, projectName+"-db-sg")
+	require.Len(t, database.VpcSecurityGroups, 1)
+	assert.Equal(t, aws.StringValue(databaseSecurityGroup.GroupId), aws.StringValue(database.VpcSecurityGroups[0].VpcSecurityGroupId))
+	require.Len(t, databaseSecurityGroup.IpPermissions, 1)
+	assert.Equal(t, int64(5432), aws.Int64Value(databaseSecurityGroup.IpPermissions[0].FromPort))
+	assert.Empty(t, databaseSecurityGroup.IpPermissions[0].IpRanges)
+	require.Len(t, databaseSecurityGroup.IpPermissions[0].UserIdGroupPairs, 1)
+	assert.Equal(t, aws.StringValue(nodeSecurityGroup.GroupId), aws.StringValue(databaseSecurityGroup.IpPermissions[0].UserIdGroupPairs[0].GroupId))
+}
+
+func TestExampleDataProcessingPipeline(t *testing.T) {
+	t.Parallel()
+
+	projectName := fmt.Sprintf("test-dpp-%d", time.Now().Unix())
+	region := "us-east-1"
+
+	terraformOptions := createExampleOptions(t, "data-processing-pipeline", map[string]cty.Value{
+		"aws_region":                cty.StringVal(region),
+		"project_name":              cty.StringVal(projectName),
+		"db_password":               cty.StringVal(exampleDBPassword),
+		"coordinator_instance_type": cty.StringVal("t3.micro"),
+	})
+
+	if isExamplePlanOnly() {
+		plan := terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+
+		for _, bucket := range []string{"raw_data_bucket", "processed_data_bucket"} {
+			versioning := getPlannedAttributes(t, plan, fmt.Sprintf("module.%s.aws_s3_bucket_versioning.main", bucket))
+			assert.Equal(t, "Enabled", versioning["versioning_configuration"].([]interface{})[0].(map[string]interface{})["status"], "versioning of %s", bucket)
+		}
+
+		lifecycle := getPlannedAttributes(t, plan, "module.raw_data_bucket.aws_s3_bucket_lifecycle_configuration.main[0]")
+		rules := lifecycle["rule"].([]interface{})
+		require.Len(t, rules, 1)
+		assert.Equal(t, "archive-raw-data", rules[0].(map[string]interface{})["id"])
+		assert.Len(t, rules[0].(map[string]interface{})["transition"], 2)
+		assert.False(t, isResourcePlanned(plan, "module.processed_data_bucket.aws_s3_bucket_lifecycle_configuration.main[0]"))
+
+		database := getPlannedAttributes(t, plan, "module.rds.aws_db_instance.main")
+		assert.Equal(t, projectName+"-metadata", database["identifier"])
+		assert.Equal(t, false, database["publicly_accessible"])
+
+		coordinator := getPlannedAttributes(t, plan, "module.coordinator.aws_instance.main[0]")
+		assert.Equal(t, "t3.micro", coordinator["instance_type"])
+
+		assertExampleReferences(t, "data-processing-pipeline", map[string]string{
+			"module.rds.subnet_ids":               "module.vpc.private_subnet_ids",
+			"module.coordinator.subnet_id":        "module.vpc.private_subnet_ids",
+			"aws_security_group.database.ingress": "aws_security_group.coordinator",
+		})
+		return
+	}
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
+	privateSubnets := terraform.OutputList(t, terraformOptions, "private_subnet_ids")
+	rawBucket := terraform.Output(t, terraformOptions, "raw_data_bucket")
+	processedBucket := terraform.Output(t, terraformOptions, "processed_data_bucket")
+	coordinatorID := terraform.Output(t, terraformOptions, "coordinator_instance_id")
+
+	s3Client := createS3Client(t, region)
+	for _, bucketName := range []string{rawBucket, processedBucket} {
+		assert.Equal(t, "Enabled", getBucketVersioning(t, s3Client, bucketName), "versioning of %s", bucketName)
+		assert.NotNil(t, getBucketEncryption(t, s3Client, bucketName), "encryption of %s", bucketName)
+		assertBucketBlocksPublicAccess(t, s3Client, bucketName)
+	}
+
+	rules := getBucketLifecycleRules(t, s3Client, rawBucket)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "archive-raw-data", aws.StringValue(rules[0].ID))
+	assert.Equal(t, "Enabled", aws.StringValue(rules[0].Status))
+	transitions := map[int64]string{}
+	for _, transition := range rules[0].Transitions {
+		transitions[aws.Int64Value(transition.Days)] = aws.StringValue(transition.StorageClass)
+	}
+	assert.Equal(t, map[int64]string{30: "STANDARD_IA", 90: "GLACIER"}, transitions)
+	assert.Nil(t, getBucketLifecycleRules(t, s3Client, processedBucket))
+
+	ec2Client := createEC2Client(t, region)
+	coordinator := getEC2Instance(t, ec2Client, coordinatorID)
+	assert.Equal(t, vpcID, aws.StringValue(coordinator.VpcId))
+	assert.Equal(t, privateSubnets[0], aws.StringValue(coordinator.SubnetId))
+	assert.Nil(t, coordinator.PublicIpAddress)
+
+	assertExampleDatabaseInPrivateSubnets(t, region, projectName+"-metadata", vpcID, privateSubnets)
+}
+
+// createExampleOptions copies the repository to a temporary folder, so the
+// example's relative module sources still resolve, and writes a
+// terraform.tfvars built from the example's terraform.tfvars.example with
+// overrides applied. Terraform loads the file automatically for apply, plan
+// and destroy.
+func createExampleOptions(t *testing.T, example string, overrides map[string]cty.Value) *terraform.Options {
+	exampleDir := test_structure.CopyTerraformFolderToTemp(t, "..", filepath.Join("examples", example))
+	writeExampleTfvars(t, exampleDir, overrides)
+
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: exampleDir,
+		NoColor:      true,
+	})
+	if isExamplePlanOnly() {
+		terraformOptions.PlanFilePath = filepath.Join(exampleDir, "tfplan")
+	}
+
+	return terraformOptions
+}
+
+// writeExampleTfvars generates terraform.tfvars in exampleDir from
+// terraform.tfvars.example, replacing or adding the given variables
+func writeExampleTfvars(t *testing.T, exampleDir string, overrides map[string]cty.Value) {
+	source, err := os.ReadFile(filepath.Join(exampleDir, "terraform.tfvars.example"))
+	require.NoError(t, err)
+
+	file, diags := hclwrite.ParseConfig(source, "terraform.tfvars.example", hcl.InitialPos)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	for name, value := range overrides {
+		file.Body().SetAttributeValue(name, value)
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(exampleDir, "terraform.tfvars"), file.Bytes(), 0o600))
+}
+
+// isExamplePlanOnly reports whether example tests should stop at terraform
+// plan instead of applying, as requested with EXAMPLES_PLAN_ONLY=true
+func isExamplePlanOnly() bool {
+	planOnly, _ := strconv.ParseBool(os.Getenv("EXAMPLES_PLAN_ONLY"))
+	return planOnly
+}
+
+// assertExampleReferences checks how an example wires its modules together
+// without applying it. Each key is a block address followed by an argument
+// name, and the argument's expression must reference the value's address or
+// something below it.
+func assertExampleReferences(t *testing.T, example string, expected map[string]string) {
+	module, err := tfmodule.Load(filepath.Join("..", "examples", example))
+	require.NoError(t, err)
+
+	for argument, target := range expected {
+		separator := strings.LastIndex(argument, ".")
+		address, name := argument[:separator], argument[separator+1:]
+
+		block := module.Lookup(address)
+		if !assert.NotNil(t, block, "%s is not declared in examples/%s", address, example) {
+			continue
+		}
+
+		references := []string{}
+		if attribute, ok := block.Body.Attributes[name]; ok {
+			for _, traversal := range attribute.Expr.Variables() {
+				references = append(references, renderTraversal(traversal))
+			}
+		}
+		for _, nested := range block.Body.Blocks {
+			if nested.Type != name {
+				continue
+			}
+			for _, attribute := range nested.Body.Attributes {
+				for _, traversal := range attribute.Expr.Variables() {
+					references = append(references, renderTraversal(traversal))
+				}
+			}
+		}
+
+		found := false
+		for _, reference := range references {
+			found = found || reference == target || strings.HasPrefix(reference, target+".")
+		}
+		assert.True(t, found, "%s in examples/%s should reference %s, references %v", argument, example, target, references)
+	}
+}
+
+// renderTraversal renders the attribute steps of a traversal as a dotted
+// address, dropping index steps
+func renderTraversal(traversal hcl.Traversal) string {
+	parts := []string{traversal.RootName()}
+	for _, step := range traversal[1:] {
+		if attr, ok := step.(hcl.TraverseAttr); ok {
+			parts = append(parts, attr.Name)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// assertExampleDatabaseInPrivateSubnets checks that an example's database sits
+// in a subnet group made of exactly the VPC's private subnets and is not
+// publicly accessible
+func assertExampleDatabaseInPrivateSubnets(t *testing.T, region string, identifier string, vpcID string, privateSubnets []string) *rds.DBInstance {
+	database := getRDSInstance(t, createRDSClient(t, region), identifier)
+	assert.False(t, aws.BoolValue(database.PubliclyAccessible))
+	assert.True(t, aws.BoolValue(database.StorageEncrypted))
+
+	require.NotNil(t, database.DBSubnetGroup)
+	assert.Equal(t, vpcID, aws.StringValue(database.DBSubnetGroup.VpcId))
+	subnets := []string{}
+	for _, subnet := range database.DBSubnetGroup.Subnets {
+		subnets = append(subnets, aws.StringValue(subnet.SubnetIdentifier))
+	}
+	assert.ElementsMatch(t, privateSubnets, subnets)
+
+	return database
+}
+
+// assertBucketBlocksPublicAccess checks that all four public access block
+// settings are enabled on a bucket
+func assertBucketBlocksPublicAccess(t *testing.T, client *s3.S3, bucketName string) {
+	block := getBucketPublicAccessBlock(t, client, bucketName)
+	require.NotNil(t, block)
+	assert.True(t, aws.BoolValue(block.BlockPublicAcls), "BlockPublicAcls on %s", bucketName)
+	assert.True(t, aws.BoolValue(block.BlockPublicPolicy), "BlockPublicPolicy on %s", bucketName)
+	assert.True(t, aws.BoolValue(block.IgnorePublicAcls), "IgnorePublicAcls on %s", bucketName)
+	assert.True(t, aws.BoolValue(block.RestrictPublicBuckets), "RestrictPublicBuckets on %s", bucketName)
+}
+
+func createELBv2Client(t *testing.T, region string) *elbv2.ELBV2 {
+	sess := createAWSSession(t, region)
+	return elbv2.New(sess)
+}
+
+func getLoadBalancer(t *testing.T, client *elbv2.ELBV2, loadBalancerArn string) *elbv2.LoadBalancer {
+	result, err := client.DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{
+		LoadBalancerArns: []*string{aws.String(loadBalancerArn)},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.LoadBalancers, 1)
+
+	return result.LoadBalancers[0]
+}
+
+func getLoadBalancerListeners(t *testing.T, client *elbv2.ELBV2, loadBalancerArn string) []*elbv2.Listener {
+	result, err := client.DescribeListeners(&elbv2.DescribeListenersInput{
+		LoadBalancerArn: aws.String(loadBalancerArn),
+	})
+	require.NoError(t, err)
+
+	return result.Listeners
+}
+
+// getTargetGroupTargets returns the port of each target registered with a
+// target group keyed by target ID
+func getTargetGroupTargets(t *testing.T, client *elbv2.ELBV2, targetGroupArn string) map[string]int64 {
+	result, err := client.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{
+		TargetGroupArn: aws.String(targetGroupArn),
+	})
+	require.NoError(t, err)
+
+	targets := map[string]int64{}
+	for _, description := range result.TargetHealthDescriptions {
+		targets[aws.StringValue(description.Target.Id)] = aws.Int64Value(description.Target.Port)
+	}
+	return targets
+}
+
+// getSecurityGroupByName returns the security group with the given name in a
+// VPC
+func getSecurityGroupByName(t *testing.T, client *ec2.EC2, vpcID string, name string) *ec2.SecurityGroup {
+	result, err := client.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("vpc-id"), Values: []*string{aws.String(vpcID)}},
+			{Name: aws.String("group-name"), Values: []*string{aws.String(name)}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.SecurityGroups, 1, "security group %s in %s", name, vpcID)
+
+	return result.SecurityGroups[0]
+}
@@ -4,8 +4,12 @@ go 1.21
 
 require (
 	github.com/aws/aws-sdk-go v1.48.0
+	github.com/go-sql-driver/mysql v1.7.1
 	github.com/gruntwork-io/terratest v0.46.7
+	github.com/hashicorp/hcl/v2 v2.23.0
+	github.com/lib/pq v1.10.9
 	github.com/stretchr/testify v1.8.4
+	github.com/zclconf/go-cty v1.14.4
 )
 
 require (
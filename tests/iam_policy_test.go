@@ -0,0 +1,92 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/iampolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleIAMPolicies(t *testing.T) {
+	t.Parallel()
+
+	name := fmt.Sprintf("test-iam-%d", time.Now().Unix())
+	topicARN := "arn:aws:sns:us-east-1:123456789012:" + name
+
+	adminPolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"*","Resource":"*"}]}`
+	writeAnywherePolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:GetObject","s3:PutObject"],"Resource":"*"}]}`
+	scopedPolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"sqs:SendMessage","Resource":"arn:aws:sqs:us-east-1:123456789012:` + name + `"}]}`
+
+	crossAccountPull := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::210987654321:root"},"Action":["ecr:BatchGetImage","ecr:GetDownloadUrlForLayer"]}]}`
+	sameAccountPull := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":["ecr:BatchGetImage","ecr:GetDownloadUrlForLayer"]}]}`
+
+	publicAPI := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"execute-api:Invoke","Resource":"execute-api:/*"}]}`
+	privateAPI := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"execute-api:Invoke","Resource":"execute-api:/*","Condition":{"StringEquals":{"aws:SourceVpce":"vpce-0a1b2c3d4e5f60001"}}}]}`
+
+	eventsTopicPolicy := func(condition string) string {
+		return `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"events.amazonaws.com"},"Action":"sns:Publish","Resource":"` + topicARN + `"` + condition + `}]}`
+	}
+
+	stepFunctionsVars := func(customPolicies ...string) map[string]interface{} {
+		overrides := map[string]interface{}{
+			"logging_configuration": map[string]interface{}{"level": "ERROR"},
+			"event_triggers": map[string]interface{}{
+				"nightly": map[string]interface{}{"schedule_expression": "cron(0 2 * * ? *)"},
+			},
+		}
+		if len(customPolicies) > 0 {
+			overrides["custom_policies"] = customPolicies
+		}
+		return minimalPlanVars(t, "stepfunctions", name, overrides)
+	}
+
+	testCases := []struct {
+		name     string
+		module   string
+		vars     map[string]interface{}
+		expected []string
+	}{
+		{"stepfunctions defaults", "stepfunctions", stepFunctionsVars(), nil},
+		{"stepfunctions scoped custom policy", "stepfunctions", stepFunctionsVars(scopedPolicy), nil},
+		{"stepfunctions admin custom policy", "stepfunctions", stepFunctionsVars(adminPolicy), []string{"IAM001"}},
+		{"stepfunctions write anywhere", "stepfunctions", stepFunctionsVars(writeAnywherePolicy), []string{"IAM002"}},
+		{"ecr same account pull", "ecr", map[string]interface{}{"repository_name": name, "repository_policy": sameAccountPull}, nil},
+		{"ecr cross account pull", "ecr", map[string]interface{}{"repository_name": name, "repository_policy": crossAccountPull}, []string{"IAM004"}},
+		{"apigateway public", "apigateway", map[string]interface{}{"name": name, "policy": publicAPI}, []string{"IAM004"}},
+		{"apigateway private", "apigateway", map[string]interface{}{"name": name, "policy": privateAPI, "endpoint_types": []string{"PRIVATE"}, "vpc_endpoint_ids": []string{"vpce-0a1b2c3d4e5f60001"}}, nil},
+		{"messaging unscoped service", "messaging", map[string]interface{}{
+			"sns_topics":         map[string]interface{}{name: map[string]interface{}{}},
+			"sns_topic_policies": map[string]interface{}{name: eventsTopicPolicy("")},
+		}, []string{"IAM003"}},
+		{"messaging scoped service", "messaging", map[string]interface{}{
+			"sns_topics":         map[string]interface{}{name: map[string]interface{}{}},
+			"sns_topic_policies": map[string]interface{}{name: eventsTopicPolicy(`,"Condition":{"ArnLike":{"aws:SourceArn":"arn:aws:events:us-east-1:123456789012:rule/*"}}`)},
+		}, nil},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			region := "us-east-1"
+			plan := planModuleJSON(t, testCase.module, region, testCase.vars)
+			policies, err := iampolicy.Extract(plan)
+			require.NoError(t, err)
+			findings := iampolicy.Analyze(policies, iampolicy.Options{TrustedAccounts: append(plan.Accounts, "123456789012")})
+
+			checkIDs := []string{}
+			for _, finding := range findings {
+				checkIDs = append(checkIDs, finding.CheckID)
+			}
+			if testCase.expected == nil {
+				assert.Empty(t, findings, "unexpected findings: %v", findings)
+			} else {
+				assert.Equal(t, testCase.expected, checkIDs, "findings: %v", findings)
+			}
+		})
+	}
+}
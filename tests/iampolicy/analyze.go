@@ -0,0 +1,295 @@
+package iampolicy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/policy"
+)
+
+// Check is one of the analyzer's checks
+type Check struct {
+	ID          string
+	Description string
+	Severity    policy.Severity
+}
+
+// Checks are the checks Analyze runs, in ID order
+var Checks = []Check{
+	{"IAM001", "Policies must not allow every action", policy.SeverityHigh},
+	{"IAM002", "Write actions must be scoped to specific resources", policy.SeverityMedium},
+	{"IAM003", "Service principals must be scoped with an aws:SourceArn condition", policy.SeverityMedium},
+	{"IAM004", "Policies must not grant access to principals outside the trusted accounts", policy.SeverityHigh},
+}
+
+// Options configures Analyze
+type Options struct {
+	// TrustedAccounts are the account IDs that may be granted access.
+	// IAM004 reports principals from every other account, so this
+	// normally includes the plan's own Accounts.
+	TrustedAccounts []string
+}
+
+// Finding is a problem in one statement of a policy
+type Finding struct {
+	CheckID   string          `json:"check_id"`
+	Severity  policy.Severity `json:"severity"`
+	Address   string          `json:"address"`
+	Attribute string          `json:"attribute"`
+	Sid       string          `json:"sid,omitempty"`
+	Message   string          `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s %s: %s", f.Severity, f.CheckID, f.Address, f.location()+f.Message)
+}
+
+// location names the attribute and statement a finding is in
+func (f Finding) location() string {
+	if f.Sid != "" {
+		return fmt.Sprintf("%s statement %q: ", f.Attribute, f.Sid)
+	}
+	return f.Attribute + ": "
+}
+
+// readVerbs are the action name prefixes of read-only actions, lower-cased
+// since IAM action names are case-insensitive
+var readVerbs = []string{"get", "list", "describe", "batchget", "head", "search", "lookup", "query", "scan"}
+
+// resourceWildcardActions can only be granted on "*", because the service
+// has no resource-level permissions for them
+var resourceWildcardActions = map[string]bool{
+	"cloudwatch:putmetricdata":      true,
+	"ecr:getauthorizationtoken":     true,
+	"logs:createlogdelivery":        true,
+	"logs:deletelogdelivery":        true,
+	"logs:describeloggroups":        true,
+	"logs:describeresourcepolicies": true,
+	"logs:getlogdelivery":           true,
+	"logs:listlogdeliveries":        true,
+	"logs:putresourcepolicy":        true,
+	"logs:updatelogdelivery":        true,
+	"sts:getcalleridentity":         true,
+	"xray:getsamplingrules":         true,
+	"xray:getsamplingtargets":       true,
+	"xray:puttelemetryrecords":      true,
+	"xray:puttracesegments":         true,
+}
+
+// sourceArnExemptServices don't pass aws:SourceArn when they assume a role,
+// so a trust policy naming them can't use the condition
+var sourceArnExemptServices = map[string]bool{
+	"ec2.amazonaws.com":        true,
+	"edgelambda.amazonaws.com": true,
+	"eks.amazonaws.com":        true,
+	"lambda.amazonaws.com":     true,
+}
+
+// principalScopeKeys are condition keys that limit a wildcard principal to
+// known callers, or to callers inside a known network as private APIs do
+var principalScopeKeys = []string{
+	"aws:PrincipalAccount",
+	"aws:PrincipalArn",
+	"aws:PrincipalOrgID",
+	"aws:SourceAccount",
+	"aws:SourceArn",
+	"aws:SourceOwner",
+	"aws:SourceVpc",
+	"aws:SourceVpce",
+}
+
+var accountPattern = regexp.MustCompile(`^(?:arn:aws[a-z-]*:(?:iam|sts)::)?([0-9]{12})(?::|$)`)
+
+// Analyze runs every check against the known policies. Findings are sorted
+// by severity, most severe first, then by address, attribute and check.
+func Analyze(policies []Policy, options Options) []Finding {
+	findings := []Finding{}
+	for _, p := range policies {
+		if p.Unknown {
+			continue
+		}
+
+		for _, statement := range p.Document.Statements {
+			if !statement.Allows() {
+				continue
+			}
+
+			add := func(checkID string, message string) {
+				findings = append(findings, Finding{
+					CheckID:   checkID,
+					Severity:  severityOf(checkID),
+					Address:   p.Address,
+					Attribute: p.Attribute,
+					Sid:       statement.Sid,
+					Message:   message,
+				})
+			}
+
+			for _, message := range checkWildcardAction(statement) {
+				add("IAM001", message)
+			}
+			for _, message := range checkWildcardResource(statement) {
+				add("IAM002", message)
+			}
+			for _, message := range checkServiceSourceArn(statement, p.Kind) {
+				add("IAM003", message)
+			}
+			for _, message := range checkCrossAccount(statement, options.TrustedAccounts) {
+				add("IAM004", message)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		if a.Attribute != b.Attribute {
+			return a.Attribute < b.Attribute
+		}
+		return a.CheckID < b.CheckID
+	})
+	return findings
+}
+
+// Rules wraps the checks as rules for the policy engine, so they run with
+// the rest of the rule pack. Policies that don't parse are left to fail at
+// apply.
+func Rules(options Options) []policy.Rule {
+	rules := []policy.Rule{}
+	for _, check := range Checks {
+		check := check
+		rules = append(rules, policy.Rule{
+			ID:            check.ID,
+			Description:   check.Description,
+			Severity:      check.Severity,
+			ResourceTypes: ResourceTypes(),
+			Check: func(resource policy.Resource) []string {
+				policies, err := extractResource(resource)
+				if err != nil {
+					return nil
+				}
+
+				messages := []string{}
+				for _, finding := range Analyze(policies, options) {
+					if finding.CheckID == check.ID {
+						messages = append(messages, finding.location()+finding.Message)
+					}
+				}
+				return messages
+			},
+		})
+	}
+	return rules
+}
+
+func checkWildcardAction(statement Statement) []string {
+	if len(statement.NotAction) > 0 {
+		return []string{fmt.Sprintf("Allow with NotAction %s allows every other action", strings.Join(statement.NotAction, ", "))}
+	}
+	for _, action := range statement.Action {
+		if action == "*" || action == "*:*" {
+			return []string{fmt.Sprintf("Action %q allows every action", action)}
+		}
+	}
+	return nil
+}
+
+func checkWildcardResource(statement Statement) []string {
+	if !statement.Resource.Contains("*") {
+		return nil
+	}
+
+	writes := []string{}
+	for _, action := range statement.Action {
+		if action == "*" || action == "*:*" {
+			// Already reported by IAM001
+			return nil
+		}
+		if isWriteAction(action) && !resourceWildcardActions[strings.ToLower(action)] {
+			writes = append(writes, action)
+		}
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("Resource \"*\" with write actions %s", strings.Join(writes, ", "))}
+}
+
+func checkServiceSourceArn(statement Statement, kind Kind) []string {
+	if statement.Principal == nil || statement.HasConditionKey("aws:SourceArn") {
+		return nil
+	}
+
+	unscoped := []string{}
+	for _, service := range statement.Principal.Service {
+		if kind == KindTrust && sourceArnExemptServices[service] {
+			continue
+		}
+		unscoped = append(unscoped, service)
+	}
+	if len(unscoped) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("service principal %s has no aws:SourceArn condition", strings.Join(unscoped, ", "))}
+}
+
+func checkCrossAccount(statement Statement, trustedAccounts []string) []string {
+	if statement.Principal == nil {
+		return nil
+	}
+
+	messages := []string{}
+	if statement.Principal.Wildcard {
+		scoped := false
+		for _, key := range principalScopeKeys {
+			scoped = scoped || statement.HasConditionKey(key)
+		}
+		if !scoped {
+			messages = append(messages, "Principal \"*\" allows any AWS account without a condition limiting the caller")
+		}
+	}
+
+	for _, principal := range statement.Principal.AWS {
+		match := accountPattern.FindStringSubmatch(principal)
+		if match == nil {
+			continue
+		}
+		if !Values(trustedAccounts).Contains(match[1]) {
+			messages = append(messages, fmt.Sprintf("principal %s is in untrusted account %s", principal, match[1]))
+		}
+	}
+	return messages
+}
+
+// isWriteAction reports whether an action, possibly with wildcards, can
+// include actions that are not read-only
+func isWriteAction(action string) bool {
+	parts := strings.SplitN(action, ":", 2)
+	if len(parts) != 2 {
+		return true
+	}
+
+	verb := strings.ToLower(parts[1])
+	for _, prefix := range readVerbs {
+		if strings.HasPrefix(verb, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func severityOf(checkID string) policy.Severity {
+	for _, check := range Checks {
+		if check.ID == checkID {
+			return check.Severity
+		}
+	}
+	return policy.SeverityLow
+}
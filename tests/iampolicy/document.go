@@ -0,0 +1,172 @@
+// Package iampolicy parses the IAM and resource policies found in a
+// Terraform plan into a typed model and checks them for overly broad grants
+package iampolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Values is a policy element that may be written as a single value or as
+// an array. Condition values written as booleans or numbers are kept in
+// their JSON form, such as "false".
+type Values []string
+
+// UnmarshalJSON accepts both "value" and ["value", ...]
+func (v *Values) UnmarshalJSON(data []byte) error {
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	elements, ok := decoded.([]interface{})
+	if !ok {
+		elements = []interface{}{decoded}
+	}
+
+	values := make(Values, 0, len(elements))
+	for _, element := range elements {
+		switch element := element.(type) {
+		case string:
+			values = append(values, element)
+		case bool, float64:
+			values = append(values, fmt.Sprint(element))
+		default:
+			return fmt.Errorf("expected a string or an array of strings, got %s", data)
+		}
+	}
+	*v = values
+	return nil
+}
+
+// Contains reports whether value is one of the values
+func (v Values) Contains(value string) bool {
+	for _, candidate := range v {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Principal is the Principal or NotPrincipal of a statement. "*" is parsed
+// as Wildcard, and so is {"AWS": "*"}.
+type Principal struct {
+	Wildcard      bool
+	AWS           Values
+	Service       Values
+	Federated     Values
+	CanonicalUser Values
+}
+
+// UnmarshalJSON accepts "*" and {"AWS": ..., "Service": ..., ...}
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	var wildcard string
+	if err := json.Unmarshal(data, &wildcard); err == nil {
+		if wildcard != "*" {
+			return fmt.Errorf("principal %q must be \"*\" or an object", wildcard)
+		}
+		p.Wildcard = true
+		return nil
+	}
+
+	var principals struct {
+		AWS           Values
+		Service       Values
+		Federated     Values
+		CanonicalUser Values
+	}
+	if err := json.Unmarshal(data, &principals); err != nil {
+		return err
+	}
+
+	p.AWS = principals.AWS
+	p.Service = principals.Service
+	p.Federated = principals.Federated
+	p.CanonicalUser = principals.CanonicalUser
+	p.Wildcard = principals.AWS.Contains("*")
+	return nil
+}
+
+// Statement is one statement of a policy document. Condition is keyed by
+// operator and then by condition key, as in the JSON.
+type Statement struct {
+	Sid          string
+	Effect       string
+	Principal    *Principal
+	NotPrincipal *Principal
+	Action       Values
+	NotAction    Values
+	Resource     Values
+	NotResource  Values
+	Condition    map[string]map[string]Values
+}
+
+// Allows reports whether the statement grants access
+func (s Statement) Allows() bool {
+	return s.Effect == "Allow"
+}
+
+// HasConditionKey reports whether any condition of the statement tests key,
+// compared without regard to case as IAM does
+func (s Statement) HasConditionKey(key string) bool {
+	for _, conditions := range s.Condition {
+		for conditionKey := range conditions {
+			if strings.EqualFold(conditionKey, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Document is a parsed policy document
+type Document struct {
+	Version    string
+	ID         string `json:"Id"`
+	Statements []Statement
+}
+
+// UnmarshalJSON accepts Statement as a single object or an array
+func (d *Document) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Version   string
+		ID        string `json:"Id"`
+		Statement json.RawMessage
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	d.Version = raw.Version
+	d.ID = raw.ID
+	d.Statements = nil
+
+	statement := bytes.TrimSpace(raw.Statement)
+	switch {
+	case len(statement) == 0:
+		return fmt.Errorf("policy has no Statement")
+	case statement[0] == '{':
+		var single Statement
+		if err := json.Unmarshal(statement, &single); err != nil {
+			return err
+		}
+		d.Statements = []Statement{single}
+	default:
+		if err := json.Unmarshal(statement, &d.Statements); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse parses a JSON policy document
+func Parse(policy string) (*Document, error) {
+	var document Document
+	if err := json.Unmarshal([]byte(policy), &document); err != nil {
+		return nil, fmt.Errorf("parsing policy document: %w", err)
+	}
+	return &document, nil
+}
@@ -0,0 +1,169 @@
+package iampolicy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/policy"
+)
+
+// Kind says what a policy document governs
+type Kind int
+
+const (
+	// KindIdentity policies are attached to a role, user or group and
+	// have no principal
+	KindIdentity Kind = iota + 1
+	// KindResource policies are attached to a resource and name the
+	// principals that may use it
+	KindResource
+	// KindTrust policies say who may assume a role
+	KindTrust
+)
+
+var kindNames = map[Kind]string{
+	KindIdentity: "identity",
+	KindResource: "resource",
+	KindTrust:    "trust",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// policyAttribute is an attribute that holds a JSON policy document. Path
+// separates nested blocks with dots, such as inline_policy.policy.
+type policyAttribute struct {
+	path string
+	kind Kind
+}
+
+// policyAttributes lists where each resource type keeps its policies
+var policyAttributes = map[string][]policyAttribute{
+	"aws_api_gateway_rest_api":           {{"policy", KindResource}},
+	"aws_api_gateway_rest_api_policy":    {{"policy", KindResource}},
+	"aws_cloudwatch_log_resource_policy": {{"policy_document", KindResource}},
+	"aws_ecr_registry_policy":            {{"policy", KindResource}},
+	"aws_ecr_repository_policy":          {{"policy", KindResource}},
+	"aws_iam_group_policy":               {{"policy", KindIdentity}},
+	"aws_iam_policy":                     {{"policy", KindIdentity}},
+	"aws_iam_role":                       {{"assume_role_policy", KindTrust}, {"inline_policy.policy", KindIdentity}},
+	"aws_iam_role_policy":                {{"policy", KindIdentity}},
+	"aws_iam_user_policy":                {{"policy", KindIdentity}},
+	"aws_kms_key":                        {{"policy", KindResource}},
+	"aws_s3_bucket_policy":               {{"policy", KindResource}},
+	"aws_secretsmanager_secret_policy":   {{"policy", KindResource}},
+	"aws_sns_topic":                      {{"policy", KindResource}},
+	"aws_sns_topic_policy":               {{"policy", KindResource}},
+	"aws_sqs_queue":                      {{"policy", KindResource}},
+	"aws_sqs_queue_policy":               {{"policy", KindResource}},
+}
+
+// ResourceTypes returns the resource types whose policies are extracted,
+// sorted
+func ResourceTypes() []string {
+	types := make([]string, 0, len(policyAttributes))
+	for resourceType := range policyAttributes {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Policy is a policy document found in a planned resource
+type Policy struct {
+	Address   string
+	Type      string
+	Attribute string
+	Kind      Kind
+
+	// Document is nil when Unknown is true
+	Document *Document
+
+	// Unknown is true when the document is only known after apply, so it
+	// can't be checked from the plan
+	Unknown bool
+}
+
+// Extract parses every policy document held by the plan's resources, in
+// resource order. Unset policies are skipped.
+func Extract(plan *policy.Plan) ([]Policy, error) {
+	policies := []Policy{}
+	for _, resource := range plan.Resources {
+		found, err := extractResource(resource)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, found...)
+	}
+	return policies, nil
+}
+
+func extractResource(resource policy.Resource) ([]Policy, error) {
+	policies := []Policy{}
+	for _, attribute := range policyAttributes[resource.Type] {
+		path := strings.Split(attribute.path, ".")
+		if resource.IsUnknown(path[0]) {
+			policies = append(policies, Policy{
+				Address:   resource.Address,
+				Type:      resource.Type,
+				Attribute: attribute.path,
+				Kind:      attribute.kind,
+				Unknown:   true,
+			})
+			continue
+		}
+
+		for name, value := range lookupPath(resource.Values, path, "") {
+			text, ok := value.(string)
+			if !ok || text == "" {
+				continue
+			}
+
+			document, err := Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", resource.Address, name, err)
+			}
+			policies = append(policies, Policy{
+				Address:   resource.Address,
+				Type:      resource.Type,
+				Attribute: name,
+				Kind:      attribute.kind,
+				Document:  document,
+			})
+		}
+	}
+
+	sort.SliceStable(policies, func(i, j int) bool {
+		return policies[i].Attribute < policies[j].Attribute
+	})
+	return policies, nil
+}
+
+// lookupPath follows path through nested blocks, which the plan renders as
+// lists of objects, and returns the values found keyed by their attribute
+// name, such as inline_policy[1].policy
+func lookupPath(values map[string]interface{}, path []string, prefix string) map[string]interface{} {
+	found := map[string]interface{}{}
+	value, ok := values[path[0]]
+	if !ok {
+		return found
+	}
+
+	name := prefix + path[0]
+	if len(path) == 1 {
+		found[name] = value
+		return found
+	}
+
+	blocks, _ := value.([]interface{})
+	for i, block := range blocks {
+		if nested, ok := block.(map[string]interface{}); ok {
+			for key, value := range lookupPath(nested, path[1:], fmt.Sprintf("%s[%d].", name, i)) {
+				found[key] = value
+			}
+		}
+	}
+	return found
+}
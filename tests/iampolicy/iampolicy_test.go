@@ -0,0 +1,230 @@
+package iampolicy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAcceptsSingleValuesAndStatements(t *testing.T) {
+	t.Parallel()
+
+	document, err := Parse(`{
+		"Version": "2012-10-17",
+		"Statement": {
+			"Effect": "Allow",
+			"Principal": "*",
+			"Action": "sns:Publish",
+			"Resource": ["arn:aws:sns:us-east-1:123456789012:alerts"],
+			"Condition": {"Bool": {"aws:SecureTransport": true}}
+		}
+	}`)
+	require.NoError(t, err)
+
+	require.Len(t, document.Statements, 1)
+	statement := document.Statements[0]
+	assert.True(t, statement.Allows())
+	assert.True(t, statement.Principal.Wildcard)
+	assert.Equal(t, Values{"sns:Publish"}, statement.Action)
+	assert.Equal(t, Values{"arn:aws:sns:us-east-1:123456789012:alerts"}, statement.Resource)
+	assert.Equal(t, Values{"true"}, statement.Condition["Bool"]["aws:SecureTransport"])
+	assert.True(t, statement.HasConditionKey("aws:securetransport"))
+}
+
+func TestParseRejectsMalformedDocuments(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []string{
+		`not json`,
+		`{"Version": "2012-10-17"}`,
+		`{"Statement": [{"Effect": "Allow", "Action": {"s3": "GetObject"}}]}`,
+		`{"Statement": [{"Effect": "Allow", "Principal": "arn:aws:iam::123456789012:root"}]}`,
+	} {
+		_, err := Parse(policy)
+		assert.Error(t, err, policy)
+	}
+}
+
+func TestAnalyzeFixturePolicies(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		fixture  string
+		kind     Kind
+		expected []string
+	}{
+		{"admin.json", KindIdentity, []string{
+			`[HIGH] IAM001 fixture: policy statement "Admin": Action "*" allows every action`,
+		}},
+		{"not_action.json", KindIdentity, []string{
+			`[HIGH] IAM001 fixture: policy: Allow with NotAction iam:*, organizations:* allows every other action`,
+		}},
+		{"log_delivery.json", KindIdentity, []string{}},
+		{"wildcard_write.json", KindIdentity, []string{
+			`[MEDIUM] IAM002 fixture: policy statement "Artifacts": Resource "*" with write actions s3:PutObject, states:*`,
+		}},
+		{"events_trust.json", KindTrust, []string{
+			`[MEDIUM] IAM003 fixture: policy: service principal events.amazonaws.com has no aws:SourceArn condition`,
+		}},
+		{"events_trust_scoped.json", KindTrust, []string{}},
+		{"ec2_trust.json", KindTrust, []string{}},
+		{"ec2_trust.json", KindResource, []string{
+			`[MEDIUM] IAM003 fixture: policy: service principal ec2.amazonaws.com has no aws:SourceArn condition`,
+		}},
+		{"ecr_cross_account.json", KindResource, []string{
+			`[HIGH] IAM004 fixture: policy statement "Pull": principal arn:aws:iam::210987654321:role/ci is in untrusted account 210987654321`,
+			`[HIGH] IAM004 fixture: policy statement "Pull": principal 345678901234 is in untrusted account 345678901234`,
+		}},
+		{"public_topic.json", KindResource, []string{
+			`[HIGH] IAM004 fixture: policy statement "Anyone": Principal "*" allows any AWS account without a condition limiting the caller`,
+			`[MEDIUM] IAM003 fixture: policy statement "Alarms": service principal cloudwatch.amazonaws.com has no aws:SourceArn condition`,
+		}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.fixture, func(t *testing.T) {
+			t.Parallel()
+
+			document := loadFixture(t, testCase.fixture)
+			policies := []Policy{{Address: "fixture", Attribute: "policy", Kind: testCase.kind, Document: document}}
+
+			findings := []string{}
+			for _, finding := range Analyze(policies, Options{TrustedAccounts: []string{"123456789012"}}) {
+				findings = append(findings, finding.String())
+			}
+			assert.Equal(t, testCase.expected, findings)
+		})
+	}
+}
+
+func TestAnalyzeWithoutTrustedAccountsReportsEveryAccount(t *testing.T) {
+	t.Parallel()
+
+	policies := []Policy{{Address: "fixture", Attribute: "policy", Kind: KindResource, Document: loadFixture(t, "ecr_cross_account.json")}}
+
+	findings := []string{}
+	for _, finding := range Analyze(policies, Options{}) {
+		findings = append(findings, finding.Message)
+	}
+	assert.Equal(t, []string{
+		"principal arn:aws:iam::123456789012:root is in untrusted account 123456789012",
+		"principal arn:aws:iam::210987654321:role/ci is in untrusted account 210987654321",
+		"principal 345678901234 is in untrusted account 345678901234",
+	}, findings)
+}
+
+func TestAnalyzeIgnoresActionCase(t *testing.T) {
+	t.Parallel()
+
+	document, err := Parse(`{
+		"Statement": [{
+			"Effect": "Allow",
+			"Action": ["s3:getobject", "S3:LISTBUCKET", "s3:putobject"],
+			"Resource": "*"
+		}]
+	}`)
+	require.NoError(t, err)
+
+	findings := Analyze([]Policy{{Address: "fixture", Attribute: "policy", Kind: KindIdentity, Document: document}}, Options{})
+	require.Len(t, findings, 1)
+	assert.Equal(t, `Resource "*" with write actions s3:putobject`, findings[0].Message)
+}
+
+func TestPlanAccountsAreTrusted(t *testing.T) {
+	t.Parallel()
+
+	plan, err := policy.LoadPlan(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"123456789012"}, plan.Accounts, "from data.aws_caller_identity")
+
+	findings := []string{}
+	for _, finding := range policy.Evaluate(plan, Rules(Options{TrustedAccounts: plan.Accounts}), nil) {
+		if finding.RuleID == "IAM004" {
+			findings = append(findings, finding.String())
+		}
+	}
+	assert.Equal(t, []string{
+		`[HIGH] IAM004 module.ecr.aws_ecr_repository_policy.this[0]: policy statement "Pull": principal arn:aws:iam::210987654321:role/ci is in untrusted account 210987654321`,
+		`[HIGH] IAM004 module.ecr.aws_ecr_repository_policy.this[0]: policy statement "Pull": principal 345678901234 is in untrusted account 345678901234`,
+		`[HIGH] IAM004 module.messaging.aws_sns_topic_policy.this["alerts"]: policy statement "Anyone": Principal "*" allows any AWS account without a condition limiting the caller`,
+	}, findings)
+}
+
+func TestExtractFindsPoliciesInPlan(t *testing.T) {
+	t.Parallel()
+
+	plan, err := policy.LoadPlan(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+
+	policies, err := Extract(plan)
+	require.NoError(t, err)
+
+	summary := []string{}
+	for _, p := range policies {
+		state := "parsed"
+		if p.Unknown {
+			state = "unknown"
+		}
+		summary = append(summary, p.Address+" "+p.Attribute+" "+p.Kind.String()+" "+state)
+	}
+	assert.Equal(t, []string{
+		"module.api.aws_api_gateway_rest_api.this policy resource unknown",
+		"module.ecr.aws_ecr_repository_policy.this[0] policy resource parsed",
+		`module.messaging.aws_sns_topic_policy.this["alerts"] policy resource parsed`,
+		"module.sfn.aws_iam_role.sfn[0] assume_role_policy trust parsed",
+		"module.sfn.aws_iam_role.sfn[0] inline_policy[0].policy identity parsed",
+		"module.sfn.aws_iam_role_policy.sfn_custom[0] policy identity parsed",
+		"module.sfn.aws_iam_role_policy.sfn_logging[0] policy identity parsed",
+	}, summary)
+}
+
+func TestExtractReportsInvalidPolicies(t *testing.T) {
+	t.Parallel()
+
+	plan := &policy.Plan{Resources: []policy.Resource{{
+		Address: "aws_iam_policy.broken",
+		Type:    "aws_iam_policy",
+		Values:  map[string]interface{}{"policy": `{"Statement": `},
+	}}}
+
+	_, err := Extract(plan)
+	assert.ErrorContains(t, err, "aws_iam_policy.broken.policy")
+}
+
+func TestRulesRunInPolicyEngine(t *testing.T) {
+	t.Parallel()
+
+	plan, err := policy.LoadPlan(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+
+	findings := []string{}
+	for _, finding := range policy.Evaluate(plan, Rules(Options{TrustedAccounts: []string{"123456789012"}}), nil) {
+		findings = append(findings, finding.String())
+	}
+
+	assert.Equal(t, []string{
+		`[HIGH] IAM004 module.ecr.aws_ecr_repository_policy.this[0]: policy statement "Pull": principal arn:aws:iam::210987654321:role/ci is in untrusted account 210987654321`,
+		`[HIGH] IAM004 module.ecr.aws_ecr_repository_policy.this[0]: policy statement "Pull": principal 345678901234 is in untrusted account 345678901234`,
+		`[HIGH] IAM004 module.messaging.aws_sns_topic_policy.this["alerts"]: policy statement "Anyone": Principal "*" allows any AWS account without a condition limiting the caller`,
+		`[HIGH] IAM001 module.sfn.aws_iam_role_policy.sfn_custom[0]: policy statement "Admin": Action "*" allows every action`,
+		`[MEDIUM] IAM003 module.messaging.aws_sns_topic_policy.this["alerts"]: policy statement "Alarms": service principal cloudwatch.amazonaws.com has no aws:SourceArn condition`,
+		`[MEDIUM] IAM002 module.sfn.aws_iam_role.sfn[0]: inline_policy[0].policy statement "Artifacts": Resource "*" with write actions s3:PutObject, states:*`,
+		`[MEDIUM] IAM003 module.sfn.aws_iam_role.sfn[0]: assume_role_policy: service principal events.amazonaws.com has no aws:SourceArn condition`,
+	}, findings)
+}
+
+// loadFixture parses a policy from testdata/policies
+func loadFixture(t *testing.T, name string) *Document {
+	data, err := os.ReadFile(filepath.Join("testdata", "policies", name))
+	require.NoError(t, err)
+
+	document, err := Parse(string(data))
+	require.NoError(t, err)
+	return document
+}
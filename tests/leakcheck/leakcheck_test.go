@@ -0,0 +1,148 @@
+package leakcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSecretName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		expected bool
+	}{
+		{"master_password", true},
+		{"db_password", true},
+		{"auth_token", true},
+		{"client_secret", true},
+		{"ssh_private_key", true},
+		{"aws_secret_access_key", true},
+		{"api_key", true},
+		{"Admin_Password", true},
+		{"password_length", false},
+		{"secret_arn", false},
+		{"kms_key_id", false},
+		{"key_name", false},
+		{"api_key_source", false},
+		{"api_key_selection_expression", false},
+		{"cpu_credits", false},
+		{"db_username", false},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, testCase.expected, IsSecretName(testCase.name))
+		})
+	}
+}
+
+func TestCheckDeclarations(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("testdata", "module")
+	module, err := tfmodule.Load(dir)
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, variable := range Variables(module) {
+		names = append(names, variable.Name)
+	}
+	assert.Equal(t, []string{"master_password", "auth_token", "ssh_private_key", "session"}, names)
+
+	messages := []string{}
+	for _, diagnostic := range CheckDeclarations(module) {
+		messages = append(messages, diagnostic.String())
+	}
+	variablesFile := filepath.Join(dir, "variables.tf")
+	assert.Equal(t, []string{
+		variablesFile + `:10: variable "auth_token" looks like a secret but is not declared sensitive = true`,
+		variablesFile + `:14: variable "ssh_private_key" looks like a secret but is not declared sensitive = true`,
+	}, messages)
+
+	secrets := SecretsFromVars(module, map[string]interface{}{
+		"name":            "orders",
+		"master_password": "S3cretPassw0rd!",
+		"session":         42,
+	})
+	assert.Equal(t, []Secret{{Name: "var.master_password", Value: "S3cretPassw0rd!"}}, secrets)
+}
+
+func TestPlanSecrets(t *testing.T) {
+	t.Parallel()
+
+	secrets, err := PlanSecrets(readTestdata(t, "plan.json"))
+	require.NoError(t, err)
+	assert.Equal(t, []Secret{
+		{Name: "var.auth_token", Value: "tok-0123456789abcdef"},
+		{Name: "var.master_password", Value: "S3cretPassw0rd!"},
+		{Name: "var.session", Value: "abc"},
+	}, secrets, "auth_token is a secret by name even though the root module doesn't declare it sensitive")
+}
+
+func TestScanJSONPlan(t *testing.T) {
+	t.Parallel()
+
+	plan := readTestdata(t, "plan.json")
+	secrets, err := PlanSecrets(plan)
+	require.NoError(t, err)
+
+	leaks, err := ScanJSON(plan, secrets)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"var.auth_token leaks into name aws_secretsmanager_secret.main.name",
+		"var.master_password leaks into output connection_string",
+		`var.auth_token leaks into tag module.cache.aws_elasticache_replication_group.main.tags["Debug"]`,
+		`var.auth_token leaks into tag module.cache.aws_elasticache_replication_group.main.tags_all["Debug"]`,
+	}, leakStrings(leaks), "sensitive outputs and attributes like password are not leaks, and var.session is too short to search for")
+}
+
+func TestScanJSONState(t *testing.T) {
+	t.Parallel()
+
+	secrets := []Secret{{Name: "var.master_password", Value: "S3cretPassw0rd!"}}
+	leaks, err := ScanJSON(readTestdata(t, "state.json"), secrets)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`var.master_password leaks into tag aws_db_instance.main.tags["S3cretPassw0rd!"]`,
+		`var.master_password leaks into tag aws_db_instance.main.tags_all["S3cretPassw0rd!"]`,
+	}, leakStrings(leaks))
+
+	_, err = ScanJSON([]byte("not json"), secrets)
+	assert.Error(t, err)
+}
+
+func TestScanLog(t *testing.T) {
+	t.Parallel()
+
+	secrets := []Secret{{Name: "var.master_password", Value: "S3cretPassw0rd!"}}
+	leaks := ScanLog(string(readTestdata(t, "terratest.log")), secrets)
+	assert.Equal(t, []string{
+		"var.master_password leaks into log line 2",
+		"var.master_password leaks into log line 6",
+	}, leakStrings(leaks), "the plan printed by terraform show -json is skipped")
+}
+
+// readTestdata reads a file under testdata
+func readTestdata(t *testing.T, name string) []byte {
+	contents, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return contents
+}
+
+// leakStrings renders leaks for comparison
+func leakStrings(leaks []Leak) []string {
+	rendered := []string{}
+	for _, leak := range leaks {
+		rendered = append(rendered, leak.String())
+	}
+	return rendered
+}
@@ -0,0 +1,237 @@
+package leakcheck
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MinSecretLength is the length below which values are not searched for,
+// since short values like "admin" or "true" match by accident
+const MinSecretLength = 6
+
+// nameAttributes are the resource attributes that name a resource, which
+// show up in consoles, ARNs and logs
+var nameAttributes = []string{
+	"name",
+	"name_prefix",
+	"identifier",
+	"identifier_prefix",
+	"bucket",
+	"bucket_prefix",
+	"function_name",
+	"cluster_id",
+	"cluster_name",
+	"replication_group_id",
+}
+
+// tagAttributes are the resource attributes that hold tags
+var tagAttributes = []string{"tags", "tags_all"}
+
+// Secret is a value that must not appear outside sensitive attributes
+type Secret struct {
+	// Name describes where the value comes from, such as
+	// var.master_password
+	Name  string
+	Value string
+}
+
+// Kind is where a secret leaked
+type Kind string
+
+const (
+	KindOutput Kind = "output"
+	KindTag    Kind = "tag"
+	KindName   Kind = "name"
+	KindLog    Kind = "log"
+)
+
+// Leak is a place a secret appears in. It never holds the secret's value.
+type Leak struct {
+	Secret   string `json:"secret"`
+	Kind     Kind   `json:"kind"`
+	Location string `json:"location"`
+}
+
+func (l Leak) String() string {
+	return fmt.Sprintf("%s leaks into %s %s", l.Secret, l.Kind, l.Location)
+}
+
+// showJSON is the part of the output of terraform show -json that is
+// searched, for a plan or for a state
+type showJSON struct {
+	Variables map[string]struct {
+		Value interface{} `json:"value"`
+	} `json:"variables"`
+	PlannedValues *valuesJSON `json:"planned_values"`
+	Values        *valuesJSON `json:"values"`
+	Configuration struct {
+		RootModule struct {
+			Variables map[string]struct {
+				Sensitive bool `json:"sensitive"`
+			} `json:"variables"`
+		} `json:"root_module"`
+	} `json:"configuration"`
+}
+
+type valuesJSON struct {
+	Outputs map[string]struct {
+		Sensitive bool        `json:"sensitive"`
+		Value     interface{} `json:"value"`
+	} `json:"outputs"`
+	RootModule moduleJSON `json:"root_module"`
+}
+
+type moduleJSON struct {
+	Resources []struct {
+		Address string                 `json:"address"`
+		Values  map[string]interface{} `json:"values"`
+	} `json:"resources"`
+	ChildModules []moduleJSON `json:"child_modules"`
+}
+
+// PlanSecrets returns the values of the root variables of a plan, in the
+// format of terraform show -json, that are declared sensitive or look like
+// secrets
+func PlanSecrets(plan []byte) ([]Secret, error) {
+	var document showJSON
+	if err := json.Unmarshal(plan, &document); err != nil {
+		return nil, fmt.Errorf("parsing plan: %w", err)
+	}
+
+	names := make([]string, 0, len(document.Variables))
+	for name := range document.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	secrets := []Secret{}
+	for _, name := range names {
+		if !document.Configuration.RootModule.Variables[name].Sensitive && !IsSecretName(name) {
+			continue
+		}
+		if value, ok := document.Variables[name].Value.(string); ok {
+			secrets = append(secrets, Secret{Name: "var." + name, Value: value})
+		}
+	}
+	return secrets, nil
+}
+
+// ScanJSON searches a plan or a state, in the format of terraform show
+// -json, for secrets in non-sensitive outputs, tags and resource names.
+// Secrets shorter than MinSecretLength are skipped.
+func ScanJSON(document []byte, secrets []Secret) ([]Leak, error) {
+	var parsed showJSON
+	if err := json.Unmarshal(document, &parsed); err != nil {
+		return nil, fmt.Errorf("parsing plan or state: %w", err)
+	}
+
+	values := parsed.Values
+	if parsed.PlannedValues != nil {
+		values = parsed.PlannedValues
+	}
+	if values == nil {
+		return []Leak{}, nil
+	}
+
+	leaks := []Leak{}
+	for _, secret := range searchable(secrets) {
+		for name, output := range values.Outputs {
+			if !output.Sensitive && containsSecret(output.Value, secret.Value) {
+				leaks = append(leaks, Leak{Secret: secret.Name, Kind: KindOutput, Location: name})
+			}
+		}
+		leaks = append(leaks, scanModule(values.RootModule, secret)...)
+	}
+
+	sort.Slice(leaks, func(i, j int) bool {
+		a, b := leaks[i], leaks[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Location != b.Location {
+			return a.Location < b.Location
+		}
+		return a.Secret < b.Secret
+	})
+	return leaks, nil
+}
+
+func scanModule(module moduleJSON, secret Secret) []Leak {
+	leaks := []Leak{}
+	for _, resource := range module.Resources {
+		for _, attribute := range tagAttributes {
+			tags, _ := resource.Values[attribute].(map[string]interface{})
+			for key, value := range tags {
+				if strings.Contains(key, secret.Value) || containsSecret(value, secret.Value) {
+					leaks = append(leaks, Leak{Secret: secret.Name, Kind: KindTag, Location: fmt.Sprintf("%s.%s[%q]", resource.Address, attribute, key)})
+				}
+			}
+		}
+		for _, attribute := range nameAttributes {
+			if containsSecret(resource.Values[attribute], secret.Value) {
+				leaks = append(leaks, Leak{Secret: secret.Name, Kind: KindName, Location: resource.Address + "." + attribute})
+			}
+		}
+	}
+	for _, child := range module.ChildModules {
+		leaks = append(leaks, scanModule(child, secret)...)
+	}
+	return leaks
+}
+
+// ScanLog searches a test log for secrets, line by line. Lines holding a
+// plan or state printed by terraform show -json are skipped, since plans
+// carry every variable value by design and are checked with ScanJSON.
+func ScanLog(log string, secrets []Secret) []Leak {
+	leaks := []Leak{}
+	scanner := bufio.NewScanner(strings.NewReader(log))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.Contains(text, `{"format_version":`) {
+			continue
+		}
+		for _, secret := range searchable(secrets) {
+			if strings.Contains(text, secret.Value) {
+				leaks = append(leaks, Leak{Secret: secret.Name, Kind: KindLog, Location: fmt.Sprintf("line %d", line)})
+			}
+		}
+	}
+	return leaks
+}
+
+// searchable drops secrets too short to search for
+func searchable(secrets []Secret) []Secret {
+	long := []Secret{}
+	for _, secret := range secrets {
+		if len(secret.Value) >= MinSecretLength {
+			long = append(long, secret)
+		}
+	}
+	return long
+}
+
+// containsSecret reports whether any string in a JSON value contains the
+// secret
+func containsSecret(value interface{}, secret string) bool {
+	switch value := value.(type) {
+	case string:
+		return strings.Contains(value, secret)
+	case []interface{}:
+		for _, element := range value {
+			if containsSecret(element, secret) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for key, element := range value {
+			if strings.Contains(key, secret) || containsSecret(element, secret) {
+				return true
+			}
+		}
+	}
+	return false
+}
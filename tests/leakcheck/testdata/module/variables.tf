@@ -0,0 +1,34 @@
+variable "name" {
+  type = string
+}
+
+variable "master_password" {
+  type      = string
+  sensitive = true
+}
+
+variable "auth_token" {
+  type = string
+}
+
+variable "ssh_private_key" {
+  type    = string
+  default = null
+}
+
+variable "secret_arn" {
+  type = string
+}
+
+variable "kms_key_id" {
+  type = string
+}
+
+variable "api_key_source" {
+  type = string
+}
+
+variable "session" {
+  type      = string
+  sensitive = true
+}
@@ -0,0 +1,140 @@
+// Package leakcheck finds the input variables of a module that hold secrets
+// and reports where their values leak: into non-sensitive outputs, tags or
+// resource names of a plan or state, or into a test log
+package leakcheck
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// secretWords are the words of a variable name that mark it as a secret
+var secretWords = map[string]bool{
+	"password":    true,
+	"passwd":      true,
+	"passphrase":  true,
+	"secret":      true,
+	"token":       true,
+	"credentials": true,
+}
+
+// secretSuffixes are the endings of a variable name that mark it as a secret
+var secretSuffixes = []string{"private_key", "access_key", "api_key", "secret_key"}
+
+// referenceWords are final words of a variable name that mean it only
+// identifies a secret, such as secret_arn or token_ttl
+var referenceWords = map[string]bool{
+	"arn":        true,
+	"arns":       true,
+	"id":         true,
+	"ids":        true,
+	"name":       true,
+	"names":      true,
+	"length":     true,
+	"rotation":   true,
+	"source":     true,
+	"type":       true,
+	"enabled":    true,
+	"expression": true,
+	"ttl":        true,
+	"version":    true,
+}
+
+// IsSecretName reports whether a variable name looks like it holds a
+// secret, such as master_password, auth_token or ssh_private_key, rather
+// than a reference to one, such as kms_key_id or secret_arn
+func IsSecretName(name string) bool {
+	name = strings.ToLower(name)
+	words := strings.Split(name, "_")
+	if referenceWords[words[len(words)-1]] {
+		return false
+	}
+	for _, word := range words {
+		if secretWords[word] {
+			return true
+		}
+	}
+	for _, suffix := range secretSuffixes {
+		if name == suffix || strings.HasSuffix(name, "_"+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Variable is an input variable that is declared sensitive or looks like a
+// secret
+type Variable struct {
+	Name string
+
+	// Sensitive is true when the variable is declared sensitive = true
+	Sensitive bool
+
+	// SecretName is true when the name looks like a secret
+	SecretName bool
+
+	// Block is the variable's declaration
+	Block *tfmodule.Block
+}
+
+// Variables returns the variables of a module that hold secrets, in
+// declaration order
+func Variables(m *tfmodule.Module) []Variable {
+	variables := []Variable{}
+	for _, block := range m.BlocksOfType("variable") {
+		variable := Variable{
+			Name:       block.Labels[0],
+			Sensitive:  isSensitive(block.Body),
+			SecretName: IsSecretName(block.Labels[0]),
+			Block:      block,
+		}
+		if variable.Sensitive || variable.SecretName {
+			variables = append(variables, variable)
+		}
+	}
+	return variables
+}
+
+// CheckDeclarations reports variables that look like secrets but are not
+// declared sensitive, so terraform would print their values in plans
+func CheckDeclarations(m *tfmodule.Module) []tfmodule.Diagnostic {
+	diagnostics := []tfmodule.Diagnostic{}
+	for _, variable := range Variables(m) {
+		if variable.Sensitive {
+			continue
+		}
+		diagnostics = append(diagnostics, tfmodule.Diagnostic{
+			File:    filepath.Join(m.Dir, variable.Block.File),
+			Line:    variable.Block.Range.Start.Line,
+			Message: fmt.Sprintf("variable %q looks like a secret but is not declared sensitive = true", variable.Name),
+		})
+	}
+	return diagnostics
+}
+
+// SecretsFromVars returns the values a test passes for the secret variables
+// of a module. Only string values are returned, since only those can be
+// searched for.
+func SecretsFromVars(m *tfmodule.Module, vars map[string]interface{}) []Secret {
+	secrets := []Secret{}
+	for _, variable := range Variables(m) {
+		if value, ok := vars[variable.Name].(string); ok {
+			secrets = append(secrets, Secret{Name: "var." + variable.Name, Value: value})
+		}
+	}
+	return secrets
+}
+
+func isSensitive(body *hclsyntax.Body) bool {
+	attr, ok := body.Attributes["sensitive"]
+	if !ok {
+		return false
+	}
+	value, diags := attr.Expr.Value(nil)
+	return !diags.HasErrors() && !value.IsNull() && value.Type().Equals(cty.Bool) && value.True()
+}
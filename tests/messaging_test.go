@@ -0,0 +1,164 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessagingFanOutAndRedrive(t *testing.T) {
+	t.Parallel()
+
+	endpoint := getLocalStackEndpoint(t)
+	prefix := fmt.Sprintf("test-msg-%d", time.Now().Unix())
+
+	ordersTopic := prefix + "-orders"
+	paymentsTopic := prefix + "-payments"
+	ordersQueue := prefix + "-orders-processor"
+	paymentsQueue := prefix + "-payments-processor"
+	auditQueue := prefix + "-audit"
+	ordersDLQ := prefix + "-orders-processor-dlq"
+	maxReceiveCount := 2
+
+	subscriptions := map[string]map[string]interface{}{
+		"orders-to-processor":   {"topic_name": ordersTopic, "queue_name": ordersQueue, "raw_message_delivery": true},
+		"orders-to-audit":       {"topic_name": ordersTopic, "queue_name": auditQueue, "raw_message_delivery": true},
+		"payments-to-processor": {"topic_name": paymentsTopic, "queue_name": paymentsQueue, "raw_message_delivery": true},
+		"payments-to-audit":     {"topic_name": paymentsTopic, "queue_name": auditQueue, "raw_message_delivery": true},
+	}
+
+	terraformOptions := createLocalStackModuleOptions(t, "messaging", endpoint, map[string]interface{}{
+		"sns_topics": map[string]interface{}{
+			ordersTopic:   map[string]interface{}{},
+			paymentsTopic: map[string]interface{}{},
+		},
+		"sqs_queues": map[string]interface{}{
+			ordersQueue:   map[string]interface{}{"visibility_timeout_seconds": 1},
+			paymentsQueue: map[string]interface{}{"visibility_timeout_seconds": 1},
+			auditQueue:    map[string]interface{}{"visibility_timeout_seconds": 1},
+		},
+		"sqs_dead_letter_queues": map[string]interface{}{
+			ordersDLQ: map[string]interface{}{},
+		},
+		"sqs_redrive_policies": map[string]interface{}{
+			ordersQueue: map[string]interface{}{
+				"dlq_name":          ordersDLQ,
+				"max_receive_count": maxReceiveCount,
+			},
+		},
+		"sns_to_sqs_subscriptions": subscriptions,
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	topicArns := terraform.OutputMap(t, terraformOptions, "sns_topic_arns")
+	queueUrls := terraform.OutputMap(t, terraformOptions, "sqs_queue_urls")
+	dlqUrls := terraform.OutputMap(t, terraformOptions, "sqs_dlq_urls")
+
+	sess := createLocalStackSession(t, endpoint)
+	snsClient := sns.New(sess)
+	sqsClient := sqs.New(sess)
+
+	// Publish one message per topic, then expect it on every queue
+	// subscribed to that topic
+	published := map[string]string{}
+	for _, topicName := range []string{ordersTopic, paymentsTopic} {
+		body := fmt.Sprintf("%s-%d", topicName, time.Now().UnixNano())
+		_, err := snsClient.Publish(&sns.PublishInput{
+			TopicArn: aws.String(topicArns[topicName]),
+			Message:  aws.String(body),
+		})
+		require.NoError(t, err)
+		published[topicName] = body
+	}
+
+	expected := map[string][]string{}
+	for _, subscription := range subscriptions {
+		queueName := subscription["queue_name"].(string)
+		topicName := subscription["topic_name"].(string)
+		expected[queueName] = append(expected[queueName], published[topicName])
+	}
+
+	for queueName, bodies := range expected {
+		received := receiveSQSMessageBodies(t, sqsClient, queueUrls[queueName], len(bodies), 30*time.Second)
+		assert.ElementsMatch(t, bodies, received, "messages received on %s", queueName)
+	}
+
+	// The audit queue is subscribed to both topics, so its single policy has
+	// to allow both of them to publish
+	attributes, err := sqsClient.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueUrls[auditQueue]),
+		AttributeNames: []*string{aws.String(sqs.QueueAttributeNamePolicy)},
+	})
+	require.NoError(t, err)
+	auditPolicy := aws.StringValue(attributes.Attributes[sqs.QueueAttributeNamePolicy])
+	assert.Contains(t, auditPolicy, topicArns[ordersTopic])
+	assert.Contains(t, auditPolicy, topicArns[paymentsTopic])
+
+	// Fail the orders message past maxReceiveCount by receiving it without
+	// deleting it, and expect SQS to move it to the DLQ
+	poisonBody := fmt.Sprintf("poison-%d", time.Now().UnixNano())
+	_, err = sqsClient.SendMessage(&sqs.SendMessageInput{
+		QueueUrl:    aws.String(queueUrls[ordersQueue]),
+		MessageBody: aws.String(poisonBody),
+	})
+	require.NoError(t, err)
+
+	receives := 0
+	deadline := time.Now().Add(60 * time.Second)
+	for time.Now().Before(deadline) && receives <= maxReceiveCount {
+		result, err := sqsClient.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(queueUrls[ordersQueue]),
+			MaxNumberOfMessages: aws.Int64(1),
+			VisibilityTimeout:   aws.Int64(0),
+			WaitTimeSeconds:     aws.Int64(1),
+		})
+		require.NoError(t, err)
+
+		if len(result.Messages) == 0 && receives == maxReceiveCount {
+			break
+		}
+		receives += len(result.Messages)
+	}
+	assert.Equal(t, maxReceiveCount, receives, "message was not redriven after maxReceiveCount receives")
+
+	dlqMessages := receiveSQSMessageBodies(t, sqsClient, dlqUrls[ordersDLQ], 1, 30*time.Second)
+	assert.Equal(t, []string{poisonBody}, dlqMessages)
+}
+
+// receiveSQSMessageBodies long-polls a queue until count messages have been
+// received or the timeout expires, deleting each message it receives
+func receiveSQSMessageBodies(t *testing.T, client *sqs.SQS, queueURL string, count int, timeout time.Duration) []string {
+	bodies := []string{}
+	deadline := time.Now().Add(timeout)
+
+	for len(bodies) < count && time.Now().Before(deadline) {
+		result, err := client.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(queueURL),
+			MaxNumberOfMessages: aws.Int64(10),
+			WaitTimeSeconds:     aws.Int64(2),
+		})
+		require.NoError(t, err)
+
+		for _, message := range result.Messages {
+			bodies = append(bodies, aws.StringValue(message.Body))
+
+			_, err := client.DeleteMessage(&sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(queueURL),
+				ReceiptHandle: message.ReceiptHandle,
+			})
+			require.NoError(t, err)
+		}
+	}
+
+	return bodies
+}
@@ -0,0 +1,343 @@
+package moddiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Impact is the semantic version bump a change needs
+type Impact int
+
+const (
+	// Patch changes don't affect callers, such as a new description
+	Patch Impact = iota + 1
+	// Minor changes are backwards compatible, such as a new optional
+	// variable or output
+	Minor
+	// Major changes can break callers, such as a removed variable or a new
+	// required one
+	Major
+)
+
+var impactNames = map[Impact]string{
+	Patch: "patch",
+	Minor: "minor",
+	Major: "major",
+}
+
+func (i Impact) String() string {
+	return impactNames[i]
+}
+
+// MarshalJSON renders the impact by name
+func (i Impact) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.String())
+}
+
+// ParseImpact parses major, minor or patch
+func ParseImpact(name string) (Impact, error) {
+	for impact, impactName := range impactNames {
+		if impactName == name {
+			return impact, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown impact %q, expected major, minor or patch", name)
+}
+
+// Kind is the kind of a change
+type Kind string
+
+const (
+	ModuleAdded        Kind = "module_added"
+	ModuleRemoved      Kind = "module_removed"
+	VariableAdded      Kind = "variable_added"
+	VariableRemoved    Kind = "variable_removed"
+	VariableRenamed    Kind = "variable_renamed"
+	VariableRequired   Kind = "variable_required"
+	VariableOptional   Kind = "variable_optional"
+	TypeChanged        Kind = "type_changed"
+	DefaultChanged     Kind = "default_changed"
+	DescriptionChanged Kind = "description_changed"
+	OutputAdded        Kind = "output_added"
+	OutputRemoved      Kind = "output_removed"
+	OutputSensitive    Kind = "output_sensitive"
+	OutputNotSensitive Kind = "output_not_sensitive"
+	OutputDescription  Kind = "output_description_changed"
+)
+
+// Change is one difference between two versions of a module's interface
+type Change struct {
+	Module string `json:"module"`
+	Kind   Kind   `json:"kind"`
+
+	// Name is the variable or output changed, its new name for renames
+	Name string `json:"name,omitempty"`
+
+	// From and To are the old and new type, default or name
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+
+	Impact  Impact `json:"impact"`
+	Message string `json:"message"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("[%s] %s: %s", c.Impact, c.Module, c.Message)
+}
+
+// Report is the comparison of every module between two versions
+type Report struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// Impact is the largest impact of any change, or patch when nothing
+	// changed
+	Impact Impact `json:"impact"`
+
+	// Modules holds the impact on each module that changed
+	Modules map[string]Impact `json:"modules"`
+	Changes []Change          `json:"changes"`
+}
+
+// NewReport classifies the changes between two versions
+func NewReport(from string, to string, changes []Change) Report {
+	report := Report{From: from, To: to, Impact: Patch, Modules: map[string]Impact{}, Changes: changes}
+	for _, change := range changes {
+		if change.Impact > report.Impact {
+			report.Impact = change.Impact
+		}
+		if change.Impact > report.Modules[change.Module] {
+			report.Modules[change.Module] = change.Impact
+		}
+	}
+	return report
+}
+
+// Diff compares the interfaces of every module between two versions. The
+// changes are sorted by module, then by variable or output.
+func Diff(before map[string]*Interface, after map[string]*Interface) []Change {
+	changes := []Change{}
+	for name, old := range before {
+		if _, ok := after[name]; !ok {
+			changes = append(changes, Change{Module: name, Kind: ModuleRemoved, Impact: Major, Message: "module removed"})
+			continue
+		}
+		changes = append(changes, diffVariables(name, old.Variables, after[name].Variables)...)
+		changes = append(changes, diffOutputs(name, old.Outputs, after[name].Outputs)...)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			changes = append(changes, Change{Module: name, Kind: ModuleAdded, Impact: Minor, Message: "module added"})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Kind < b.Kind
+	})
+	return changes
+}
+
+func diffVariables(module string, before map[string]Variable, after map[string]Variable) []Change {
+	changes := []Change{}
+
+	removed, added := []string{}, []string{}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	renamed := map[string]bool{}
+	for _, oldName := range removed {
+		newName, ok := findRename(before[oldName], after, added, renamed)
+		if !ok {
+			changes = append(changes, Change{Module: module, Kind: VariableRemoved, Name: oldName, Impact: Major, Message: fmt.Sprintf("variable %q removed", oldName)})
+			continue
+		}
+		renamed[newName] = true
+		changes = append(changes, Change{
+			Module:  module,
+			Kind:    VariableRenamed,
+			Name:    newName,
+			From:    oldName,
+			To:      newName,
+			Impact:  Major,
+			Message: fmt.Sprintf("variable %q renamed to %q", oldName, newName),
+		})
+	}
+
+	for _, name := range added {
+		if renamed[name] {
+			continue
+		}
+		if after[name].Required {
+			changes = append(changes, Change{Module: module, Kind: VariableAdded, Name: name, Impact: Major, Message: fmt.Sprintf("new required variable %q", name)})
+		} else {
+			changes = append(changes, Change{Module: module, Kind: VariableAdded, Name: name, To: after[name].Default, Impact: Minor, Message: fmt.Sprintf("new optional variable %q", name)})
+		}
+	}
+
+	for name, old := range before {
+		if updated, ok := after[name]; ok {
+			changes = append(changes, diffVariable(module, old, updated)...)
+		}
+	}
+	return changes
+}
+
+// findRename returns the added variable that a removed one was renamed to.
+// The candidates must have the same type, default and requiredness, and the
+// best one must share the description or a word of the name with it.
+func findRename(old Variable, after map[string]Variable, added []string, renamed map[string]bool) (string, bool) {
+	best, bestScore, tied := "", 0, false
+	for _, name := range added {
+		candidate := after[name]
+		if renamed[name] || !candidate.constraint.Equals(old.constraint) || candidate.Required != old.Required || candidate.Default != old.Default {
+			continue
+		}
+
+		score := 0
+		if old.Description != "" && candidate.Description == old.Description {
+			score = 2
+		} else if sharesWord(old.Name, name) {
+			score = 1
+		}
+
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = name, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	return best, bestScore > 0 && !tied
+}
+
+func sharesWord(a string, b string) bool {
+	for _, word := range strings.Split(a, "_") {
+		for _, other := range strings.Split(b, "_") {
+			if word != "" && word == other {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func diffVariable(module string, old Variable, updated Variable) []Change {
+	changes := []Change{}
+	change := func(kind Kind, from string, to string, impact Impact, message string) {
+		changes = append(changes, Change{Module: module, Kind: kind, Name: old.Name, From: from, To: to, Impact: impact, Message: message})
+	}
+
+	switch {
+	case !old.Required && updated.Required:
+		change(VariableRequired, old.Default, "", Major, fmt.Sprintf("variable %q is now required", old.Name))
+	case old.Required && !updated.Required:
+		change(VariableOptional, "", updated.Default, Minor, fmt.Sprintf("variable %q is now optional", old.Name))
+	case !old.Required && !defaultEqual(old, updated):
+		change(DefaultChanged, old.Default, updated.Default, Minor, fmt.Sprintf("default of variable %q changed from %s to %s", old.Name, old.Default, updated.Default))
+	}
+
+	if !old.constraint.Equals(updated.constraint) {
+		if acceptsAll(old.constraint, updated.constraint) {
+			change(TypeChanged, old.Type, updated.Type, Minor, fmt.Sprintf("type of variable %q widened", old.Name))
+		} else {
+			change(TypeChanged, old.Type, updated.Type, Major, fmt.Sprintf("type of variable %q changed incompatibly", old.Name))
+		}
+	} else if !defaultsEqual(old.defaults, updated.defaults) {
+		change(DefaultChanged, old.Type, updated.Type, Minor, fmt.Sprintf("optional attribute defaults of variable %q changed", old.Name))
+	}
+
+	if old.Description != updated.Description {
+		change(DescriptionChanged, "", "", Patch, fmt.Sprintf("description of variable %q changed", old.Name))
+	}
+	return changes
+}
+
+// acceptsAll reports whether every value of the old type converts to the new
+// one without loss, so callers don't have to change
+func acceptsAll(old cty.Type, updated cty.Type) bool {
+	if updated == cty.DynamicPseudoType {
+		return true
+	}
+	if old.HasDynamicTypes() {
+		return false
+	}
+	return convert.GetConversion(old, updated) != nil
+}
+
+func defaultEqual(old Variable, updated Variable) bool {
+	if old.value != cty.NilVal && updated.value != cty.NilVal {
+		return old.value.RawEquals(updated.value)
+	}
+	return old.Default == updated.Default
+}
+
+// defaultsEqual compares the defaults of the optional attributes of two
+// equal types
+func defaultsEqual(a *typeexpr.Defaults, b *typeexpr.Defaults) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if len(a.DefaultValues) != len(b.DefaultValues) || len(a.Children) != len(b.Children) {
+		return false
+	}
+	for name, value := range a.DefaultValues {
+		other, ok := b.DefaultValues[name]
+		if !ok || !value.RawEquals(other) {
+			return false
+		}
+	}
+	for key, child := range a.Children {
+		if !defaultsEqual(child, b.Children[key]) {
+			return false
+		}
+	}
+	return true
+}
+
+func diffOutputs(module string, before map[string]Output, after map[string]Output) []Change {
+	changes := []Change{}
+	for name, old := range before {
+		updated, ok := after[name]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Module: module, Kind: OutputRemoved, Name: name, Impact: Major, Message: fmt.Sprintf("output %q removed", name)})
+			continue
+		case !old.Sensitive && updated.Sensitive:
+			changes = append(changes, Change{Module: module, Kind: OutputSensitive, Name: name, Impact: Major, Message: fmt.Sprintf("output %q is now sensitive", name)})
+		case old.Sensitive && !updated.Sensitive:
+			changes = append(changes, Change{Module: module, Kind: OutputNotSensitive, Name: name, Impact: Minor, Message: fmt.Sprintf("output %q is no longer sensitive", name)})
+		}
+		if old.Description != updated.Description {
+			changes = append(changes, Change{Module: module, Kind: OutputDescription, Name: name, Impact: Patch, Message: fmt.Sprintf("description of output %q changed", name)})
+		}
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			changes = append(changes, Change{Module: module, Kind: OutputAdded, Name: name, Impact: Minor, Message: fmt.Sprintf("new output %q", name)})
+		}
+	}
+	return changes
+}
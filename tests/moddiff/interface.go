@@ -0,0 +1,156 @@
+// Package moddiff compares the interface of Terraform modules, their input
+// variables and outputs, between two versions and classifies the change as a
+// semantic version bump
+package moddiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Interface is what callers of a module depend on
+type Interface struct {
+	Module    string
+	Variables map[string]Variable
+	Outputs   map[string]Output
+}
+
+// Variable is an input variable of a module
+type Variable struct {
+	Name string `json:"name"`
+
+	// Type is the type constraint as written, with whitespace collapsed, or
+	// any when the variable has none
+	Type string `json:"type"`
+
+	// Default is the default value rendered as HCL, empty when the variable
+	// is required
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required"`
+	Sensitive   bool   `json:"sensitive,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	constraint cty.Type
+	defaults   *typeexpr.Defaults
+	value      cty.Value
+}
+
+// Output is an output value of a module
+type Output struct {
+	Name        string `json:"name"`
+	Sensitive   bool   `json:"sensitive,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Extract reads the interface of a module from its variable and output
+// blocks
+func Extract(m *tfmodule.Module) (*Interface, error) {
+	iface := &Interface{Module: m.Name, Variables: map[string]Variable{}, Outputs: map[string]Output{}}
+
+	for _, block := range m.BlocksOfType("variable") {
+		variable := Variable{
+			Name:        block.Labels[0],
+			Type:        "any",
+			Required:    true,
+			Sensitive:   boolAttribute(block.Body, "sensitive"),
+			Description: stringAttribute(block.Body, "description"),
+			constraint:  cty.DynamicPseudoType,
+			value:       cty.NilVal,
+		}
+
+		if attr, ok := block.Body.Attributes["type"]; ok {
+			constraint, defaults, diags := typeexpr.TypeConstraintWithDefaults(attr.Expr)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("%s: variable %q: %s", block.Pos(m.Dir), variable.Name, diags.Error())
+			}
+			variable.Type = sourceText(m, block, attr.Expr)
+			variable.constraint = constraint
+			variable.defaults = defaults
+		}
+
+		if attr, ok := block.Body.Attributes["default"]; ok {
+			variable.Required = false
+			value, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() {
+				variable.Default = sourceText(m, block, attr.Expr)
+			} else {
+				variable.value = value
+				variable.Default = collapse(string(hclwrite.TokensForValue(value).Bytes()))
+			}
+		}
+
+		iface.Variables[variable.Name] = variable
+	}
+
+	for _, block := range m.BlocksOfType("output") {
+		output := Output{
+			Name:        block.Labels[0],
+			Sensitive:   boolAttribute(block.Body, "sensitive"),
+			Description: stringAttribute(block.Body, "description"),
+		}
+		iface.Outputs[output.Name] = output
+	}
+
+	return iface, nil
+}
+
+// ExtractAll extracts the interface of every module, keyed by name
+func ExtractAll(modules map[string]*tfmodule.Module) (map[string]*Interface, error) {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	interfaces := map[string]*Interface{}
+	for _, name := range names {
+		iface, err := Extract(modules[name])
+		if err != nil {
+			return nil, err
+		}
+		interfaces[name] = iface
+	}
+	return interfaces, nil
+}
+
+// sourceText returns the source of an expression with whitespace collapsed,
+// so that reformatting a type or default is not reported as a change
+func sourceText(m *tfmodule.Module, block *tfmodule.Block, expr hclsyntax.Expression) string {
+	exprRange := expr.Range()
+	return collapse(string(m.Source(block.File)[exprRange.Start.Byte:exprRange.End.Byte]))
+}
+
+func collapse(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func stringAttribute(body *hclsyntax.Body, name string) string {
+	attr, ok := body.Attributes[name]
+	if !ok {
+		return ""
+	}
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || value.IsNull() || !value.Type().Equals(cty.String) {
+		return ""
+	}
+	return value.AsString()
+}
+
+func boolAttribute(body *hclsyntax.Body, name string) bool {
+	attr, ok := body.Attributes[name]
+	if !ok {
+		return false
+	}
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || value.IsNull() || !value.Type().Equals(cty.Bool) {
+		return false
+	}
+	return value.True()
+}
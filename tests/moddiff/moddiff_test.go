@@ -0,0 +1,131 @@
+package moddiff
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffClassifiesChanges(t *testing.T) {
+	t.Parallel()
+
+	changes := Diff(loadInterfaces(t, "before"), loadInterfaces(t, "after"))
+
+	type classified struct {
+		module string
+		kind   Kind
+		name   string
+		impact Impact
+	}
+	actual := []classified{}
+	for _, change := range changes {
+		actual = append(actual, classified{change.Module, change.Kind, change.Name, change.Impact})
+	}
+
+	assert.Equal(t, []classified{
+		{"legacy", ModuleRemoved, "", Major},
+		{"network", VariableAdded, "enable_dns_hostnames", Minor},
+		{"network", DefaultChanged, "enable_nat_gateway", Minor},
+		{"network", DefaultChanged, "endpoints", Minor},
+		{"network", OutputSensitive, "flow_log_key", Major},
+		{"network", DefaultChanged, "flow_log_retention", Minor},
+		{"network", TypeChanged, "flow_log_retention", Major},
+		{"network", VariableRemoved, "instance_tenancy", Major},
+		{"network", VariableRequired, "kms_key_id", Major},
+		{"network", VariableAdded, "log_bucket", Major},
+		{"network", DescriptionChanged, "name", Patch},
+		{"network", OutputRemoved, "nat_ip", Major},
+		{"network", DefaultChanged, "ports", Minor},
+		{"network", TypeChanged, "ports", Minor},
+		{"network", VariableOptional, "region", Minor},
+		{"network", OutputAdded, "subnet_ids", Minor},
+		{"network", TypeChanged, "subnets", Minor},
+		{"network", VariableRenamed, "vpc_cidr_block", Major},
+		{"queue", ModuleAdded, "", Minor},
+	}, actual, "labels is only reformatted, so it is not reported")
+
+	for _, change := range changes {
+		if change.Kind == VariableRenamed {
+			assert.Equal(t, "cidr_block", change.From)
+			assert.Equal(t, `variable "cidr_block" renamed to "vpc_cidr_block"`, change.Message)
+		}
+		if change.Kind == TypeChanged && change.Name == "subnets" {
+			assert.Equal(t, "map(object({ cidr = string public = optional(bool, false) az = optional(string) }))", change.To)
+		}
+	}
+}
+
+func TestAcceptsAll(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		old      string
+		updated  string
+		expected bool
+	}{
+		{"number to string", "number", "string", true},
+		{"string to number", "string", "number", false},
+		{"anything to any", "list(string)", "any", true},
+		{"any to string", "any", "string", false},
+		{"list to set drops duplicates", "list(string)", "set(string)", false},
+		{"set to list", "set(string)", "list(string)", true},
+		{"new optional attribute", "object({ a = string })", "object({ a = string, b = optional(number) })", true},
+		{"new required attribute", "object({ a = string })", "object({ a = string, b = number })", false},
+		{"removed attribute", "object({ a = string, b = number })", "object({ a = string })", true},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			old := parseVariable(t, testCase.old)
+			updated := parseVariable(t, testCase.updated)
+			assert.Equal(t, testCase.expected, acceptsAll(old.constraint, updated.constraint))
+		})
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	t.Parallel()
+
+	report := NewReport("v1.0.0", "HEAD", Diff(loadInterfaces(t, "before"), loadInterfaces(t, "after")))
+	assert.Equal(t, Major, report.Impact)
+	assert.Equal(t, map[string]Impact{"legacy": Major, "network": Major, "queue": Minor}, report.Modules)
+
+	encoded, err := json.Marshal(NewReport("v1.0.0", "HEAD", []Change{}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"from": "v1.0.0", "to": "HEAD", "impact": "patch", "modules": {}, "changes": []}`, string(encoded))
+
+	impact, err := ParseImpact("minor")
+	require.NoError(t, err)
+	assert.Equal(t, Minor, impact)
+	_, err = ParseImpact("breaking")
+	assert.Error(t, err)
+}
+
+// loadInterfaces extracts the interfaces of the modules in a testdata
+// directory
+func loadInterfaces(t *testing.T, dir string) map[string]*Interface {
+	modules, err := tfmodule.LoadAll(filepath.Join("testdata", dir))
+	require.NoError(t, err)
+	interfaces, err := ExtractAll(modules)
+	require.NoError(t, err)
+	return interfaces
+}
+
+// parseVariable extracts a variable with the given type constraint
+func parseVariable(t *testing.T, constraint string) Variable {
+	module, err := tfmodule.Parse("test", "test", map[string][]byte{
+		"variables.tf": []byte("variable \"value\" {\n  type = " + constraint + "\n}\n"),
+	})
+	require.NoError(t, err)
+	iface, err := Extract(module)
+	require.NoError(t, err)
+	return iface.Variables["value"]
+}
@@ -0,0 +1,15 @@
+output "vpc_id" {
+  description = "ID of the VPC"
+  value       = aws_vpc.main.id
+}
+
+output "flow_log_key" {
+  description = "Key of the flow log bucket"
+  value       = aws_s3_bucket.flow_logs.id
+  sensitive   = true
+}
+
+output "subnet_ids" {
+  description = "IDs of the subnets"
+  value       = [for subnet in aws_subnet.main : subnet.id]
+}
@@ -0,0 +1,78 @@
+variable "name" {
+  description = "Name prefix for every resource"
+  type        = string
+}
+
+variable "vpc_cidr_block" {
+  description = "CIDR block of the VPC"
+  type        = string
+  default     = "10.0.0.0/16"
+}
+
+variable "enable_nat_gateway" {
+  description = "Create NAT gateways for private subnets"
+  type        = bool
+  default     = false
+}
+
+variable "ports" {
+  description = "Ports allowed between subnets"
+  type        = list(string)
+  default     = ["443"]
+}
+
+variable "flow_log_retention" {
+  description = "Retention of flow logs, such as 30d"
+  type        = number
+  default     = 30
+}
+
+variable "subnets" {
+  description = "Subnets to create"
+  type = map(object({
+    cidr   = string
+    public = optional(bool, false)
+    az     = optional(string)
+  }))
+  default = {}
+}
+
+variable "endpoints" {
+  description = "Interface endpoint settings"
+  type = object({
+    services = optional(list(string), [])
+    timeout  = optional(number, 600)
+  })
+  default = {}
+}
+
+variable "labels" {
+  description = "Extra labels"
+  type = map(object({
+    key   = string
+    value = string
+  }))
+  default = {}
+}
+
+variable "kms_key_id" {
+  description = "KMS key for flow logs"
+  type        = string
+}
+
+variable "region" {
+  description = "AWS region"
+  type        = string
+  default     = "us-east-1"
+}
+
+variable "log_bucket" {
+  description = "Bucket that receives flow logs"
+  type        = string
+}
+
+variable "enable_dns_hostnames" {
+  description = "Enable DNS hostnames in the VPC"
+  type        = bool
+  default     = true
+}
@@ -0,0 +1,14 @@
+output "vpc_id" {
+  description = "ID of the VPC"
+  value       = aws_vpc.main.id
+}
+
+output "nat_ip" {
+  description = "Public IP of the NAT gateway"
+  value       = aws_eip.nat.public_ip
+}
+
+output "flow_log_key" {
+  description = "Key of the flow log bucket"
+  value       = aws_s3_bucket.flow_logs.id
+}
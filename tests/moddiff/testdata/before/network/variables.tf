@@ -0,0 +1,69 @@
+variable "name" {
+  description = "Name prefix for resources"
+  type        = string
+}
+
+variable "cidr_block" {
+  description = "CIDR block of the VPC"
+  type        = string
+  default     = "10.0.0.0/16"
+}
+
+variable "enable_nat_gateway" {
+  description = "Create NAT gateways for private subnets"
+  type        = bool
+  default     = true
+}
+
+variable "instance_tenancy" {
+  description = "Tenancy of instances launched into the VPC"
+  type        = string
+  default     = "default"
+}
+
+variable "ports" {
+  description = "Ports allowed between subnets"
+  type        = list(number)
+  default     = [443]
+}
+
+variable "flow_log_retention" {
+  description = "Retention of flow logs, such as 30d"
+  type        = string
+  default     = "30d"
+}
+
+variable "subnets" {
+  description = "Subnets to create"
+  type = map(object({
+    cidr   = string
+    public = optional(bool, false)
+  }))
+  default = {}
+}
+
+variable "endpoints" {
+  description = "Interface endpoint settings"
+  type = object({
+    services = optional(list(string), [])
+    timeout  = optional(number, 300)
+  })
+  default = {}
+}
+
+variable "labels" {
+  description = "Extra labels"
+  type        = map(object({ key = string, value = string }))
+  default     = {}
+}
+
+variable "kms_key_id" {
+  description = "KMS key for flow logs"
+  type        = string
+  default     = null
+}
+
+variable "region" {
+  description = "AWS region"
+  type        = string
+}
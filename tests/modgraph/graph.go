@@ -0,0 +1,265 @@
+// Package modgraph builds the graph of module calls in the examples, the
+// wiring of module outputs into other modules' inputs and the resources of
+// each module straight from HCL, and renders it as DOT or Mermaid
+package modgraph
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Graph is a set of examples and the modules they call
+type Graph struct {
+	Examples []*Example `json:"examples"`
+	Modules  []*Module  `json:"modules"`
+}
+
+// Example is a root configuration that calls modules
+type Example struct {
+	Name  string `json:"name"`
+	Calls []Call `json:"calls"`
+	Wires []Wire `json:"wires"`
+}
+
+// Call is a module block of an example
+type Call struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+
+	// Module is the name of the called module, empty when the source is
+	// not one of the loaded modules, such as a registry module
+	Module string `json:"module,omitempty"`
+}
+
+// Wire is a module call argument that uses another call's output, such as
+// module.vpc.private_subnet_ids passed as subnet_ids to module.rds
+type Wire struct {
+	From string `json:"from"`
+
+	// Output is empty when the argument uses the whole module
+	Output string `json:"output,omitempty"`
+
+	To    string `json:"to"`
+	Input string `json:"input"`
+}
+
+// Module is a module that examples can call
+type Module struct {
+	Name      string   `json:"name"`
+	Resources []string `json:"resources"`
+	Outputs   []string `json:"outputs"`
+}
+
+// Build builds the graph of the given examples and modules, keyed by name.
+// It reports references to module calls an example doesn't declare and to
+// outputs the called module doesn't declare.
+func Build(examples map[string]*tfmodule.Module, modules map[string]*tfmodule.Module) (*Graph, []tfmodule.Diagnostic) {
+	graph := &Graph{Examples: []*Example{}, Modules: []*Module{}}
+	diagnostics := []tfmodule.Diagnostic{}
+
+	moduleDirs := map[string]string{}
+	outputs := map[string]map[string]bool{}
+	for _, name := range sortedNames(modules) {
+		module := modules[name]
+		moduleDirs[absolute(module.Dir)] = name
+
+		node := &Module{Name: name, Resources: []string{}, Outputs: []string{}}
+		outputs[name] = map[string]bool{}
+		for _, block := range module.Blocks {
+			switch block.Type {
+			case "resource":
+				node.Resources = append(node.Resources, block.Address())
+			case "output":
+				node.Outputs = append(node.Outputs, block.Labels[0])
+				outputs[name][block.Labels[0]] = true
+			}
+		}
+		sort.Strings(node.Resources)
+		sort.Strings(node.Outputs)
+		graph.Modules = append(graph.Modules, node)
+	}
+
+	for _, name := range sortedNames(examples) {
+		example := examples[name]
+		node := &Example{Name: name, Calls: []Call{}, Wires: []Wire{}}
+
+		calls := map[string]Call{}
+		for _, block := range example.BlocksOfType("module") {
+			call := Call{Name: block.Labels[0], Source: stringAttribute(block, "source")}
+			if strings.HasPrefix(call.Source, "./") || strings.HasPrefix(call.Source, "../") {
+				call.Module = moduleDirs[absolute(filepath.Join(example.Dir, call.Source))]
+			}
+			calls[call.Name] = call
+			node.Calls = append(node.Calls, call)
+		}
+		sort.Slice(node.Calls, func(i, j int) bool {
+			return node.Calls[i].Name < node.Calls[j].Name
+		})
+
+		indirect := indirectOutputs(example)
+		for _, reference := range example.References() {
+			from, output, ok := moduleReference(reference.Traversal, indirect)
+			if !ok {
+				continue
+			}
+
+			rng := reference.Traversal.SourceRange()
+			pos := func(format string, args ...interface{}) tfmodule.Diagnostic {
+				return tfmodule.Diagnostic{
+					File:    filepath.Join(example.Dir, filepath.Base(rng.Filename)),
+					Line:    rng.Start.Line,
+					Message: fmt.Sprintf(format, args...),
+				}
+			}
+
+			call, declared := calls[from]
+			switch {
+			case !declared:
+				diagnostics = append(diagnostics, pos("reference to undeclared module.%s", from))
+			case call.Module != "" && output != "" && !outputs[call.Module][output]:
+				diagnostics = append(diagnostics, pos("module.%s.%s is not an output of modules/%s", from, output, call.Module))
+			}
+		}
+
+		for _, block := range example.BlocksOfType("module") {
+			node.Wires = append(node.Wires, callWires(block, indirect)...)
+		}
+		sort.Slice(node.Wires, func(i, j int) bool {
+			a, b := node.Wires[i], node.Wires[j]
+			if a.To != b.To {
+				return a.To < b.To
+			}
+			if a.Input != b.Input {
+				return a.Input < b.Input
+			}
+			if a.From != b.From {
+				return a.From < b.From
+			}
+			return a.Output < b.Output
+		})
+
+		graph.Examples = append(graph.Examples, node)
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].File != diagnostics[j].File {
+			return diagnostics[i].File < diagnostics[j].File
+		}
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+	return graph, diagnostics
+}
+
+// callWires returns the other module calls whose outputs the arguments of a
+// module block use
+func callWires(block *tfmodule.Block, indirect map[hcl.Range]string) []Wire {
+	wires := []Wire{}
+	seen := map[Wire]bool{}
+	for input, attr := range block.Body.Attributes {
+		for _, traversal := range attr.Expr.Variables() {
+			from, output, ok := moduleReference(traversal, indirect)
+			wire := Wire{From: from, Output: output, To: block.Labels[0], Input: input}
+			if !ok || seen[wire] {
+				continue
+			}
+			seen[wire] = true
+			wires = append(wires, wire)
+		}
+	}
+	return wires
+}
+
+// moduleReference returns the module call and output a traversal such as
+// module.vpc.vpc_id or module.web_server[0].instance_id points at. Traversals
+// that stop short of the output are looked up in indirect.
+func moduleReference(traversal hcl.Traversal, indirect map[hcl.Range]string) (string, string, bool) {
+	if traversal.RootName() != "module" || len(traversal) < 2 {
+		return "", "", false
+	}
+	call, ok := traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return "", "", false
+	}
+
+	for _, step := range traversal[2:] {
+		switch step := step.(type) {
+		case hcl.TraverseAttr:
+			return call.Name, step.Name, true
+		case hcl.TraverseIndex, hcl.TraverseSplat:
+			continue
+		}
+		break
+	}
+	return call.Name, indirect[traversal.SourceRange()], true
+}
+
+// indirectOutputs finds the module references whose output follows a splat
+// or a computed index, as in module.web_server[*].instance_id or
+// module.web_server[index].instance_id. HCL ends the static traversal at
+// module.web_server there, so the output is returned keyed by its range.
+func indirectOutputs(m *tfmodule.Module) map[hcl.Range]string {
+	outputs := map[hcl.Range]string{}
+	record := func(source hclsyntax.Expression, traversal hcl.Traversal) {
+		if index, ok := source.(*hclsyntax.IndexExpr); ok {
+			source = index.Collection
+		}
+		scope, ok := source.(*hclsyntax.ScopeTraversalExpr)
+		if !ok || scope.Traversal.RootName() != "module" || len(traversal) == 0 {
+			return
+		}
+		if attr, ok := traversal[0].(hcl.TraverseAttr); ok {
+			outputs[scope.Traversal.SourceRange()] = attr.Name
+		}
+	}
+
+	for _, block := range m.Blocks {
+		hclsyntax.VisitAll(block.Body, func(node hclsyntax.Node) hcl.Diagnostics {
+			switch expr := node.(type) {
+			case *hclsyntax.RelativeTraversalExpr:
+				record(expr.Source, expr.Traversal)
+			case *hclsyntax.SplatExpr:
+				if each, ok := expr.Each.(*hclsyntax.RelativeTraversalExpr); ok {
+					record(expr.Source, each.Traversal)
+				}
+			}
+			return nil
+		})
+	}
+	return outputs
+}
+
+func stringAttribute(block *tfmodule.Block, name string) string {
+	attr, ok := block.Body.Attributes[name]
+	if !ok {
+		return ""
+	}
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || value.IsNull() || !value.Type().Equals(cty.String) {
+		return ""
+	}
+	return value.AsString()
+}
+
+func absolute(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+func sortedNames(modules map[string]*tfmodule.Module) []string {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
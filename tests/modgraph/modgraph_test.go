@@ -0,0 +1,136 @@
+package modgraph
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildWiresOutputsToInputs(t *testing.T) {
+	t.Parallel()
+
+	graph, diagnostics := buildGraph(t, "web")
+	assert.Empty(t, diagnostics)
+
+	require.Len(t, graph.Examples, 1)
+	web := graph.Examples[0]
+	assert.Equal(t, []Call{
+		{Name: "api", Source: "../../modules/service", Module: "service"},
+		{Name: "labels", Source: "cloudposse/label/null"},
+		{Name: "network", Source: "../../modules/network", Module: "network"},
+		{Name: "worker", Source: "../../modules/service", Module: "service"},
+	}, web.Calls)
+	assert.Equal(t, []Wire{
+		{From: "network", Output: "subnet_ids", To: "api", Input: "subnet_ids"},
+		{From: "network", Output: "vpc_id", To: "api", Input: "vpc_id"},
+		{From: "api", To: "worker", Input: "peers"},
+		{From: "network", Output: "subnet_ids", To: "worker", Input: "subnet_ids"},
+		{From: "network", Output: "vpc_id", To: "worker", Input: "vpc_id"},
+	}, web.Wires)
+
+	require.Len(t, graph.Modules, 3)
+	assert.Equal(t, &Module{
+		Name:      "network",
+		Resources: []string{"aws_subnet.private", "aws_vpc.main"},
+		Outputs:   []string{"subnet_ids", "vpc_id"},
+	}, graph.Modules[0])
+}
+
+func TestBuildReportsUndeclaredOutputs(t *testing.T) {
+	t.Parallel()
+
+	graph, diagnostics := buildGraph(t, "broken")
+
+	messages := []string{}
+	for _, diagnostic := range diagnostics {
+		messages = append(messages, diagnostic.String())
+	}
+	mainFile := filepath.Join("testdata", "examples", "broken", "main.tf")
+	outputsFile := filepath.Join("testdata", "examples", "broken", "outputs.tf")
+	assert.Equal(t, []string{
+		mainFile + ":27: module.workers.service_id is not an output of modules/service",
+		outputsFile + ":2: module.network.private_subnet_ids is not an output of modules/network",
+		outputsFile + ":6: reference to undeclared module.queue",
+		outputsFile + ":14: module.workers.name is not an output of modules/service",
+	}, messages, "outputs of registry modules are not checked")
+
+	// The output after a computed index is recovered for the wire too
+	assert.Equal(t, []Wire{
+		{From: "workers", Output: "service_id", To: "monitor", Input: "peers"},
+	}, graph.Examples[0].Wires)
+}
+
+func TestWriteDOT(t *testing.T) {
+	t.Parallel()
+
+	graph, _ := buildGraph(t, "web")
+	var output strings.Builder
+	require.NoError(t, graph.WriteDOT(&output, Options{Resources: true}))
+
+	assert.Equal(t, `digraph modules {
+  rankdir = "LR"
+  node [shape = "box"]
+
+  subgraph "cluster_web" {
+    label = "examples/web"
+    "web/module.api" [label = "module.api"]
+    "web/module.labels" [label = "module.labels"]
+    "web/module.network" [label = "module.network"]
+    "web/module.worker" [label = "module.worker"]
+  }
+
+  "modules/network" [label = "modules/network\naws_subnet.private\naws_vpc.main", shape = "component"]
+  "modules/service" [label = "modules/service\naws_ecs_service.main", shape = "component"]
+
+  "web/module.api" -> "modules/service" [style = "dashed"]
+  "web/module.network" -> "modules/network" [style = "dashed"]
+  "web/module.worker" -> "modules/service" [style = "dashed"]
+  "web/module.network" -> "web/module.api" [label = "subnet_ids -> subnet_ids"]
+  "web/module.network" -> "web/module.api" [label = "vpc_id -> vpc_id"]
+  "web/module.api" -> "web/module.worker" [label = "peers"]
+  "web/module.network" -> "web/module.worker" [label = "subnet_ids -> subnet_ids"]
+  "web/module.network" -> "web/module.worker" [label = "vpc_id -> vpc_id"]
+}
+`, output.String(), "the unused module is left out")
+}
+
+func TestWriteMermaid(t *testing.T) {
+	t.Parallel()
+
+	graph, _ := buildGraph(t, "web")
+	var output strings.Builder
+	require.NoError(t, graph.WriteMermaid(&output, Options{}))
+
+	assert.Equal(t, `flowchart LR
+  subgraph example_web["examples/web"]
+    web_module_api["module.api"]
+    web_module_labels["module.labels"]
+    web_module_network["module.network"]
+    web_module_worker["module.worker"]
+  end
+  modules_network[["modules/network"]]
+  modules_service[["modules/service"]]
+  web_module_api -.-> modules_service
+  web_module_network -.-> modules_network
+  web_module_worker -.-> modules_service
+  web_module_network -->|"subnet_ids -> subnet_ids"| web_module_api
+  web_module_network -->|"vpc_id -> vpc_id"| web_module_api
+  web_module_api -->|"peers"| web_module_worker
+  web_module_network -->|"subnet_ids -> subnet_ids"| web_module_worker
+  web_module_network -->|"vpc_id -> vpc_id"| web_module_worker
+`, output.String())
+}
+
+// buildGraph builds the graph of one example under testdata/examples and
+// every module under testdata/modules
+func buildGraph(t *testing.T, example string) (*Graph, []tfmodule.Diagnostic) {
+	module, err := tfmodule.Load(filepath.Join("testdata", "examples", example))
+	require.NoError(t, err)
+	modules, err := tfmodule.LoadAll(filepath.Join("testdata", "modules"))
+	require.NoError(t, err)
+	return Build(map[string]*tfmodule.Module{example: module}, modules)
+}
@@ -0,0 +1,138 @@
+package modgraph
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Options controls what the renderers include
+type Options struct {
+	// Resources lists the resources of each module in its node
+	Resources bool
+}
+
+// nonIdentifier matches what can't appear in a Mermaid node ID
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// calledModules returns the modules that at least one example calls, so that
+// unused modules don't clutter the graph
+func (g *Graph) calledModules() []*Module {
+	called := map[string]bool{}
+	for _, example := range g.Examples {
+		for _, call := range example.Calls {
+			called[call.Module] = true
+		}
+	}
+
+	modules := []*Module{}
+	for _, module := range g.Modules {
+		if called[module.Name] {
+			modules = append(modules, module)
+		}
+	}
+	return modules
+}
+
+// WriteDOT renders the graph in Graphviz DOT. Each example is a cluster of
+// its module calls, wires are labeled output -> input, and dashed edges lead
+// from each call to the module it uses.
+func (g *Graph) WriteDOT(w io.Writer, options Options) error {
+	var b strings.Builder
+	b.WriteString("digraph modules {\n")
+	b.WriteString("  rankdir = \"LR\"\n")
+	b.WriteString("  node [shape = \"box\"]\n")
+
+	for _, example := range g.Examples {
+		fmt.Fprintf(&b, "\n  subgraph %q {\n", "cluster_"+example.Name)
+		fmt.Fprintf(&b, "    label = %q\n", "examples/"+example.Name)
+		for _, call := range example.Calls {
+			fmt.Fprintf(&b, "    %q [label = %q]\n", callID(example, call.Name), "module."+call.Name)
+		}
+		b.WriteString("  }\n")
+	}
+
+	b.WriteString("\n")
+	for _, module := range g.calledModules() {
+		label := "modules/" + module.Name
+		if options.Resources {
+			label += "\n" + strings.Join(module.Resources, "\n")
+		}
+		fmt.Fprintf(&b, "  %q [label = %q, shape = \"component\"]\n", moduleID(module.Name), label)
+	}
+
+	for _, example := range g.Examples {
+		b.WriteString("\n")
+		for _, call := range example.Calls {
+			if call.Module != "" {
+				fmt.Fprintf(&b, "  %q -> %q [style = \"dashed\"]\n", callID(example, call.Name), moduleID(call.Module))
+			}
+		}
+		for _, wire := range example.Wires {
+			fmt.Fprintf(&b, "  %q -> %q [label = %q]\n", callID(example, wire.From), callID(example, wire.To), wire.label())
+		}
+	}
+
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid renders the graph as a Mermaid flowchart, in the same layout
+// as WriteDOT
+func (g *Graph) WriteMermaid(w io.Writer, options Options) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	for _, example := range g.Examples {
+		fmt.Fprintf(&b, "  subgraph %s[\"examples/%s\"]\n", mermaidID("example_"+example.Name), example.Name)
+		for _, call := range example.Calls {
+			fmt.Fprintf(&b, "    %s[\"module.%s\"]\n", mermaidID(callID(example, call.Name)), call.Name)
+		}
+		b.WriteString("  end\n")
+	}
+
+	for _, module := range g.calledModules() {
+		label := "modules/" + module.Name
+		if options.Resources {
+			label += "<br/>" + strings.Join(module.Resources, "<br/>")
+		}
+		fmt.Fprintf(&b, "  %s[[\"%s\"]]\n", mermaidID(moduleID(module.Name)), label)
+	}
+
+	for _, example := range g.Examples {
+		for _, call := range example.Calls {
+			if call.Module != "" {
+				fmt.Fprintf(&b, "  %s -.-> %s\n", mermaidID(callID(example, call.Name)), mermaidID(moduleID(call.Module)))
+			}
+		}
+		for _, wire := range example.Wires {
+			fmt.Fprintf(&b, "  %s -->|\"%s\"| %s\n", mermaidID(callID(example, wire.From)), wire.label(), mermaidID(callID(example, wire.To)))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// label renders a wire as output -> input, or only the input when the whole
+// module is used
+func (w Wire) label() string {
+	if w.Output == "" {
+		return w.Input
+	}
+	return w.Output + " -> " + w.Input
+}
+
+func callID(example *Example, call string) string {
+	return example.Name + "/module." + call
+}
+
+func moduleID(module string) string {
+	return "modules/" + module
+}
+
+func mermaidID(id string) string {
+	return nonIdentifier.ReplaceAllString(id, "_")
+}
@@ -0,0 +1,28 @@
+module "network" {
+  source = "../../modules/network"
+
+  cidr_block = "10.0.0.0/16"
+}
+
+module "labels" {
+  source  = "cloudposse/label/null"
+  version = "0.25.0"
+}
+
+module "workers" {
+  source = "../../modules/service"
+  count  = 2
+
+  name       = "worker-${count.index}"
+  vpc_id     = "vpc-0123456789abcdef0"
+  subnet_ids = []
+}
+
+module "monitor" {
+  source = "../../modules/service"
+
+  name       = "monitor"
+  vpc_id     = "vpc-0123456789abcdef0"
+  subnet_ids = []
+  peers      = [for index in range(2) : module.workers[index].service_id]
+}
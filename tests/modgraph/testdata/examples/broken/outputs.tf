@@ -0,0 +1,15 @@
+output "private_subnet_ids" {
+  value = module.network.private_subnet_ids
+}
+
+output "queue_url" {
+  value = module.queue.url
+}
+
+output "label" {
+  value = module.labels.id
+}
+
+output "worker_names" {
+  value = module.workers[*].name
+}
@@ -0,0 +1,28 @@
+module "network" {
+  source = "../../modules/network"
+
+  cidr_block = "10.0.0.0/16"
+}
+
+module "api" {
+  source = "../../modules/service"
+
+  name       = "api"
+  vpc_id     = module.network.vpc_id
+  subnet_ids = module.network.subnet_ids
+}
+
+module "worker" {
+  source = "../../modules/service"
+  count  = 2
+
+  name       = "worker-${count.index}"
+  vpc_id     = module.network.vpc_id
+  subnet_ids = [module.network.subnet_ids[0]]
+  peers      = module.api
+}
+
+module "labels" {
+  source  = "cloudposse/label/null"
+  version = "0.25.0"
+}
@@ -0,0 +1,3 @@
+output "worker_names" {
+  value = module.worker[*].service_name
+}
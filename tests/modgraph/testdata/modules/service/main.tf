@@ -0,0 +1,3 @@
+resource "aws_ecs_service" "main" {
+  name = var.name
+}
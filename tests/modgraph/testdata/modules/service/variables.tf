@@ -0,0 +1,16 @@
+variable "name" {
+  type = string
+}
+
+variable "vpc_id" {
+  type = string
+}
+
+variable "subnet_ids" {
+  type = list(string)
+}
+
+variable "peers" {
+  type    = any
+  default = null
+}
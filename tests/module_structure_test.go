@@ -0,0 +1,72 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/stretchr/testify/require"
+)
+
+// TestModuleStructure parses every module with the HCL parser and checks file
+// layout and references without invoking terraform
+func TestModuleStructure(t *testing.T) {
+	t.Parallel()
+
+	modules, err := tfmodule.LoadAll("../modules")
+	require.NoError(t, err)
+	require.NotEmpty(t, modules)
+
+	for name, module := range modules {
+		module := module
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, diagnostic := range tfmodule.CheckStructure(module) {
+				t.Error(diagnostic.String())
+			}
+		})
+	}
+}
+
+// TestModuleTagPropagation checks that every taggable resource in every
+// module passes var.tags on in full
+func TestModuleTagPropagation(t *testing.T) {
+	t.Parallel()
+
+	modules, err := tfmodule.LoadAll("../modules")
+	require.NoError(t, err)
+	require.NotEmpty(t, modules)
+
+	for name, module := range modules {
+		module := module
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, diagnostic := range tfmodule.CheckTags(module) {
+				t.Error(diagnostic.String())
+			}
+		})
+	}
+}
+
+// TestExampleModuleCalls type-checks the arguments every example passes to
+// the modules against their variables, without terraform init
+func TestExampleModuleCalls(t *testing.T) {
+	t.Parallel()
+
+	examples, err := tfmodule.LoadAll("../examples")
+	require.NoError(t, err)
+	require.NotEmpty(t, examples)
+
+	for name, example := range examples {
+		example := example
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.NotEmpty(t, example.BlocksOfType("module"))
+			for _, diagnostic := range tfmodule.CheckModuleCalls(example) {
+				t.Error(diagnostic.String())
+			}
+		})
+	}
+}
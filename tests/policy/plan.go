@@ -0,0 +1,326 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Resource is a managed resource from the planned values of a plan
+type Resource struct {
+	Address string
+	Type    string
+	Name    string
+
+	// Values holds the planned attribute values. Attributes only known after
+	// apply are missing here and set in Unknown instead.
+	Values  map[string]interface{}
+	Unknown map[string]interface{}
+
+	// References maps each attribute set from an expression to the managed
+	// resources the expression refers to, such as
+	// module.eks.aws_security_group.node. Attributes of nested blocks are
+	// keyed by their dotted path, such as vpc_config.security_group_ids.
+	// This resolves attributes whose values are only known after apply.
+	References map[string][]string
+}
+
+// Plan is the part of `terraform show -json` output the rules evaluate
+type Plan struct {
+	Resources []Resource
+
+	// Accounts are the AWS account IDs the plan deploys into: the
+	// account_id of data.aws_caller_identity when the plan reads it, and
+	// otherwise the accounts in the arn attributes of its resources and
+	// data sources
+	Accounts []string
+}
+
+type planJSON struct {
+	PlannedValues struct {
+		RootModule moduleJSON `json:"root_module"`
+	} `json:"planned_values"`
+	PriorState struct {
+		Values struct {
+			RootModule moduleJSON `json:"root_module"`
+		} `json:"values"`
+	} `json:"prior_state"`
+	Configuration struct {
+		RootModule configModuleJSON `json:"root_module"`
+	} `json:"configuration"`
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			AfterUnknown interface{} `json:"after_unknown"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+type moduleJSON struct {
+	Address   string `json:"address"`
+	Resources []struct {
+		Address string                 `json:"address"`
+		Mode    string                 `json:"mode"`
+		Type    string                 `json:"type"`
+		Name    string                 `json:"name"`
+		Values  map[string]interface{} `json:"values"`
+	} `json:"resources"`
+	ChildModules []moduleJSON `json:"child_modules"`
+}
+
+type configModuleJSON struct {
+	Resources []struct {
+		Address     string                     `json:"address"`
+		Mode        string                     `json:"mode"`
+		Expressions map[string]json.RawMessage `json:"expressions"`
+	} `json:"resources"`
+	ModuleCalls map[string]struct {
+		Module configModuleJSON `json:"module"`
+	} `json:"module_calls"`
+}
+
+// arnAccountPattern captures the account ID of an ARN
+var arnAccountPattern = regexp.MustCompile(`^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:([0-9]{12}):`)
+
+// instanceKeyPattern matches the count and for_each keys of an address
+var instanceKeyPattern = regexp.MustCompile(`\[(?:"(?:[^"\\]|\\.)*"|[0-9]+)\]`)
+
+// referencePrefixes start references that don't name a managed resource
+var referencePrefixes = map[string]bool{
+	"count": true, "data": true, "each": true, "local": true, "module": true,
+	"path": true, "self": true, "terraform": true, "var": true,
+}
+
+// LoadPlan reads a plan rendered with `terraform show -json`
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := ParsePlan(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plan, nil
+}
+
+// ParsePlan parses the JSON rendering of a plan. Data sources are left out
+// and resources are sorted by address. Data sources read during the plan
+// are in its prior state, which is only used to find the plan's accounts.
+func ParsePlan(data []byte) (*Plan, error) {
+	var raw planJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing plan JSON: %w", err)
+	}
+
+	unknown := map[string]map[string]interface{}{}
+	for _, change := range raw.ResourceChanges {
+		if values, ok := change.Change.AfterUnknown.(map[string]interface{}); ok {
+			unknown[change.Address] = values
+		}
+	}
+
+	references := map[string]map[string][]string{}
+	collectReferences(raw.Configuration.RootModule, "", references)
+
+	plan := &Plan{}
+	collectResources(raw.PlannedValues.RootModule, unknown, references, plan)
+	sort.Slice(plan.Resources, func(i, j int) bool {
+		return plan.Resources[i].Address < plan.Resources[j].Address
+	})
+	plan.Accounts = planAccounts(raw.PlannedValues.RootModule, raw.PriorState.Values.RootModule)
+
+	return plan, nil
+}
+
+// planAccounts returns the sorted accounts of the caller identities in the
+// modules or, without any, the accounts of the ARNs of their resources
+func planAccounts(modules ...moduleJSON) []string {
+	callers := map[string]bool{}
+	owners := map[string]bool{}
+	var walk func(module moduleJSON)
+	walk = func(module moduleJSON) {
+		for _, resource := range module.Resources {
+			if account, ok := resource.Values["account_id"].(string); ok && resource.Mode == "data" && resource.Type == "aws_caller_identity" {
+				callers[account] = true
+			}
+			if arn, ok := resource.Values["arn"].(string); ok {
+				if match := arnAccountPattern.FindStringSubmatch(arn); match != nil {
+					owners[match[1]] = true
+				}
+			}
+		}
+		for _, child := range module.ChildModules {
+			walk(child)
+		}
+	}
+	for _, module := range modules {
+		walk(module)
+	}
+
+	if len(callers) == 0 {
+		callers = owners
+	}
+	accounts := []string{}
+	for account := range callers {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	return accounts
+}
+
+func collectResources(module moduleJSON, unknown map[string]map[string]interface{}, references map[string]map[string][]string, plan *Plan) {
+	for _, resource := range module.Resources {
+		if resource.Mode != "managed" {
+			continue
+		}
+
+		values := resource.Values
+		if values == nil {
+			values = map[string]interface{}{}
+		}
+		unknownValues := unknown[resource.Address]
+		if unknownValues == nil {
+			unknownValues = map[string]interface{}{}
+		}
+
+		// References in the configuration are relative to the module, so
+		// they are resolved within this instance of it
+		resourceReferences := map[string][]string{}
+		for attribute, addresses := range references[instanceKeyPattern.ReplaceAllString(resource.Address, "")] {
+			for _, address := range addresses {
+				if module.Address != "" {
+					address = module.Address + "." + address
+				}
+				resourceReferences[attribute] = append(resourceReferences[attribute], address)
+			}
+		}
+
+		plan.Resources = append(plan.Resources, Resource{
+			Address:    resource.Address,
+			Type:       resource.Type,
+			Name:       resource.Name,
+			Values:     values,
+			Unknown:    unknownValues,
+			References: resourceReferences,
+		})
+	}
+
+	for _, child := range module.ChildModules {
+		collectResources(child, unknown, references, plan)
+	}
+}
+
+// collectReferences records the managed resources each configured resource
+// refers to, keyed by its address without instance keys
+func collectReferences(module configModuleJSON, prefix string, references map[string]map[string][]string) {
+	for _, resource := range module.Resources {
+		if resource.Mode != "managed" {
+			continue
+		}
+		attributes := map[string][]string{}
+		for name, expression := range resource.Expressions {
+			collectExpressionReferences(name, expression, attributes)
+		}
+		references[prefix+resource.Address] = attributes
+	}
+
+	for name, call := range module.ModuleCalls {
+		collectReferences(call.Module, prefix+"module."+name+".", references)
+	}
+}
+
+// collectExpressionReferences adds the resources an expression refers to.
+// Nested blocks are rendered as an object of expressions, or a list of them,
+// and are walked with their attributes keyed by dotted path.
+func collectExpressionReferences(name string, raw json.RawMessage, attributes map[string][]string) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return
+	}
+
+	if raw[0] == '[' {
+		var blocks []json.RawMessage
+		if err := json.Unmarshal(raw, &blocks); err == nil {
+			for _, block := range blocks {
+				collectExpressionReferences(name, block, attributes)
+			}
+		}
+		return
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return
+	}
+
+	_, hasReferences := object["references"]
+	_, hasConstant := object["constant_value"]
+	if !hasReferences && !hasConstant {
+		for nestedName, nested := range object {
+			collectExpressionReferences(name+"."+nestedName, nested, attributes)
+		}
+		return
+	}
+
+	var expression struct {
+		References []string `json:"references"`
+	}
+	if err := json.Unmarshal(raw, &expression); err != nil {
+		return
+	}
+	for _, reference := range expression.References {
+		parts := strings.SplitN(reference, ".", 3)
+		if len(parts) < 2 || referencePrefixes[parts[0]] {
+			continue
+		}
+
+		address := parts[0] + "." + instanceKeyPattern.ReplaceAllString(parts[1], "")
+		if !containsString(attributes[name], address) {
+			attributes[name] = append(attributes[name], address)
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUnknown reports whether a top-level attribute is only known after apply
+func (r Resource) IsUnknown(name string) bool {
+	unknown, _ := r.Unknown[name].(bool)
+	return unknown
+}
+
+// Bool returns a boolean attribute, and false if it is unset
+func (r Resource) Bool(name string) bool {
+	value, _ := r.Values[name].(bool)
+	return value
+}
+
+// String returns a string attribute, and "" if it is unset
+func (r Resource) String(name string) string {
+	value, _ := r.Values[name].(string)
+	return value
+}
+
+// Block returns the first instance of a nested block, or nil if the block is
+// absent
+func (r Resource) Block(name string) map[string]interface{} {
+	blocks, _ := r.Values[name].([]interface{})
+	if len(blocks) == 0 {
+		return nil
+	}
+	block, _ := blocks[0].(map[string]interface{})
+	return block
+}
@@ -0,0 +1,188 @@
+// Package policy evaluates typed rules against Terraform plan JSON and
+// reports per-resource findings
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severity ranks how serious a finding is
+type Severity int
+
+const (
+	SeverityLow Severity = iota + 1
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = map[Severity]string{
+	SeverityLow:      "LOW",
+	SeverityMedium:   "MEDIUM",
+	SeverityHigh:     "HIGH",
+	SeverityCritical: "CRITICAL",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText renders the severity by name in JSON reports
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity parses a severity name, ignoring case
+func ParseSeverity(name string) (Severity, error) {
+	for severity, severityName := range severityNames {
+		if strings.EqualFold(name, severityName) {
+			return severity, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q, expected one of low, medium, high, critical", name)
+}
+
+// Rule checks every planned resource of the listed types. Check returns one
+// message per violation, or nothing if the resource complies.
+type Rule struct {
+	ID            string
+	Description   string
+	Severity      Severity
+	ResourceTypes []string
+	Check         func(resource Resource) []string
+}
+
+// Finding is a rule violation by one resource
+type Finding struct {
+	RuleID     string   `json:"rule_id"`
+	Severity   Severity `json:"severity"`
+	Address    string   `json:"address"`
+	Message    string   `json:"message"`
+	Suppressed bool     `json:"suppressed"`
+
+	// Reason is the justification of the suppression that matched
+	Reason string `json:"reason,omitempty"`
+}
+
+func (f Finding) String() string {
+	s := fmt.Sprintf("[%s] %s %s: %s", f.Severity, f.RuleID, f.Address, f.Message)
+	if f.Suppressed {
+		s += fmt.Sprintf(" (suppressed: %s)", f.Reason)
+	}
+	return s
+}
+
+// Suppression silences a rule for matching resource addresses. Address may
+// use * to match any run of characters, and an empty address matches every
+// resource.
+type Suppression struct {
+	RuleID  string `json:"rule_id"`
+	Address string `json:"address"`
+	Reason  string `json:"reason"`
+}
+
+func (s Suppression) matches(finding Finding) bool {
+	if s.RuleID != finding.RuleID {
+		return false
+	}
+	if s.Address == "" {
+		return true
+	}
+
+	parts := strings.Split(s.Address, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(finding.Address)
+}
+
+// LoadSuppressions reads a JSON array of suppressions. Every suppression must
+// name a rule and give a reason.
+func LoadSuppressions(path string) ([]Suppression, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var suppressions []Suppression
+	if err := json.Unmarshal(data, &suppressions); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, suppression := range suppressions {
+		if suppression.RuleID == "" || suppression.Reason == "" {
+			return nil, fmt.Errorf("%s: suppression %d needs both rule_id and reason", path, i)
+		}
+	}
+	return suppressions, nil
+}
+
+// Evaluate runs every rule against the plan's resources. Findings matched by
+// a suppression are kept but marked suppressed. Findings are sorted by
+// severity, most severe first, then by address and rule.
+func Evaluate(plan *Plan, rules []Rule, suppressions []Suppression) []Finding {
+	findings := []Finding{}
+	for _, resource := range plan.Resources {
+		for _, rule := range rules {
+			if !contains(rule.ResourceTypes, resource.Type) {
+				continue
+			}
+
+			for _, message := range rule.Check(resource) {
+				finding := Finding{
+					RuleID:   rule.ID,
+					Severity: rule.Severity,
+					Address:  resource.Address,
+					Message:  message,
+				}
+				for _, suppression := range suppressions {
+					if suppression.matches(finding) {
+						finding.Suppressed = true
+						finding.Reason = suppression.Reason
+						break
+					}
+				}
+				findings = append(findings, finding)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity > findings[j].Severity
+		}
+		if findings[i].Address != findings[j].Address {
+			return findings[i].Address < findings[j].Address
+		}
+		return findings[i].RuleID < findings[j].RuleID
+	})
+	return findings
+}
+
+// Unsuppressed returns the findings that are not suppressed and at least as
+// severe as minimum
+func Unsuppressed(findings []Finding, minimum Severity) []Finding {
+	result := []Finding{}
+	for _, finding := range findings {
+		if !finding.Suppressed && finding.Severity >= minimum {
+			result = append(result, finding)
+		}
+	}
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlanCollectsManagedResourcesFromChildModules(t *testing.T) {
+	t.Parallel()
+
+	plan, err := LoadPlan(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+
+	addresses := []string{}
+	for _, resource := range plan.Resources {
+		addresses = append(addresses, resource.Address)
+	}
+	assert.Equal(t, []string{
+		"aws_instance.bastion",
+		"module.assets.aws_s3_bucket_public_access_block.main",
+		"module.cache.aws_elasticache_replication_group.redis[0]",
+		"module.eks.aws_eks_cluster.main",
+		"module.eks_internal.aws_eks_cluster.main",
+		"module.legacy.aws_instance.main",
+		"module.rds.aws_db_instance.main",
+		"module.rds.aws_db_instance.replica[0]",
+		"module.web[0].aws_instance.main",
+	}, addresses)
+}
+
+func TestParsePlanResolvesReferencesWithinModuleInstances(t *testing.T) {
+	t.Parallel()
+
+	plan, err := ParsePlan([]byte(`{
+		"planned_values": {"root_module": {"child_modules": [{
+			"address": "module.eks[\"blue\"]",
+			"resources": [{
+				"address": "module.eks[\"blue\"].aws_eks_cluster.main",
+				"mode": "managed",
+				"type": "aws_eks_cluster",
+				"name": "main",
+				"values": {"name": "blue"}
+			}]
+		}]}},
+		"configuration": {"root_module": {"module_calls": {"eks": {"module": {"resources": [{
+			"address": "aws_eks_cluster.main",
+			"mode": "managed",
+			"expressions": {
+				"name": {"references": ["var.cluster_name"]},
+				"role_arn": {"references": ["aws_iam_role.cluster[0].arn", "aws_iam_role.cluster[0]", "aws_iam_role.cluster"]},
+				"vpc_config": [{
+					"security_group_ids": {"references": ["aws_security_group.cluster.id", "aws_security_group.cluster", "data.aws_security_group.extra.id"]},
+					"endpoint_private_access": {"constant_value": true}
+				}]
+			}
+		}]}}}}}
+	}`))
+	require.NoError(t, err)
+
+	require.Len(t, plan.Resources, 1)
+	assert.Equal(t, map[string][]string{
+		"role_arn":                      {`module.eks["blue"].aws_iam_role.cluster`},
+		"vpc_config.security_group_ids": {`module.eks["blue"].aws_security_group.cluster`},
+	}, plan.Resources[0].References)
+}
+
+func TestParsePlanFindsAccounts(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		plan     string
+		expected []string
+	}{
+		{"caller identity", `{
+			"planned_values": {"root_module": {"resources": [{
+				"address": "aws_iam_role.ci",
+				"mode": "managed",
+				"type": "aws_iam_role",
+				"values": {"arn": "arn:aws:iam::210987654321:role/ci"}
+			}]}},
+			"prior_state": {"values": {"root_module": {"child_modules": [{"resources": [{
+				"address": "module.kms.data.aws_caller_identity.current",
+				"mode": "data",
+				"type": "aws_caller_identity",
+				"values": {"account_id": "123456789012", "arn": "arn:aws:sts::123456789012:assumed-role/deploy/ci"}
+			}]}]}}}
+		}`, []string{"123456789012"}},
+		{"resource ARNs", `{
+			"planned_values": {"root_module": {"resources": [{
+				"address": "aws_s3_bucket.logs",
+				"mode": "managed",
+				"type": "aws_s3_bucket",
+				"values": {"arn": "arn:aws:s3:::logs"}
+			}, {
+				"address": "aws_sns_topic.alerts",
+				"mode": "managed",
+				"type": "aws_sns_topic",
+				"values": {"arn": "arn:aws:sns:us-east-1:123456789012:alerts", "policy": "arn:aws:iam::345678901234:root"}
+			}]}}
+		}`, []string{"123456789012"}},
+		{"nothing known", `{"planned_values": {"root_module": {}}}`, []string{}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			plan, err := ParsePlan([]byte(testCase.plan))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, plan.Accounts)
+		})
+	}
+}
+
+func TestEvaluateSecurityRules(t *testing.T) {
+	t.Parallel()
+
+	plan, err := LoadPlan(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+
+	findings := []string{}
+	for _, finding := range Evaluate(plan, SecurityRules(), nil) {
+		findings = append(findings, finding.String())
+	}
+
+	assert.Equal(t, []string{
+		"[CRITICAL] RDS002 module.rds.aws_db_instance.main: publicly_accessible is true",
+		"[HIGH] S3001 module.assets.aws_s3_bucket_public_access_block.main: block_public_policy is not true",
+		"[HIGH] S3001 module.assets.aws_s3_bucket_public_access_block.main: restrict_public_buckets is not true",
+		"[HIGH] ECA001 module.cache.aws_elasticache_replication_group.redis[0]: transit_encryption_enabled is not true",
+		"[HIGH] EKS001 module.eks.aws_eks_cluster.main: endpoint_public_access is true and public_access_cidrs contains 0.0.0.0/0",
+		"[HIGH] RDS001 module.rds.aws_db_instance.main: storage_encrypted is not true",
+		`[MEDIUM] EC2001 aws_instance.bastion: metadata_options is not set, so IMDSv1 stays enabled`,
+		`[MEDIUM] EC2001 module.legacy.aws_instance.main: metadata_options.http_tokens is "optional", expected "required"`,
+	}, findings)
+}
+
+func TestEvaluateAppliesSuppressions(t *testing.T) {
+	t.Parallel()
+
+	plan, err := LoadPlan(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+	suppressions, err := LoadSuppressions(filepath.Join("testdata", "suppressions.json"))
+	require.NoError(t, err)
+
+	findings := Evaluate(plan, SecurityRules(), suppressions)
+
+	suppressed := map[string]string{}
+	for _, finding := range findings {
+		if finding.Suppressed {
+			suppressed[finding.RuleID+" "+finding.Address] = finding.Reason
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"EC2001 module.legacy.aws_instance.main":                     "Legacy AMI whose agent only speaks IMDSv1, tracked for replacement",
+		"S3001 module.assets.aws_s3_bucket_public_access_block.main": "Public website bucket",
+	}, suppressed)
+
+	remaining := []string{}
+	for _, finding := range Unsuppressed(findings, SeverityHigh) {
+		remaining = append(remaining, finding.RuleID+" "+finding.Address)
+	}
+	assert.Equal(t, []string{
+		"RDS002 module.rds.aws_db_instance.main",
+		"ECA001 module.cache.aws_elasticache_replication_group.redis[0]",
+		"EKS001 module.eks.aws_eks_cluster.main",
+		"RDS001 module.rds.aws_db_instance.main",
+	}, remaining)
+}
+
+func TestSuppressionMatching(t *testing.T) {
+	t.Parallel()
+
+	finding := Finding{RuleID: "EC2001", Address: `module.web["a"].aws_instance.main`}
+
+	testCases := []struct {
+		name        string
+		suppression Suppression
+		expected    bool
+	}{
+		{"exact address", Suppression{RuleID: "EC2001", Address: `module.web["a"].aws_instance.main`}, true},
+		{"wildcard", Suppression{RuleID: "EC2001", Address: "module.web[*].aws_instance.*"}, true},
+		{"every resource", Suppression{RuleID: "EC2001"}, true},
+		{"other rule", Suppression{RuleID: "RDS001"}, false},
+		{"other address", Suppression{RuleID: "EC2001", Address: "module.api*"}, false},
+		{"prefix only", Suppression{RuleID: "EC2001", Address: "module.web"}, false},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.expected, testCase.suppression.matches(finding))
+		})
+	}
+}
+
+func TestLoadSuppressionsRequiresReason(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "suppressions.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"rule_id": "RDS002", "address": "*"}]`), 0o600))
+
+	_, err := LoadSuppressions(path)
+	assert.ErrorContains(t, err, "needs both rule_id and reason")
+}
+
+func TestParseSeverity(t *testing.T) {
+	t.Parallel()
+
+	severity, err := ParseSeverity("high")
+	require.NoError(t, err)
+	assert.Equal(t, SeverityHigh, severity)
+
+	_, err = ParseSeverity("urgent")
+	assert.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package policy
+
+import "fmt"
+
+// SecurityRules returns the built-in rule pack for resources created by the
+// modules in this repository
+func SecurityRules() []Rule {
+	return []Rule{
+		{
+			ID:            "RDS001",
+			Description:   "Database instances must encrypt storage",
+			Severity:      SeverityHigh,
+			ResourceTypes: []string{"aws_db_instance"},
+			Check:         checkDBStorageEncrypted,
+		},
+		{
+			ID:            "RDS002",
+			Description:   "Database instances must not be publicly accessible",
+			Severity:      SeverityCritical,
+			ResourceTypes: []string{"aws_db_instance"},
+			Check:         checkDBNotPublic,
+		},
+		{
+			ID:            "S3001",
+			Description:   "S3 public access blocks must enable all four settings",
+			Severity:      SeverityHigh,
+			ResourceTypes: []string{"aws_s3_bucket_public_access_block"},
+			Check:         checkS3PublicAccessBlock,
+		},
+		{
+			ID:            "EC2001",
+			Description:   "Instances must require IMDSv2 session tokens",
+			Severity:      SeverityMedium,
+			ResourceTypes: []string{"aws_instance"},
+			Check:         checkInstanceIMDSv2,
+		},
+		{
+			ID:            "EKS001",
+			Description:   "Public EKS API endpoints must be restricted to specific CIDRs",
+			Severity:      SeverityHigh,
+			ResourceTypes: []string{"aws_eks_cluster"},
+			Check:         checkEKSPublicEndpoint,
+		},
+		{
+			ID:            "ECA001",
+			Description:   "ElastiCache replication groups must encrypt data in transit",
+			Severity:      SeverityHigh,
+			ResourceTypes: []string{"aws_elasticache_replication_group"},
+			Check:         checkElastiCacheTransitEncryption,
+		},
+	}
+}
+
+// checkDBStorageEncrypted skips read replicas, which inherit encryption from
+// their source and plan storage_encrypted as unknown
+func checkDBStorageEncrypted(resource Resource) []string {
+	if resource.String("replicate_source_db") != "" || resource.IsUnknown("replicate_source_db") {
+		return nil
+	}
+	if resource.IsUnknown("storage_encrypted") || resource.Bool("storage_encrypted") {
+		return nil
+	}
+	return []string{"storage_encrypted is not true"}
+}
+
+func checkDBNotPublic(resource Resource) []string {
+	if resource.Bool("publicly_accessible") {
+		return []string{"publicly_accessible is true"}
+	}
+	return nil
+}
+
+func checkS3PublicAccessBlock(resource Resource) []string {
+	messages := []string{}
+	for _, name := range []string{"block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"} {
+		if !resource.Bool(name) {
+			messages = append(messages, fmt.Sprintf("%s is not true", name))
+		}
+	}
+	return messages
+}
+
+func checkInstanceIMDSv2(resource Resource) []string {
+	metadataOptions := resource.Block("metadata_options")
+	if metadataOptions == nil {
+		return []string{"metadata_options is not set, so IMDSv1 stays enabled"}
+	}
+	if tokens, _ := metadataOptions["http_tokens"].(string); tokens != "required" {
+		return []string{fmt.Sprintf("metadata_options.http_tokens is %q, expected \"required\"", tokens)}
+	}
+	return nil
+}
+
+func checkEKSPublicEndpoint(resource Resource) []string {
+	vpcConfig := resource.Block("vpc_config")
+	if vpcConfig == nil {
+		return nil
+	}
+	if public, _ := vpcConfig["endpoint_public_access"].(bool); !public {
+		return nil
+	}
+
+	cidrs, _ := vpcConfig["public_access_cidrs"].([]interface{})
+	if len(cidrs) == 0 {
+		return []string{"endpoint_public_access is true without public_access_cidrs, which defaults to 0.0.0.0/0"}
+	}
+	for _, cidr := range cidrs {
+		if cidr == "0.0.0.0/0" {
+			return []string{"endpoint_public_access is true and public_access_cidrs contains 0.0.0.0/0"}
+		}
+	}
+	return nil
+}
+
+func checkElastiCacheTransitEncryption(resource Resource) []string {
+	if resource.IsUnknown("transit_encryption_enabled") || resource.Bool("transit_encryption_enabled") {
+		return nil
+	}
+	return []string{"transit_encryption_enabled is not true"}
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleSecurityPolicies(t *testing.T) {
+	t.Parallel()
+
+	name := fmt.Sprintf("test-policy-%d", time.Now().Unix())
+
+	testCases := []struct {
+		name     string
+		module   string
+		vars     map[string]interface{}
+		expected []string
+	}{
+		{"rds defaults", "rds", minimalPlanVars(t, "rds", name, nil), nil},
+		{"rds public and unencrypted", "rds", minimalPlanVars(t, "rds", name, map[string]interface{}{"publicly_accessible": true, "storage_encrypted": false}), []string{"RDS002", "RDS001"}},
+		{"s3 defaults", "s3-bucket", minimalPlanVars(t, "s3-bucket", name, nil), nil},
+		{"s3 public policy allowed", "s3-bucket", minimalPlanVars(t, "s3-bucket", name, map[string]interface{}{"block_public_policy": false}), []string{"S3001"}},
+		{"ec2 defaults", "ec2-instance", minimalPlanVars(t, "ec2-instance", name, nil), nil},
+		{"ec2 imdsv1", "ec2-instance", minimalPlanVars(t, "ec2-instance", name, map[string]interface{}{"require_imdsv2": false}), []string{"EC2001"}},
+		{"eks default public endpoint", "eks", minimalPlanVars(t, "eks", name, nil), []string{"EKS001"}},
+		{"eks restricted public endpoint", "eks", minimalPlanVars(t, "eks", name, map[string]interface{}{"public_access_cidrs": []string{"203.0.113.0/24"}}), nil},
+		{"eks private endpoint", "eks", minimalPlanVars(t, "eks", name, map[string]interface{}{"endpoint_public_access": false}), nil},
+		{"elasticache transit encryption", "elasticache", minimalPlanVars(t, "elasticache", name, nil), nil},
+		{"elasticache plaintext", "elasticache", minimalPlanVars(t, "elasticache", name, map[string]interface{}{"transit_encryption_enabled": false, "auth_token": nil}), []string{"ECA001"}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			region := "us-east-1"
+			plan := planModuleJSON(t, testCase.module, region, testCase.vars)
+			findings := policy.Unsuppressed(policy.Evaluate(plan, policy.SecurityRules(), nil), policy.SeverityLow)
+
+			ruleIDs := []string{}
+			for _, finding := range findings {
+				ruleIDs = append(ruleIDs, finding.RuleID)
+			}
+			if testCase.expected == nil {
+				assert.Empty(t, findings, "unexpected findings: %v", findings)
+			} else {
+				assert.Equal(t, testCase.expected, ruleIDs, "findings: %v", findings)
+			}
+		})
+	}
+}
@@ -2,13 +2,20 @@ package test
 
 import (
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/rds"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRDSInstanceCreation(t *testing.T) {
@@ -152,6 +159,303 @@ func TestRDSEncryption(t *testing.T) {
 	assert.True(t, *dbInstance.StorageEncrypted)
 }
 
+func TestRDSReadReplicasAndMonitoring(t *testing.T) {
+	t.Parallel()
+
+	instanceID := fmt.Sprintf("test-db-replica-%d", time.Now().Unix())
+	replicaCount := 2
+
+	region := "us-east-1"
+	rdsClient := createRDSClient(t, region)
+	ec2Client := createEC2Client(t, region)
+	subnetIDs := getDefaultVPCSubnetIDs(t, ec2Client)
+	_, securityGroupID := getDefaultSubnetAndSecurityGroup(t, ec2Client)
+	monitoringRoleArn := createRDSMonitoringRole(t, region, instanceID)
+
+	parameters := []map[string]string{
+		{"name": "log_min_duration_statement", "value": "500", "apply_method": "immediate"},
+		{"name": "log_connections", "value": "1", "apply_method": "immediate"},
+		{"name": "shared_preload_libraries", "value": "pg_stat_statements", "apply_method": "pending-reboot"},
+	}
+
+	terraformOptions := createModuleOptions(t, "rds", region, map[string]interface{}{
+		"db_identifier":                         instanceID,
+		"engine":                                "postgres",
+		"engine_version":                        "16",
+		"parameter_group_family":                "postgres16",
+		"instance_class":                        "db.t4g.medium",
+		"database_name":                         "testdb",
+		"master_username":                       "dbadmin",
+		"master_password":                       "TestPassword123!",
+		"subnet_ids":                            subnetIDs,
+		"vpc_security_group_ids":                []string{securityGroupID},
+		"db_parameters":                         parameters,
+		"monitoring_interval":                   30,
+		"monitoring_role_arn":                   monitoringRoleArn,
+		"performance_insights_enabled":          true,
+		"performance_insights_retention_period": 7,
+		"create_read_replica":                   true,
+		"read_replica_count":                    replicaCount,
+		"replica_instance_class":                "db.t4g.large",
+		"deletion_protection":                   false,
+		"skip_final_snapshot":                   true,
+		"apply_immediately":                     true,
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	dbInstanceID := terraform.Output(t, terraformOptions, "db_instance_id")
+	primary := getRDSInstance(t, rdsClient, dbInstanceID)
+	assert.Equal(t, "db.t4g.medium", *primary.DBInstanceClass)
+	assert.True(t, aws.BoolValue(primary.PerformanceInsightsEnabled))
+	assert.Equal(t, int64(7), aws.Int64Value(primary.PerformanceInsightsRetentionPeriod))
+	assert.Equal(t, int64(30), aws.Int64Value(primary.MonitoringInterval))
+	assert.Equal(t, monitoringRoleArn, aws.StringValue(primary.MonitoringRoleArn))
+
+	replicaIDs := terraform.OutputList(t, terraformOptions, "read_replica_ids")
+	require.Len(t, replicaIDs, replicaCount)
+	assert.Len(t, primary.ReadReplicaDBInstanceIdentifiers, replicaCount)
+
+	for i, replicaID := range replicaIDs {
+		assert.Equal(t, fmt.Sprintf("%s-replica-%d", instanceID, i+1), replicaID)
+
+		replica := getRDSInstance(t, rdsClient, replicaID)
+		assert.Equal(t, instanceID, aws.StringValue(replica.ReadReplicaSourceDBInstanceIdentifier), "source of %s", replicaID)
+		assert.Equal(t, "db.t4g.large", *replica.DBInstanceClass, "instance class of %s", replicaID)
+		assert.True(t, aws.BoolValue(replica.PerformanceInsightsEnabled), "Performance Insights on %s", replicaID)
+		assert.Equal(t, int64(30), aws.Int64Value(replica.MonitoringInterval), "monitoring interval of %s", replicaID)
+		assert.Equal(t, monitoringRoleArn, aws.StringValue(replica.MonitoringRoleArn), "monitoring role of %s", replicaID)
+	}
+
+	parameterGroupName := terraform.Output(t, terraformOptions, "db_parameter_group_name")
+	require.Len(t, primary.DBParameterGroups, 1)
+	assert.Equal(t, parameterGroupName, aws.StringValue(primary.DBParameterGroups[0].DBParameterGroupName))
+
+	live := getRDSParameters(t, rdsClient, parameterGroupName)
+	for _, parameter := range parameters {
+		actual, ok := live[parameter["name"]]
+		if !assert.True(t, ok, "parameter %s is not set in %s", parameter["name"], parameterGroupName) {
+			continue
+		}
+		assert.Equal(t, parameter["value"], aws.StringValue(actual.ParameterValue), "value of %s", parameter["name"])
+		assert.Equal(t, parameter["apply_method"], aws.StringValue(actual.ApplyMethod), "apply method of %s", parameter["name"])
+	}
+}
+
+func TestRDSOptionGroup(t *testing.T) {
+	t.Parallel()
+
+	instanceID := fmt.Sprintf("test-db-options-%d", time.Now().Unix())
+
+	region := "us-east-1"
+	rdsClient := createRDSClient(t, region)
+	ec2Client := createEC2Client(t, region)
+	subnetIDs := getDefaultVPCSubnetIDs(t, ec2Client)
+	_, securityGroupID := getDefaultSubnetAndSecurityGroup(t, ec2Client)
+
+	terraformOptions := createModuleOptions(t, "rds", region, map[string]interface{}{
+		"db_identifier":          instanceID,
+		"engine":                 "mysql",
+		"engine_version":         "8.0",
+		"major_engine_version":   "8.0",
+		"parameter_group_family": "mysql8.0",
+		"instance_class":         "db.t3.micro",
+		"database_name":          "testdb",
+		"database_port":          3306,
+		"master_username":        "dbadmin",
+		"master_password":        "TestPassword123!",
+		"subnet_ids":             subnetIDs,
+		"vpc_security_group_ids": []string{securityGroupID},
+		"db_options": []map[string]interface{}{
+			{
+				"option_name": "MARIADB_AUDIT_PLUGIN",
+				"option_settings": []map[string]string{
+					{"name": "SERVER_AUDIT_EVENTS", "value": "CONNECT,QUERY_DDL"},
+				},
+			},
+		},
+		"deletion_protection": false,
+		"skip_final_snapshot": true,
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	optionGroupName := terraform.Output(t, terraformOptions, "db_option_group_name")
+	assert.Equal(t, fmt.Sprintf("%s-options", instanceID), optionGroupName)
+
+	dbInstance := getRDSInstance(t, rdsClient, terraform.Output(t, terraformOptions, "db_instance_id"))
+	memberships := []string{}
+	for _, membership := range dbInstance.OptionGroupMemberships {
+		memberships = append(memberships, aws.StringValue(membership.OptionGroupName))
+	}
+	assert.Contains(t, memberships, optionGroupName)
+
+	optionGroup := getRDSOptionGroup(t, rdsClient, optionGroupName)
+	assert.Equal(t, "mysql", aws.StringValue(optionGroup.EngineName))
+	assert.Equal(t, "8.0", aws.StringValue(optionGroup.MajorEngineVersion))
+	require.Len(t, optionGroup.Options, 1)
+
+	option := optionGroup.Options[0]
+	assert.Equal(t, "MARIADB_AUDIT_PLUGIN", aws.StringValue(option.OptionName))
+
+	settings := map[string]string{}
+	for _, setting := range option.OptionSettings {
+		settings[aws.StringValue(setting.Name)] = aws.StringValue(setting.Value)
+	}
+	assert.Equal(t, "CONNECT,QUERY_DDL", settings["SERVER_AUDIT_EVENTS"])
+}
+
+func TestRDSConnectivity(t *testing.T) {
+	t.Parallel()
+
+	// Sessions to instances with a ca_cert_identifier verify the server
+	// certificate against that CA alone, with and without force_ssl
+	testCases := []struct {
+		name             string
+		engine           string
+		engineVersion    string
+		family           string
+		port             int64
+		caCertIdentifier string
+		parameters       []map[string]string
+	}{
+		{
+			name:             "postgres-force-ssl",
+			engine:           "postgres",
+			engineVersion:    "16",
+			family:           "postgres16",
+			port:             5432,
+			caCertIdentifier: "rds-ca-rsa2048-g1",
+			parameters: []map[string]string{
+				{"name": "rds.force_ssl", "value": "1"},
+			},
+		},
+		{
+			name:             "mysql-ca",
+			engine:           "mysql",
+			engineVersion:    "8.0",
+			family:           "mysql8.0",
+			port:             3306,
+			caCertIdentifier: "rds-ca-rsa4096-g1",
+		},
+		{
+			name:          "mysql",
+			engine:        "mysql",
+			engineVersion: "8.0",
+			family:        "mysql8.0",
+			port:          3306,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			instanceID := fmt.Sprintf("test-db-conn-%s-%d", testCase.name, time.Now().Unix())
+			username := "dbadmin"
+			password := "TestPassword123!"
+			databaseName := "testdb"
+
+			region := "us-east-1"
+			rdsClient := createRDSClient(t, region)
+			ec2Client := createEC2Client(t, region)
+			subnetIDs := getDefaultVPCSubnetIDs(t, ec2Client)
+			securityGroupID := createDatabaseAccessSecurityGroup(t, ec2Client, instanceID, testCase.port)
+
+			vars := map[string]interface{}{
+				"db_identifier":          instanceID,
+				"engine":                 testCase.engine,
+				"engine_version":         testCase.engineVersion,
+				"parameter_group_family": testCase.family,
+				"instance_class":         "db.t3.micro",
+				"database_name":          databaseName,
+				"database_port":          testCase.port,
+				"master_username":        username,
+				"master_password":        password,
+				"subnet_ids":             subnetIDs,
+				"vpc_security_group_ids": []string{securityGroupID},
+				"publicly_accessible":    true,
+				"deletion_protection":    false,
+				"skip_final_snapshot":    true,
+			}
+			if testCase.caCertIdentifier != "" {
+				vars["ca_cert_identifier"] = testCase.caCertIdentifier
+			}
+			if testCase.parameters != nil {
+				vars["db_parameters"] = testCase.parameters
+			}
+
+			terraformOptions := createModuleOptions(t, "rds", region, vars)
+
+			defer terraform.Destroy(t, terraformOptions)
+
+			terraform.InitAndApply(t, terraformOptions)
+
+			endpoint := terraform.Output(t, terraformOptions, "db_instance_endpoint")
+			assert.Equal(t, fmt.Sprintf("%d", testCase.port), terraform.Output(t, terraformOptions, "db_instance_port"))
+
+			var caCertificates []byte
+			if testCase.caCertIdentifier != "" {
+				dbInstance := getRDSInstance(t, rdsClient, instanceID)
+				assert.Equal(t, testCase.caCertIdentifier, aws.StringValue(dbInstance.CACertificateIdentifier))
+				caCertificates = getRDSCACertificates(t, region, testCase.caCertIdentifier)
+			}
+
+			checkDatabaseConnection(t, databaseConnection{
+				Engine:         testCase.engine,
+				Endpoint:       endpoint,
+				Username:       username,
+				Password:       password,
+				Database:       databaseName,
+				RequireTLS:     caCertificates != nil,
+				CACertificates: caCertificates,
+			}, 5*time.Minute)
+		})
+	}
+}
+
+// TestRDSConnectionHelperStandIn runs the connectivity helper against local
+// containers standing in for RDS instances, see the README for how to start
+// them
+func TestRDSConnectionHelperStandIn(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		engine          string
+		envVar          string
+		defaultEndpoint string
+		requireTLS      bool
+	}{
+		// The postgres image does not enable TLS out of the box
+		{"postgres", "RDS_STANDIN_POSTGRES_ENDPOINT", "localhost:5432", false},
+		// MySQL 8 generates certificates on first start and offers TLS
+		{"mysql", "RDS_STANDIN_MYSQL_ENDPOINT", "localhost:3306", true},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.engine, func(t *testing.T) {
+			t.Parallel()
+
+			endpoint := getStandInDatabaseEndpoint(t, testCase.envVar, testCase.defaultEndpoint)
+
+			checkDatabaseConnection(t, databaseConnection{
+				Engine:     testCase.engine,
+				Endpoint:   endpoint,
+				Username:   "dbadmin",
+				Password:   "TestPassword123!",
+				Database:   "testdb",
+				RequireTLS: testCase.requireTLS,
+			}, time.Minute)
+		})
+	}
+}
+
 func createRDSClient(t *testing.T, region string) *rds.RDS {
 	sess := createAWSSession(t, region)
 	return rds.New(sess)
@@ -168,3 +472,176 @@ func getRDSInstance(t *testing.T, client *rds.RDS, instanceID string) *rds.DBIns
 
 	return result.DBInstances[0]
 }
+
+// getRDSParameters returns the user-modified parameters of a parameter group
+// keyed by name
+func getRDSParameters(t *testing.T, client *rds.RDS, parameterGroupName string) map[string]*rds.Parameter {
+	input := &rds.DescribeDBParametersInput{
+		DBParameterGroupName: aws.String(parameterGroupName),
+		Source:               aws.String("user"),
+	}
+
+	parameters := make(map[string]*rds.Parameter)
+	err := client.DescribeDBParametersPages(input, func(page *rds.DescribeDBParametersOutput, lastPage bool) bool {
+		for _, parameter := range page.Parameters {
+			parameters[aws.StringValue(parameter.ParameterName)] = parameter
+		}
+		return true
+	})
+	require.NoError(t, err)
+
+	return parameters
+}
+
+func getRDSOptionGroup(t *testing.T, client *rds.RDS, optionGroupName string) *rds.OptionGroup {
+	input := &rds.DescribeOptionGroupsInput{
+		OptionGroupName: aws.String(optionGroupName),
+	}
+
+	result, err := client.DescribeOptionGroups(input)
+	require.NoError(t, err)
+	require.Len(t, result.OptionGroupsList, 1)
+
+	return result.OptionGroupsList[0]
+}
+
+// getDefaultVPCSubnetIDs returns the default subnets of the region's default
+// VPC, which span enough AZs for a DB subnet group
+func getDefaultVPCSubnetIDs(t *testing.T, client *ec2.EC2) []string {
+	input := &ec2.DescribeSubnetsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("default-for-az"),
+				Values: []*string{aws.String("true")},
+			},
+		},
+	}
+
+	result, err := client.DescribeSubnets(input)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(result.Subnets), 2, "default VPC needs subnets in at least two AZs")
+
+	subnetIDs := []string{}
+	for _, subnet := range result.Subnets {
+		subnetIDs = append(subnetIDs, aws.StringValue(subnet.SubnetId))
+	}
+
+	return subnetIDs
+}
+
+// createRDSMonitoringRole creates the IAM role RDS enhanced monitoring assumes
+// to publish OS metrics, and deletes it when the test finishes
+func createRDSMonitoringRole(t *testing.T, region string, name string) string {
+	iamClient := iam.New(createAWSSession(t, region))
+	roleName := fmt.Sprintf("%s-monitoring", name)
+	policyArn := "arn:aws:iam::aws:policy/service-role/AmazonRDSEnhancedMonitoringRole"
+
+	role, err := iamClient.CreateRole(&iam.CreateRoleInput{
+		RoleName: aws.String(roleName),
+		AssumeRolePolicyDocument: aws.String(`{
+  "Version": "2012-10-17",
+  "Statement": [{
+    "Effect": "Allow",
+    "Principal": {"Service": "monitoring.rds.amazonaws.com"},
+    "Action": "sts:AssumeRole"
+  }]
+}`),
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, err := iamClient.DetachRolePolicy(&iam.DetachRolePolicyInput{
+			RoleName:  aws.String(roleName),
+			PolicyArn: aws.String(policyArn),
+		})
+		assert.NoError(t, err)
+
+		_, err = iamClient.DeleteRole(&iam.DeleteRoleInput{RoleName: aws.String(roleName)})
+		assert.NoError(t, err)
+	})
+
+	_, err = iamClient.AttachRolePolicy(&iam.AttachRolePolicyInput{
+		RoleName:  aws.String(roleName),
+		PolicyArn: aws.String(policyArn),
+	})
+	require.NoError(t, err)
+
+	// RDS rejects roles it cannot assume yet, so give IAM time to propagate
+	time.Sleep(15 * time.Second)
+
+	return aws.StringValue(role.Role.Arn)
+}
+
+// createDatabaseAccessSecurityGroup creates a security group in the default VPC
+// that lets this machine reach a database port, and deletes it when the test
+// finishes
+func createDatabaseAccessSecurityGroup(t *testing.T, client *ec2.EC2, name string, port int64) string {
+	vpcs, err := client.DescribeVpcs(&ec2.DescribeVpcsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("is-default"),
+				Values: []*string{aws.String("true")},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, vpcs.Vpcs, "region has no default VPC")
+
+	securityGroup, err := client.CreateSecurityGroup(&ec2.CreateSecurityGroupInput{
+		GroupName:   aws.String(name),
+		Description: aws.String("Terratest database access"),
+		VpcId:       vpcs.Vpcs[0].VpcId,
+	})
+	require.NoError(t, err)
+	groupID := securityGroup.GroupId
+
+	t.Cleanup(func() {
+		// The instance's network interface can linger for a while after
+		// destroy, which keeps the group in use
+		deadline := time.Now().Add(5 * time.Minute)
+		for {
+			_, err := client.DeleteSecurityGroup(&ec2.DeleteSecurityGroupInput{GroupId: groupID})
+			if err == nil || time.Now().After(deadline) {
+				assert.NoError(t, err)
+				return
+			}
+			time.Sleep(15 * time.Second)
+		}
+	})
+
+	_, err = client.AuthorizeSecurityGroupIngress(&ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId: groupID,
+		IpPermissions: []*ec2.IpPermission{
+			{
+				IpProtocol: aws.String("tcp"),
+				FromPort:   aws.Int64(port),
+				ToPort:     aws.Int64(port),
+				IpRanges: []*ec2.IpRange{
+					{
+						CidrIp:      aws.String(getPublicIP(t) + "/32"),
+						Description: aws.String("Terratest runner"),
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	return aws.StringValue(groupID)
+}
+
+// getPublicIP returns the public IPv4 address this machine reaches AWS from
+func getPublicIP(t *testing.T) string {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get("https://checkip.amazonaws.com")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	ip := strings.TrimSpace(string(body))
+	require.NotNil(t, net.ParseIP(ip), "checkip returned %q", ip)
+
+	return ip
+}
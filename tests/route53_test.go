@@ -0,0 +1,316 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// s3WebsiteZoneID is the hosted zone ID of S3 website endpoints in us-east-1
+const s3WebsiteZoneID = "Z3AQBSTGFYJSTF"
+
+func TestRoute53RoutingPolicies(t *testing.T) {
+	t.Parallel()
+
+	zoneName := fmt.Sprintf("test-r53-%d.com", time.Now().Unix())
+	healthCheckName := fmt.Sprintf("test-r53-primary-%d", time.Now().Unix())
+
+	region := "us-east-1"
+	terraformOptions := createModuleOptions(t, "route53", region, map[string]interface{}{
+		"hosted_zones": map[string]interface{}{
+			zoneName: map[string]interface{}{
+				"comment":       "Terratest routing policies",
+				"force_destroy": true,
+			},
+		},
+		"health_checks": map[string]interface{}{
+			healthCheckName: map[string]interface{}{
+				"type":          "HTTPS",
+				"fqdn":          "aws.amazon.com",
+				"port":          443,
+				"resource_path": "/",
+			},
+		},
+		"create_health_check_alarms": true,
+		"records": map[string]interface{}{
+			"www-blue": map[string]interface{}{
+				"zone_name":               zoneName,
+				"name":                    "www",
+				"type":                    "A",
+				"ttl":                     60,
+				"records":                 []string{"198.51.100.10"},
+				"set_identifier":          "blue",
+				"weighted_routing_policy": map[string]interface{}{"weight": 80},
+			},
+			"www-green": map[string]interface{}{
+				"zone_name":               zoneName,
+				"name":                    "www",
+				"type":                    "A",
+				"ttl":                     60,
+				"records":                 []string{"198.51.100.20"},
+				"set_identifier":          "green",
+				"weighted_routing_policy": map[string]interface{}{"weight": 20},
+			},
+			"api-us": map[string]interface{}{
+				"zone_name":              zoneName,
+				"name":                   "api",
+				"type":                   "A",
+				"ttl":                    60,
+				"records":                []string{"198.51.100.30"},
+				"set_identifier":         "us-east-1",
+				"latency_routing_policy": map[string]interface{}{"region": "us-east-1"},
+			},
+			"api-eu": map[string]interface{}{
+				"zone_name":              zoneName,
+				"name":                   "api",
+				"type":                   "A",
+				"ttl":                    60,
+				"records":                []string{"198.51.100.40"},
+				"set_identifier":         "eu-west-1",
+				"latency_routing_policy": map[string]interface{}{"region": "eu-west-1"},
+			},
+			"geo-europe": map[string]interface{}{
+				"zone_name":                  zoneName,
+				"name":                       "geo",
+				"type":                       "A",
+				"ttl":                        60,
+				"records":                    []string{"198.51.100.50"},
+				"set_identifier":             "europe",
+				"geolocation_routing_policy": map[string]interface{}{"continent": "EU"},
+			},
+			"geo-default": map[string]interface{}{
+				"zone_name":                  zoneName,
+				"name":                       "geo",
+				"type":                       "A",
+				"ttl":                        60,
+				"records":                    []string{"198.51.100.60"},
+				"set_identifier":             "default",
+				"geolocation_routing_policy": map[string]interface{}{"country": "*"},
+			},
+			"app-primary": map[string]interface{}{
+				"zone_name":               zoneName,
+				"name":                    "app",
+				"type":                    "A",
+				"ttl":                     60,
+				"records":                 []string{"198.51.100.70"},
+				"set_identifier":          "primary",
+				"health_check_id":         healthCheckName,
+				"failover_routing_policy": map[string]interface{}{"type": "PRIMARY"},
+			},
+			"app-secondary": map[string]interface{}{
+				"zone_name":               zoneName,
+				"name":                    "app",
+				"type":                    "A",
+				"ttl":                     60,
+				"records":                 []string{"198.51.100.80"},
+				"set_identifier":          "secondary",
+				"failover_routing_policy": map[string]interface{}{"type": "SECONDARY"},
+			},
+			"static": map[string]interface{}{
+				"zone_name": zoneName,
+				"name":      "static",
+				"type":      "A",
+				"alias": map[string]interface{}{
+					"name":    "s3-website-us-east-1.amazonaws.com",
+					"zone_id": s3WebsiteZoneID,
+				},
+			},
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	zoneIDs := terraform.OutputMap(t, terraformOptions, "zone_ids")
+	healthCheckIDs := terraform.OutputMap(t, terraformOptions, "health_check_ids")
+	zoneID := zoneIDs[zoneName]
+	require.NotEmpty(t, zoneID)
+
+	route53Client := createRoute53Client(t, region)
+	recordSets := getRoute53RecordSets(t, route53Client, zoneID)
+
+	www := findRoute53RecordSet(t, recordSets, "www."+zoneName, "blue")
+	assert.Equal(t, int64(80), *www.Weight)
+	www = findRoute53RecordSet(t, recordSets, "www."+zoneName, "green")
+	assert.Equal(t, int64(20), *www.Weight)
+
+	api := findRoute53RecordSet(t, recordSets, "api."+zoneName, "us-east-1")
+	assert.Equal(t, "us-east-1", *api.Region)
+	api = findRoute53RecordSet(t, recordSets, "api."+zoneName, "eu-west-1")
+	assert.Equal(t, "eu-west-1", *api.Region)
+
+	geo := findRoute53RecordSet(t, recordSets, "geo."+zoneName, "europe")
+	assert.Equal(t, "EU", *geo.GeoLocation.ContinentCode)
+	geo = findRoute53RecordSet(t, recordSets, "geo."+zoneName, "default")
+	assert.Equal(t, "*", *geo.GeoLocation.CountryCode)
+
+	// health_check_id names a module health check and must resolve to its ID
+	app := findRoute53RecordSet(t, recordSets, "app."+zoneName, "primary")
+	assert.Equal(t, "PRIMARY", *app.Failover)
+	assert.Equal(t, healthCheckIDs[healthCheckName], aws.StringValue(app.HealthCheckId))
+	app = findRoute53RecordSet(t, recordSets, "app."+zoneName, "secondary")
+	assert.Equal(t, "SECONDARY", *app.Failover)
+	assert.Nil(t, app.HealthCheckId)
+
+	static := findRoute53RecordSet(t, recordSets, "static."+zoneName, "")
+	require.NotNil(t, static.AliasTarget)
+	assert.Equal(t, "s3-website-us-east-1.amazonaws.com.", *static.AliasTarget.DNSName)
+	assert.Equal(t, s3WebsiteZoneID, *static.AliasTarget.HostedZoneId)
+	assert.False(t, *static.AliasTarget.EvaluateTargetHealth)
+
+	cloudwatchClient := cloudwatch.New(createAWSSession(t, region))
+	alarm := getCloudWatchAlarm(t, cloudwatchClient, healthCheckName+"-health-check-failed")
+	assert.Equal(t, "AWS/Route53", *alarm.Namespace)
+	assert.Equal(t, "HealthCheckStatus", *alarm.MetricName)
+	require.Len(t, alarm.Dimensions, 1)
+	assert.Equal(t, "HealthCheckId", *alarm.Dimensions[0].Name)
+	assert.Equal(t, healthCheckIDs[healthCheckName], *alarm.Dimensions[0].Value)
+}
+
+func TestRoute53PrivateZoneAssociations(t *testing.T) {
+	t.Parallel()
+
+	zoneName := fmt.Sprintf("test-r53-private-%d.internal", time.Now().Unix())
+
+	region := "us-east-1"
+	ec2Client := createEC2Client(t, region)
+	primaryVpcID := createTestVPC(t, ec2Client, "10.90.0.0/16")
+	defer deleteTestVPC(t, ec2Client, primaryVpcID)
+	associatedVpcID := createTestVPC(t, ec2Client, "10.91.0.0/16")
+	defer deleteTestVPC(t, ec2Client, associatedVpcID)
+
+	// A private zone needs one inline VPC to be created, the second VPC comes
+	// from zone_associations
+	terraformOptions := createModuleOptions(t, "route53", region, map[string]interface{}{
+		"hosted_zones": map[string]interface{}{
+			zoneName: map[string]interface{}{
+				"force_destroy": true,
+				"vpcs": []map[string]interface{}{
+					{"vpc_id": primaryVpcID, "vpc_region": region},
+				},
+			},
+		},
+		"zone_associations": map[string]interface{}{
+			"secondary": map[string]interface{}{
+				"zone_name": zoneName,
+				"vpc_id":    associatedVpcID,
+			},
+		},
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	zoneIDs := terraform.OutputMap(t, terraformOptions, "zone_ids")
+	route53Client := createRoute53Client(t, region)
+
+	zone := getRoute53HostedZone(t, route53Client, zoneIDs[zoneName])
+	assert.True(t, *zone.HostedZone.Config.PrivateZone)
+
+	associatedVpcs := []string{}
+	for _, vpc := range zone.VPCs {
+		assert.Equal(t, region, *vpc.VPCRegion)
+		associatedVpcs = append(associatedVpcs, *vpc.VPCId)
+	}
+	assert.ElementsMatch(t, []string{primaryVpcID, associatedVpcID}, associatedVpcs)
+
+	// The zone ignores the association in its inline vpcs, so it doesn't
+	// flip back and forth between applies
+	assert.Equal(t, 0, terraform.PlanExitCode(t, terraformOptions), "second plan is not empty")
+}
+
+func createRoute53Client(t *testing.T, region string) *route53.Route53 {
+	sess := createAWSSession(t, region)
+	return route53.New(sess)
+}
+
+func getRoute53HostedZone(t *testing.T, client *route53.Route53, zoneID string) *route53.GetHostedZoneOutput {
+	input := &route53.GetHostedZoneInput{
+		Id: aws.String(zoneID),
+	}
+
+	result, err := client.GetHostedZone(input)
+	require.NoError(t, err)
+
+	return result
+}
+
+func getRoute53RecordSets(t *testing.T, client *route53.Route53, zoneID string) []*route53.ResourceRecordSet {
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneID),
+	}
+
+	recordSets := []*route53.ResourceRecordSet{}
+	err := client.ListResourceRecordSetsPages(input, func(page *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+		recordSets = append(recordSets, page.ResourceRecordSets...)
+		return true
+	})
+	require.NoError(t, err)
+
+	return recordSets
+}
+
+// findRoute53RecordSet returns the record set with the given name and set
+// identifier; an empty setIdentifier matches simple routing records
+func findRoute53RecordSet(t *testing.T, recordSets []*route53.ResourceRecordSet, name string, setIdentifier string) *route53.ResourceRecordSet {
+	fqdn := strings.TrimSuffix(name, ".") + "."
+
+	for _, recordSet := range recordSets {
+		if *recordSet.Name == fqdn && aws.StringValue(recordSet.SetIdentifier) == setIdentifier {
+			return recordSet
+		}
+	}
+
+	t.Fatalf("Record set %s with set identifier %q not found", fqdn, setIdentifier)
+	return nil
+}
+
+func getCloudWatchAlarm(t *testing.T, client *cloudwatch.CloudWatch, alarmName string) *cloudwatch.MetricAlarm {
+	input := &cloudwatch.DescribeAlarmsInput{
+		AlarmNames: []*string{aws.String(alarmName)},
+	}
+
+	result, err := client.DescribeAlarms(input)
+	require.NoError(t, err)
+	require.Len(t, result.MetricAlarms, 1)
+
+	return result.MetricAlarms[0]
+}
+
+// createTestVPC creates a bare VPC with DNS support, which private hosted
+// zones require
+func createTestVPC(t *testing.T, client *ec2.EC2, cidr string) string {
+	result, err := client.CreateVpc(&ec2.CreateVpcInput{
+		CidrBlock: aws.String(cidr),
+	})
+	require.NoError(t, err)
+	vpcID := *result.Vpc.VpcId
+
+	for _, attribute := range []*ec2.ModifyVpcAttributeInput{
+		{VpcId: aws.String(vpcID), EnableDnsSupport: &ec2.AttributeBooleanValue{Value: aws.Bool(true)}},
+		{VpcId: aws.String(vpcID), EnableDnsHostnames: &ec2.AttributeBooleanValue{Value: aws.Bool(true)}},
+	} {
+		_, err := client.ModifyVpcAttribute(attribute)
+		require.NoError(t, err)
+	}
+
+	return vpcID
+}
+
+func deleteTestVPC(t *testing.T, client *ec2.EC2, vpcID string) {
+	_, err := client.DeleteVpc(&ec2.DeleteVpcInput{
+		VpcId: aws.String(vpcID),
+	})
+	assert.NoError(t, err)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestS3BucketCreation(t *testing.T) {
@@ -118,9 +119,8 @@ func TestS3BucketLifecyclePolicy(t *testing.T) {
 	region := "us-east-1"
 	s3Client := createS3Client(t, region)
 
-	lifecycle := getBucketLifecycle(t, s3Client, bucketID)
-	assert.NotNil(t, lifecycle)
-	assert.NotEmpty(t, lifecycle.Rules)
+	rules := getBucketLifecycleRules(t, s3Client, bucketID)
+	assert.NotEmpty(t, rules)
 }
 
 func TestS3BucketPublicAccessBlock(t *testing.T) {
@@ -186,6 +186,246 @@ func TestS3BucketTags(t *testing.T) {
 	assert.Equal(t, "DevOps-Team", tags["Owner"])
 }
 
+func TestS3BucketSSEConfiguration(t *testing.T) {
+	t.Parallel()
+
+	region := "us-east-1"
+	keyArn := createKMSKey(t, region, "Terratest S3 bucket encryption")
+
+	testCases := []struct {
+		name           string
+		sseAlgorithm   string
+		kmsMasterKeyID string
+	}{
+		{"aes256", "AES256", ""},
+		{"kms", "aws:kms", keyArn},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			bucketName := fmt.Sprintf("test-bucket-sse-%s-%d", testCase.name, time.Now().Unix())
+
+			vars := map[string]interface{}{
+				"bucket_name":   bucketName,
+				"force_destroy": true,
+				"sse_algorithm": testCase.sseAlgorithm,
+			}
+			if testCase.kmsMasterKeyID != "" {
+				vars["kms_master_key_id"] = testCase.kmsMasterKeyID
+			}
+
+			terraformOptions := createModuleOptions(t, "s3-bucket", region, vars)
+
+			defer terraform.Destroy(t, terraformOptions)
+
+			terraform.InitAndApply(t, terraformOptions)
+
+			bucketID := terraform.Output(t, terraformOptions, "bucket_id")
+			s3Client := createS3Client(t, region)
+
+			encryption := getBucketEncryption(t, s3Client, bucketID)
+			require.NotNil(t, encryption, "bucket %s has no default encryption", bucketID)
+			require.Len(t, encryption.Rules, 1)
+
+			defaults := encryption.Rules[0].ApplyServerSideEncryptionByDefault
+			require.NotNil(t, defaults)
+			assert.Equal(t, testCase.sseAlgorithm, aws.StringValue(defaults.SSEAlgorithm))
+			assert.Equal(t, testCase.kmsMasterKeyID, aws.StringValue(defaults.KMSMasterKeyID))
+
+			// Optional features left unset must read back as not configured,
+			// not as errors
+			assert.Nil(t, getBucketCORSRules(t, s3Client, bucketID))
+			assert.Nil(t, getBucketLifecycleRules(t, s3Client, bucketID))
+			assert.Nil(t, getBucketLogging(t, s3Client, bucketID))
+		})
+	}
+}
+
+func TestS3BucketLifecycleRules(t *testing.T) {
+	t.Parallel()
+
+	bucketName := fmt.Sprintf("test-bucket-lifecycle-rules-%d", time.Now().Unix())
+
+	type transition struct {
+		days         int64
+		storageClass string
+	}
+
+	expected := []struct {
+		id             string
+		enabled        bool
+		prefix         string
+		tags           map[string]string
+		expirationDays int64
+		transitions    []transition
+	}{
+		{
+			id:             "archive-logs",
+			enabled:        true,
+			prefix:         "logs/",
+			expirationDays: 365,
+			transitions:    []transition{{30, "STANDARD_IA"}, {90, "GLACIER"}},
+		},
+		{
+			id:             "expire-temporary",
+			enabled:        true,
+			prefix:         "tmp/",
+			tags:           map[string]string{"temporary": "true"},
+			expirationDays: 7,
+		},
+		{
+			id:          "deep-archive-disabled",
+			enabled:     false,
+			transitions: []transition{{180, "DEEP_ARCHIVE"}},
+		},
+	}
+
+	lifecycleRules := []map[string]interface{}{}
+	for _, rule := range expected {
+		transitions := []map[string]interface{}{}
+		for _, tr := range rule.transitions {
+			transitions = append(transitions, map[string]interface{}{"days": tr.days, "storage_class": tr.storageClass})
+		}
+
+		lifecycleRule := map[string]interface{}{
+			"id":          rule.id,
+			"enabled":     rule.enabled,
+			"transitions": transitions,
+		}
+		if rule.prefix != "" {
+			lifecycleRule["prefix"] = rule.prefix
+		}
+		if rule.tags != nil {
+			lifecycleRule["tags"] = rule.tags
+		}
+		if rule.expirationDays != 0 {
+			lifecycleRule["expiration_days"] = rule.expirationDays
+		}
+		lifecycleRules = append(lifecycleRules, lifecycleRule)
+	}
+
+	region := "us-east-1"
+	terraformOptions := createModuleOptions(t, "s3-bucket", region, map[string]interface{}{
+		"bucket_name":     bucketName,
+		"force_destroy":   true,
+		"lifecycle_rules": lifecycleRules,
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	bucketID := terraform.Output(t, terraformOptions, "bucket_id")
+	s3Client := createS3Client(t, region)
+
+	rules := map[string]*s3.LifecycleRule{}
+	for _, rule := range getBucketLifecycleRules(t, s3Client, bucketID) {
+		rules[aws.StringValue(rule.ID)] = rule
+	}
+	assert.Len(t, rules, len(expected))
+
+	for _, want := range expected {
+		rule, ok := rules[want.id]
+		if !assert.True(t, ok, "lifecycle rule %s is missing", want.id) {
+			continue
+		}
+
+		status := "Disabled"
+		if want.enabled {
+			status = "Enabled"
+		}
+		assert.Equal(t, status, aws.StringValue(rule.Status), "status of %s", want.id)
+
+		prefix, tags := getLifecycleRuleFilter(rule)
+		assert.Equal(t, want.prefix, prefix, "prefix of %s", want.id)
+		if want.tags == nil {
+			assert.Empty(t, tags, "tags of %s", want.id)
+		} else {
+			assert.Equal(t, want.tags, tags, "tags of %s", want.id)
+		}
+
+		if want.expirationDays == 0 {
+			assert.Nil(t, rule.Expiration, "expiration of %s", want.id)
+		} else if assert.NotNil(t, rule.Expiration, "expiration of %s", want.id) {
+			assert.Equal(t, want.expirationDays, aws.Int64Value(rule.Expiration.Days), "expiration days of %s", want.id)
+		}
+
+		transitions := []transition{}
+		for _, tr := range rule.Transitions {
+			transitions = append(transitions, transition{aws.Int64Value(tr.Days), aws.StringValue(tr.StorageClass)})
+		}
+		if want.transitions == nil {
+			want.transitions = []transition{}
+		}
+		assert.ElementsMatch(t, want.transitions, transitions, "transitions of %s", want.id)
+	}
+}
+
+func TestS3BucketCORSAndLogging(t *testing.T) {
+	t.Parallel()
+
+	bucketName := fmt.Sprintf("test-bucket-cors-%d", time.Now().Unix())
+	logBucketName := fmt.Sprintf("test-bucket-access-logs-%d", time.Now().Unix())
+	loggingPrefix := fmt.Sprintf("%s/", bucketName)
+
+	region := "us-east-1"
+	s3Client := createS3Client(t, region)
+	createAccessLogBucket(t, s3Client, logBucketName)
+
+	corsRules := []map[string]interface{}{
+		{
+			"allowed_headers": []string{"*"},
+			"allowed_methods": []string{"GET", "HEAD"},
+			"allowed_origins": []string{"https://www.example.com"},
+			"expose_headers":  []string{"ETag"},
+			"max_age_seconds": 3600,
+		},
+		{
+			"allowed_headers": []string{"Content-Type", "Authorization"},
+			"allowed_methods": []string{"PUT", "POST"},
+			"allowed_origins": []string{"https://app.example.com"},
+		},
+	}
+
+	terraformOptions := createModuleOptions(t, "s3-bucket", region, map[string]interface{}{
+		"bucket_name":    bucketName,
+		"force_destroy":  true,
+		"cors_rules":     corsRules,
+		"logging_bucket": logBucketName,
+		"logging_prefix": loggingPrefix,
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	bucketID := terraform.Output(t, terraformOptions, "bucket_id")
+
+	rules := getBucketCORSRules(t, s3Client, bucketID)
+	require.Len(t, rules, len(corsRules))
+
+	// S3 returns CORS rules in the order they were configured
+	assert.Equal(t, []string{"*"}, aws.StringValueSlice(rules[0].AllowedHeaders))
+	assert.ElementsMatch(t, []string{"GET", "HEAD"}, aws.StringValueSlice(rules[0].AllowedMethods))
+	assert.Equal(t, []string{"https://www.example.com"}, aws.StringValueSlice(rules[0].AllowedOrigins))
+	assert.Equal(t, []string{"ETag"}, aws.StringValueSlice(rules[0].ExposeHeaders))
+	assert.Equal(t, int64(3600), aws.Int64Value(rules[0].MaxAgeSeconds))
+
+	assert.ElementsMatch(t, []string{"Content-Type", "Authorization"}, aws.StringValueSlice(rules[1].AllowedHeaders))
+	assert.ElementsMatch(t, []string{"PUT", "POST"}, aws.StringValueSlice(rules[1].AllowedMethods))
+	assert.Equal(t, []string{"https://app.example.com"}, aws.StringValueSlice(rules[1].AllowedOrigins))
+	assert.Empty(t, rules[1].ExposeHeaders)
+	assert.Equal(t, int64(3000), aws.Int64Value(rules[1].MaxAgeSeconds), "module default max_age_seconds")
+
+	logging := getBucketLogging(t, s3Client, bucketID)
+	require.NotNil(t, logging, "access logging is not enabled on %s", bucketID)
+	assert.Equal(t, logBucketName, aws.StringValue(logging.TargetBucket))
+	assert.Equal(t, loggingPrefix, aws.StringValue(logging.TargetPrefix))
+}
+
 func createS3Client(t *testing.T, region string) *s3.S3 {
 	sess := createAWSSession(t, region)
 	return s3.New(sess)
@@ -220,30 +460,110 @@ func getBucketVersioning(t *testing.T, client *s3.S3, bucketName string) string
 	return *result.Status
 }
 
+// getBucketEncryption returns the default encryption configuration of a
+// bucket, or nil if none is configured
 func getBucketEncryption(t *testing.T, client *s3.S3, bucketName string) *s3.ServerSideEncryptionConfiguration {
+	configuration, err := getBucketEncryptionE(client, bucketName)
+	require.NoError(t, err)
+
+	return configuration
+}
+
+// getBucketEncryptionE returns the default encryption configuration of a
+// bucket, nil if none is configured, or an error if the API call fails
+func getBucketEncryptionE(client *s3.S3, bucketName string) (*s3.ServerSideEncryptionConfiguration, error) {
 	input := &s3.GetBucketEncryptionInput{
 		Bucket: aws.String(bucketName),
 	}
 
 	result, err := client.GetBucketEncryption(input)
+	if isAWSErrorCode(err, "ServerSideEncryptionConfigurationNotFoundError") {
+		return nil, nil
+	}
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	return result.ServerSideEncryptionConfiguration
+	return result.ServerSideEncryptionConfiguration, nil
+}
+
+// getBucketLifecycleRules returns the lifecycle rules of a bucket, or nil if
+// it has no lifecycle configuration
+func getBucketLifecycleRules(t *testing.T, client *s3.S3, bucketName string) []*s3.LifecycleRule {
+	rules, err := getBucketLifecycleRulesE(client, bucketName)
+	require.NoError(t, err)
+
+	return rules
 }
 
-func getBucketLifecycle(t *testing.T, client *s3.S3, bucketName string) *s3.GetBucketLifecycleConfigurationOutput {
+// getBucketLifecycleRulesE returns the lifecycle rules of a bucket, nil if it
+// has no lifecycle configuration, or an error if the API call fails
+func getBucketLifecycleRulesE(client *s3.S3, bucketName string) ([]*s3.LifecycleRule, error) {
 	input := &s3.GetBucketLifecycleConfigurationInput{
 		Bucket: aws.String(bucketName),
 	}
 
 	result, err := client.GetBucketLifecycleConfiguration(input)
+	if isAWSErrorCode(err, "NoSuchLifecycleConfiguration") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Rules, nil
+}
+
+// getBucketCORSRules returns the CORS rules of a bucket, or nil if it has no
+// CORS configuration
+func getBucketCORSRules(t *testing.T, client *s3.S3, bucketName string) []*s3.CORSRule {
+	rules, err := getBucketCORSRulesE(client, bucketName)
+	require.NoError(t, err)
+
+	return rules
+}
+
+// getBucketCORSRulesE returns the CORS rules of a bucket, nil if it has no CORS
+// configuration, or an error if the API call fails
+func getBucketCORSRulesE(client *s3.S3, bucketName string) ([]*s3.CORSRule, error) {
+	input := &s3.GetBucketCorsInput{
+		Bucket: aws.String(bucketName),
+	}
+
+	result, err := client.GetBucketCors(input)
+	if isAWSErrorCode(err, "NoSuchCORSConfiguration") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result.CORSRules, nil
+}
+
+// getBucketLogging returns the access logging settings of a bucket, or nil if
+// access logging is disabled
+func getBucketLogging(t *testing.T, client *s3.S3, bucketName string) *s3.LoggingEnabled {
+	logging, err := getBucketLoggingE(client, bucketName)
+	require.NoError(t, err)
+
+	return logging
+}
+
+// getBucketLoggingE returns the access logging settings of a bucket, nil if
+// access logging is disabled, or an error if the API call fails. S3 reports a
+// disabled configuration as an empty response rather than an error.
+func getBucketLoggingE(client *s3.S3, bucketName string) (*s3.LoggingEnabled, error) {
+	input := &s3.GetBucketLoggingInput{
+		Bucket: aws.String(bucketName),
+	}
+
+	result, err := client.GetBucketLogging(input)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	return result
+	return result.LoggingEnabled, nil
 }
 
 func getBucketPublicAccessBlock(t *testing.T, client *s3.S3, bucketName string) *s3.PublicAccessBlockConfiguration {
@@ -274,3 +594,69 @@ func getBucketTags(t *testing.T, client *s3.S3, bucketName string) map[string]st
 
 	return tags
 }
+
+// getLifecycleRuleFilter returns the prefix and tags a lifecycle rule is
+// filtered on, whether they are set directly or inside an And block
+func getLifecycleRuleFilter(rule *s3.LifecycleRule) (string, map[string]string) {
+	tags := map[string]string{}
+	if rule.Filter == nil {
+		return aws.StringValue(rule.Prefix), tags
+	}
+
+	if rule.Filter.And != nil {
+		for _, tag := range rule.Filter.And.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		return aws.StringValue(rule.Filter.And.Prefix), tags
+	}
+
+	if rule.Filter.Tag != nil {
+		tags[aws.StringValue(rule.Filter.Tag.Key)] = aws.StringValue(rule.Filter.Tag.Value)
+	}
+	return aws.StringValue(rule.Filter.Prefix), tags
+}
+
+// createAccessLogBucket creates a bucket that the S3 logging service may write
+// to, and empties and deletes it when the test finishes
+func createAccessLogBucket(t *testing.T, client *s3.S3, bucketName string) {
+	_, err := client.CreateBucket(&s3.CreateBucketInput{
+		Bucket: aws.String(bucketName),
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		err := client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+			Bucket: aws.String(bucketName),
+		}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, object := range page.Contents {
+				_, err := client.DeleteObject(&s3.DeleteObjectInput{
+					Bucket: aws.String(bucketName),
+					Key:    object.Key,
+				})
+				assert.NoError(t, err)
+			}
+			return true
+		})
+		assert.NoError(t, err)
+
+		_, err = client.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String(bucketName)})
+		assert.NoError(t, err)
+	})
+
+	policy := fmt.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [{
+    "Sid": "S3ServerAccessLogsPolicy",
+    "Effect": "Allow",
+    "Principal": {"Service": "logging.s3.amazonaws.com"},
+    "Action": "s3:PutObject",
+    "Resource": "arn:aws:s3:::%s/*"
+  }]
+}`, bucketName)
+
+	_, err = client.PutBucketPolicy(&s3.PutBucketPolicyInput{
+		Bucket: aws.String(bucketName),
+		Policy: aws.String(policy),
+	})
+	require.NoError(t, err)
+}
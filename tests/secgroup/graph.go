@@ -0,0 +1,340 @@
+// Package secgroup builds a graph of security groups and the rules between
+// them, from a plan or from the EC2 API, and answers which ports are open to
+// the internet and whether one group can reach another
+package secgroup
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Direction is the direction of traffic a rule allows
+type Direction string
+
+const (
+	Ingress Direction = "ingress"
+	Egress  Direction = "egress"
+)
+
+// ProtocolAll is the protocol of rules that allow every protocol and port
+const ProtocolAll = "all"
+
+// worldCIDRs are the CIDR blocks that match every address
+var worldCIDRs = []string{"0.0.0.0/0", "::/0"}
+
+// protocolNames maps IP protocol numbers to the names rules use for them
+var protocolNames = map[string]string{
+	"-1": ProtocolAll,
+	"1":  "icmp",
+	"6":  "tcp",
+	"17": "udp",
+	"58": "icmpv6",
+}
+
+// Rule is one permission of a security group. The peer of the traffic is
+// any of CIDRs, Groups or PrefixLists.
+type Rule struct {
+	// Source names what defines the rule, such as the address of an
+	// aws_security_group_rule
+	Source      string    `json:"source"`
+	Direction   Direction `json:"direction"`
+	Protocol    string    `json:"protocol"`
+	FromPort    int       `json:"from_port"`
+	ToPort      int       `json:"to_port"`
+	CIDRs       []string  `json:"cidrs,omitempty"`
+	Groups      []string  `json:"groups,omitempty"`
+	PrefixLists []string  `json:"prefix_lists,omitempty"`
+	Description string    `json:"description,omitempty"`
+}
+
+// Ports renders the protocol and port range, such as tcp/443 or
+// tcp/1025-65535
+func (r Rule) Ports() string {
+	switch {
+	case r.Protocol == ProtocolAll:
+		return ProtocolAll
+	case r.FromPort == r.ToPort:
+		return fmt.Sprintf("%s/%d", r.Protocol, r.FromPort)
+	default:
+		return fmt.Sprintf("%s/%d-%d", r.Protocol, r.FromPort, r.ToPort)
+	}
+}
+
+// Covers reports whether the rule allows traffic on protocol and port.
+// ICMP rules use the ICMP type as the port, with -1 for every type.
+func (r Rule) Covers(protocol string, port int) bool {
+	if r.Protocol == ProtocolAll {
+		return true
+	}
+	if r.Protocol != NormalizeProtocol(protocol) {
+		return false
+	}
+	if r.FromPort == -1 {
+		return true
+	}
+	return r.FromPort <= port && port <= r.ToPort
+}
+
+// worldOpen reports whether the rule's peers include every address
+func (r Rule) worldOpen() (string, bool) {
+	for _, cidr := range r.CIDRs {
+		for _, world := range worldCIDRs {
+			if cidr == world {
+				return cidr, true
+			}
+		}
+	}
+	return "", false
+}
+
+// NormalizeProtocol returns the name of a protocol given by name or number,
+// with ProtocolAll for -1
+func NormalizeProtocol(protocol string) string {
+	protocol = strings.ToLower(protocol)
+	if name, ok := protocolNames[protocol]; ok {
+		return name
+	}
+	return protocol
+}
+
+// Group is a security group. Groups defined in the plan are keyed by their
+// address, and groups only referenced by ID are keyed by the ID.
+type Group struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+
+	// External groups are referenced but not defined by the plan, so their
+	// rules are unknown unless they were loaded from the EC2 API
+	External bool `json:"external"`
+
+	Rules []Rule `json:"rules"`
+
+	// AttachedTo lists the addresses of the resources using the group,
+	// such as load balancers and instances
+	AttachedTo []string `json:"attached_to,omitempty"`
+}
+
+// RulesFor returns the rules of the group in one direction
+func (g *Group) RulesFor(direction Direction) []Rule {
+	rules := []Rule{}
+	for _, rule := range g.Rules {
+		if rule.Direction == direction {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// Graph is a set of security groups, linked by the rules that name other
+// groups as peers
+type Graph struct {
+	Groups map[string]*Group `json:"groups"`
+}
+
+// NewGraph returns an empty graph
+func NewGraph() *Graph {
+	return &Graph{Groups: map[string]*Group{}}
+}
+
+// Group returns the group with the given key, or nil
+func (g *Graph) Group(id string) *Group {
+	return g.Groups[id]
+}
+
+// GroupIDs returns the keys of every group, sorted
+func (g *Graph) GroupIDs() []string {
+	ids := make([]string, 0, len(g.Groups))
+	for id := range g.Groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ExternalGroupIDs returns the IDs of the groups whose rules are unknown,
+// sorted, so they can be loaded with LoadEC2
+func (g *Graph) ExternalGroupIDs() []string {
+	ids := []string{}
+	for _, id := range g.GroupIDs() {
+		if g.Groups[id].External && strings.HasPrefix(id, "sg-") {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// define returns the group with the given key, adding it as a group whose
+// rules are known
+func (g *Graph) define(id string) *Group {
+	group := g.reference(id)
+	group.External = false
+	return group
+}
+
+// reference returns the group with the given key, adding it as an external
+// group if it is not in the graph yet
+func (g *Graph) reference(id string) *Group {
+	group, ok := g.Groups[id]
+	if !ok {
+		group = &Group{ID: id, External: true, Rules: []Rule{}}
+		g.Groups[id] = group
+	}
+	return group
+}
+
+// AddRule adds a rule to a group, adding the group and the groups the rule
+// names as peers if needed
+func (g *Graph) AddRule(groupID string, rule Rule) {
+	rule.Protocol = NormalizeProtocol(rule.Protocol)
+	if rule.Protocol == ProtocolAll {
+		rule.FromPort, rule.ToPort = -1, -1
+	}
+	for _, peer := range rule.Groups {
+		g.reference(peer)
+	}
+
+	group := g.reference(groupID)
+	group.Rules = append(group.Rules, rule)
+}
+
+// Attach records that a resource uses a group
+func (g *Graph) Attach(groupID string, address string) {
+	group := g.reference(groupID)
+	for _, attached := range group.AttachedTo {
+		if attached == address {
+			return
+		}
+	}
+	group.AttachedTo = append(group.AttachedTo, address)
+	sort.Strings(group.AttachedTo)
+}
+
+// Exposure is an ingress rule that admits traffic from every address
+type Exposure struct {
+	Group string `json:"group"`
+	CIDR  string `json:"cidr"`
+	Rule  Rule   `json:"rule"`
+}
+
+func (e Exposure) String() string {
+	return fmt.Sprintf("%s: %s open to %s (%s)", e.Group, e.Rule.Ports(), e.CIDR, e.Rule.Source)
+}
+
+// Covers reports whether the exposure is limited to the given ports of
+// protocol, so that it can be accepted as intended
+func (e Exposure) Covers(protocol string, ports []int) bool {
+	rule := e.Rule
+	if rule.Protocol != NormalizeProtocol(protocol) || rule.FromPort == -1 {
+		return false
+	}
+	for port := rule.FromPort; port <= rule.ToPort; port++ {
+		if !containsPort(ports, port) {
+			return false
+		}
+	}
+	return true
+}
+
+// WorldOpen returns the ingress rules that admit traffic from 0.0.0.0/0 or
+// ::/0, sorted by group and then by port
+func (g *Graph) WorldOpen() []Exposure {
+	exposures := []Exposure{}
+	for _, id := range g.GroupIDs() {
+		for _, rule := range g.Groups[id].RulesFor(Ingress) {
+			if cidr, ok := rule.worldOpen(); ok {
+				exposures = append(exposures, Exposure{Group: id, CIDR: cidr, Rule: rule})
+			}
+		}
+	}
+
+	sort.SliceStable(exposures, func(i, j int) bool {
+		a, b := exposures[i], exposures[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.Rule.FromPort < b.Rule.FromPort
+	})
+	return exposures
+}
+
+// Path is the pair of rules that lets one group reach another
+type Path struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Egress  Rule   `json:"egress"`
+	Ingress Rule   `json:"ingress"`
+}
+
+func (p Path) String() string {
+	return fmt.Sprintf("%s -> %s: egress %s (%s), ingress %s (%s)", p.From, p.To, p.Egress.Ports(), p.Egress.Source, p.Ingress.Ports(), p.Ingress.Source)
+}
+
+// CanReach reports whether resources in group from can open connections to
+// resources in group to on protocol and port. It needs an egress rule of
+// from that allows the traffic to to, by group or to every address, and an
+// ingress rule of to that admits it from from in the same way. Rules naming
+// other CIDR blocks are ignored, since the graph doesn't know the addresses
+// of a group's members, and external groups that were not loaded can't
+// reach or be reached.
+func (g *Graph) CanReach(from string, to string, protocol string, port int) (Path, bool) {
+	source, target := g.Groups[from], g.Groups[to]
+	if source == nil || target == nil {
+		return Path{}, false
+	}
+
+	egress, ok := findRule(source.RulesFor(Egress), to, protocol, port)
+	if !ok {
+		return Path{}, false
+	}
+	ingress, ok := findRule(target.RulesFor(Ingress), from, protocol, port)
+	if !ok {
+		return Path{}, false
+	}
+	return Path{From: from, To: to, Egress: egress, Ingress: ingress}, true
+}
+
+// findRule returns the first rule that covers the traffic and names peer or
+// every address
+func findRule(rules []Rule, peer string, protocol string, port int) (Rule, bool) {
+	for _, rule := range rules {
+		if !rule.Covers(protocol, port) {
+			continue
+		}
+		if _, ok := rule.worldOpen(); ok {
+			return rule, true
+		}
+		for _, group := range rule.Groups {
+			if group == peer {
+				return rule, true
+			}
+		}
+	}
+	return Rule{}, false
+}
+
+// ParsePorts parses a comma-separated list of ports, such as 80,443
+func ParsePorts(value string) ([]int, error) {
+	ports := []int{}
+	if value == "" {
+		return ports, nil
+	}
+	for _, field := range strings.Split(value, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || port < 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", field)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+func containsPort(ports []int, port int) bool {
+	for _, candidate := range ports {
+		if candidate == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package secgroup
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// FromEC2 builds the graph of the given security groups as the EC2 API
+// describes them. Groups they name as peers are added as external groups.
+func FromEC2(client ec2iface.EC2API, groupIDs []string) (*Graph, error) {
+	graph := NewGraph()
+	if err := graph.LoadEC2(client, groupIDs); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+// LoadEC2 describes the given security groups and replaces what the graph
+// knows about them with their live rules. Use it with ExternalGroupIDs to
+// fill in the groups a plan only references by ID.
+func (g *Graph) LoadEC2(client ec2iface.EC2API, groupIDs []string) error {
+	if len(groupIDs) == 0 {
+		return nil
+	}
+
+	input := &ec2.DescribeSecurityGroupsInput{GroupIds: aws.StringSlice(groupIDs)}
+	var groups []*ec2.SecurityGroup
+	err := client.DescribeSecurityGroupsPages(input, func(page *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+		groups = append(groups, page.SecurityGroups...)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("describing security groups: %w", err)
+	}
+
+	for _, securityGroup := range groups {
+		id := aws.StringValue(securityGroup.GroupId)
+		group := g.define(id)
+		group.Name = aws.StringValue(securityGroup.GroupName)
+		group.Rules = []Rule{}
+
+		for i, permission := range securityGroup.IpPermissions {
+			g.AddRule(id, permissionRule(id, Ingress, i, permission))
+		}
+		for i, permission := range securityGroup.IpPermissionsEgress {
+			g.AddRule(id, permissionRule(id, Egress, i, permission))
+		}
+	}
+	return nil
+}
+
+// permissionRule converts an IP permission of a described security group
+func permissionRule(groupID string, direction Direction, index int, permission *ec2.IpPermission) Rule {
+	rule := Rule{
+		Source:    fmt.Sprintf("%s.%s[%d]", groupID, direction, index),
+		Direction: direction,
+		Protocol:  aws.StringValue(permission.IpProtocol),
+		FromPort:  int(aws.Int64Value(permission.FromPort)),
+		ToPort:    int(aws.Int64Value(permission.ToPort)),
+	}
+
+	for _, ipRange := range permission.IpRanges {
+		rule.CIDRs = append(rule.CIDRs, aws.StringValue(ipRange.CidrIp))
+		if rule.Description == "" {
+			rule.Description = aws.StringValue(ipRange.Description)
+		}
+	}
+	for _, ipRange := range permission.Ipv6Ranges {
+		rule.CIDRs = append(rule.CIDRs, aws.StringValue(ipRange.CidrIpv6))
+	}
+	for _, pair := range permission.UserIdGroupPairs {
+		rule.Groups = append(rule.Groups, aws.StringValue(pair.GroupId))
+		if rule.Description == "" {
+			rule.Description = aws.StringValue(pair.Description)
+		}
+	}
+	for _, prefixList := range permission.PrefixListIds {
+		rule.PrefixLists = append(rule.PrefixLists, aws.StringValue(prefixList.PrefixListId))
+	}
+	return rule
+}
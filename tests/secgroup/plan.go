@@ -0,0 +1,218 @@
+package secgroup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jaaparjazzery/aws-terraform-tests/policy"
+)
+
+// attachmentAttributes lists the attributes through which each resource type
+// uses security groups. Nested blocks are separated by dots.
+var attachmentAttributes = map[string][]string{
+	"aws_db_instance":                   {"vpc_security_group_ids"},
+	"aws_eks_cluster":                   {"vpc_config.security_group_ids"},
+	"aws_elasticache_replication_group": {"security_group_ids"},
+	"aws_instance":                      {"vpc_security_group_ids", "security_groups"},
+	"aws_lambda_function":               {"vpc_config.security_group_ids"},
+	"aws_launch_template":               {"vpc_security_group_ids", "network_interfaces.security_groups"},
+	"aws_lb":                            {"security_groups"},
+	"aws_network_interface":             {"security_groups"},
+}
+
+// FromPlan builds the graph of the security groups in a plan. Group IDs that
+// are only known after apply are resolved through the configuration's
+// references, so a rule whose security_group_id is aws_security_group.node.id
+// belongs to the group at that address. Inline rules of aws_security_group
+// that are only known after apply are skipped.
+func FromPlan(plan *policy.Plan) *Graph {
+	graph := NewGraph()
+
+	groupAddresses := []string{}
+	for _, resource := range plan.Resources {
+		if resource.Type == "aws_security_group" {
+			groupAddresses = append(groupAddresses, resource.Address)
+			group := graph.define(resource.Address)
+			group.Name = resource.String("name")
+		}
+	}
+	resolver := groupResolver{addresses: groupAddresses}
+
+	for _, resource := range plan.Resources {
+		switch resource.Type {
+		case "aws_security_group":
+			addInlineRules(graph, resource, Ingress)
+			addInlineRules(graph, resource, Egress)
+		case "aws_security_group_rule":
+			addSecurityGroupRule(graph, resolver, resource)
+		case "aws_vpc_security_group_ingress_rule":
+			addVPCSecurityGroupRule(graph, resolver, resource, Ingress)
+		case "aws_vpc_security_group_egress_rule":
+			addVPCSecurityGroupRule(graph, resolver, resource, Egress)
+		}
+
+		for _, attribute := range attachmentAttributes[resource.Type] {
+			for _, group := range resolver.groups(resource, attribute) {
+				graph.Attach(group, resource.Address)
+			}
+		}
+	}
+	return graph
+}
+
+func addInlineRules(graph *Graph, resource policy.Resource, direction Direction) {
+	blocks, _ := resource.Values[string(direction)].([]interface{})
+	for i, block := range blocks {
+		values, ok := block.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		rule := Rule{
+			Source:      fmt.Sprintf("%s.%s[%d]", resource.Address, direction, i),
+			Direction:   direction,
+			Protocol:    stringValue(values["protocol"]),
+			FromPort:    intValue(values["from_port"]),
+			ToPort:      intValue(values["to_port"]),
+			CIDRs:       append(stringList(values["cidr_blocks"]), stringList(values["ipv6_cidr_blocks"])...),
+			Groups:      stringList(values["security_groups"]),
+			PrefixLists: stringList(values["prefix_list_ids"]),
+			Description: stringValue(values["description"]),
+		}
+		if self, _ := values["self"].(bool); self {
+			rule.Groups = append(rule.Groups, resource.Address)
+		}
+		graph.AddRule(resource.Address, rule)
+	}
+}
+
+func addSecurityGroupRule(graph *Graph, resolver groupResolver, resource policy.Resource) {
+	direction := Direction(resource.String("type"))
+	rule := Rule{
+		Source:      resource.Address,
+		Direction:   direction,
+		Protocol:    resource.String("protocol"),
+		FromPort:    intValue(resource.Values["from_port"]),
+		ToPort:      intValue(resource.Values["to_port"]),
+		CIDRs:       append(stringList(resource.Values["cidr_blocks"]), stringList(resource.Values["ipv6_cidr_blocks"])...),
+		Groups:      resolver.groups(resource, "source_security_group_id"),
+		PrefixLists: stringList(resource.Values["prefix_list_ids"]),
+		Description: resource.String("description"),
+	}
+
+	for _, group := range resolver.groups(resource, "security_group_id") {
+		groupRule := rule
+		if resource.Bool("self") {
+			groupRule.Groups = append(append([]string{}, rule.Groups...), group)
+		}
+		graph.AddRule(group, groupRule)
+	}
+}
+
+func addVPCSecurityGroupRule(graph *Graph, resolver groupResolver, resource policy.Resource, direction Direction) {
+	rule := Rule{
+		Source:      resource.Address,
+		Direction:   direction,
+		Protocol:    resource.String("ip_protocol"),
+		FromPort:    intValue(resource.Values["from_port"]),
+		ToPort:      intValue(resource.Values["to_port"]),
+		Groups:      resolver.groups(resource, "referenced_security_group_id"),
+		Description: resource.String("description"),
+	}
+	for _, name := range []string{"cidr_ipv4", "cidr_ipv6"} {
+		if cidr := resource.String(name); cidr != "" {
+			rule.CIDRs = append(rule.CIDRs, cidr)
+		}
+	}
+	if prefixList := resource.String("prefix_list_id"); prefixList != "" {
+		rule.PrefixLists = []string{prefixList}
+	}
+
+	for _, group := range resolver.groups(resource, "security_group_id") {
+		graph.AddRule(group, rule)
+	}
+}
+
+// groupResolver finds the groups an attribute names, from its value when it
+// holds literal group IDs and from the configuration's references otherwise
+type groupResolver struct {
+	addresses []string
+}
+
+// groups returns the keys of the groups an attribute names. A reference to a
+// group with count or for_each resolves to every instance of it.
+func (r groupResolver) groups(resource policy.Resource, attribute string) []string {
+	groups := []string{}
+	add := func(group string) {
+		for _, existing := range groups {
+			if existing == group {
+				return
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	for _, value := range lookupStrings(resource.Values, strings.Split(attribute, ".")) {
+		if strings.HasPrefix(value, "sg-") {
+			add(value)
+		}
+	}
+
+	for _, reference := range resource.References[attribute] {
+		for _, address := range r.addresses {
+			if address == reference || strings.HasPrefix(address, reference+"[") {
+				add(address)
+			}
+		}
+	}
+
+	sort.Strings(groups)
+	return groups
+}
+
+// lookupStrings returns the strings at path, following nested blocks, which
+// the plan renders as lists of objects
+func lookupStrings(values map[string]interface{}, path []string) []string {
+	value, ok := values[path[0]]
+	if !ok {
+		return nil
+	}
+	if len(path) == 1 {
+		if text, ok := value.(string); ok {
+			return []string{text}
+		}
+		return stringList(value)
+	}
+
+	found := []string{}
+	blocks, _ := value.([]interface{})
+	for _, block := range blocks {
+		if nested, ok := block.(map[string]interface{}); ok {
+			found = append(found, lookupStrings(nested, path[1:])...)
+		}
+	}
+	return found
+}
+
+func stringValue(value interface{}) string {
+	text, _ := value.(string)
+	return text
+}
+
+func stringList(value interface{}) []string {
+	elements, _ := value.([]interface{})
+	values := []string{}
+	for _, element := range elements {
+		if text, ok := element.(string); ok {
+			values = append(values, text)
+		}
+	}
+	return values
+}
+
+// intValue returns a number from the plan, which encodes numbers as float64
+func intValue(value interface{}) int {
+	number, _ := value.(float64)
+	return int(number)
+}
@@ -0,0 +1,183 @@
+package secgroup
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/jaaparjazzery/aws-terraform-tests/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromPlanResolvesGroupsThroughReferences(t *testing.T) {
+	t.Parallel()
+
+	graph := loadGraph(t)
+
+	assert.Equal(t, []string{
+		"aws_security_group.alb",
+		"module.eks.aws_security_group.cluster",
+		"module.eks.aws_security_group.node",
+		"sg-0a1b2c3d4e5f60009",
+	}, graph.GroupIDs())
+	assert.Equal(t, []string{"sg-0a1b2c3d4e5f60009"}, graph.ExternalGroupIDs())
+
+	assert.Equal(t, []string{"aws_lb.web"}, graph.Group("aws_security_group.alb").AttachedTo)
+	assert.Equal(t, []string{"module.eks.aws_eks_cluster.main"}, graph.Group("module.eks.aws_security_group.cluster").AttachedTo)
+	assert.Equal(t, []string{"aws_instance.app"}, graph.Group("sg-0a1b2c3d4e5f60009").AttachedTo)
+
+	node := graph.Group("module.eks.aws_security_group.node")
+	assert.Equal(t, "orders-eks-node-sg", node.Name)
+	assert.False(t, node.External)
+	require.Len(t, node.RulesFor(Ingress), 2)
+	self := node.RulesFor(Ingress)[1]
+	assert.Equal(t, "module.eks.aws_security_group_rule.node_ingress_self", self.Source)
+	assert.Equal(t, []string{"module.eks.aws_security_group.node"}, self.Groups)
+	assert.Equal(t, ProtocolAll, self.Ports())
+}
+
+func TestWorldOpen(t *testing.T) {
+	t.Parallel()
+
+	exposures := []string{}
+	for _, exposure := range loadGraph(t).WorldOpen() {
+		exposures = append(exposures, exposure.String())
+	}
+	assert.Equal(t, []string{
+		"aws_security_group.alb: tcp/80 open to 0.0.0.0/0 (aws_security_group.alb.ingress[1])",
+		"aws_security_group.alb: tcp/443 open to 0.0.0.0/0 (aws_security_group.alb.ingress[0])",
+		"sg-0a1b2c3d4e5f60009: tcp/22 open to 0.0.0.0/0 (aws_vpc_security_group_ingress_rule.ssh)",
+	}, exposures)
+}
+
+func TestCanReach(t *testing.T) {
+	t.Parallel()
+
+	graph := loadGraph(t)
+	cluster := "module.eks.aws_security_group.cluster"
+	node := "module.eks.aws_security_group.node"
+
+	testCases := []struct {
+		name     string
+		from     string
+		to       string
+		protocol string
+		port     int
+		expected bool
+	}{
+		{"node to cluster API", node, cluster, "tcp", 443, true},
+		{"node to cluster on another port", node, cluster, "tcp", 22, false},
+		{"cluster to kubelet", cluster, node, "tcp", 10250, true},
+		{"cluster to node below 1025", cluster, node, "tcp", 443, false},
+		{"cluster to node over udp", cluster, node, "udp", 10250, false},
+		{"node to node over udp", node, node, "17", 53, true},
+		{"load balancer to app", "aws_security_group.alb", "sg-0a1b2c3d4e5f60009", "tcp", 8080, true},
+		{"external group has no known egress", "sg-0a1b2c3d4e5f60009", "aws_security_group.alb", "tcp", 443, false},
+		{"unknown group", "sg-0000000000000000", cluster, "tcp", 443, false},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			path, ok := graph.CanReach(testCase.from, testCase.to, testCase.protocol, testCase.port)
+			assert.Equal(t, testCase.expected, ok, path.String())
+		})
+	}
+
+	path, ok := graph.CanReach(node, cluster, "tcp", 443)
+	require.True(t, ok)
+	assert.Equal(t, "module.eks.aws_security_group.node -> module.eks.aws_security_group.cluster: egress all (module.eks.aws_security_group_rule.node_egress), ingress tcp/443 (module.eks.aws_security_group_rule.cluster_ingress_node_https)", path.String())
+}
+
+func TestExposureCovers(t *testing.T) {
+	t.Parallel()
+
+	https := Exposure{Rule: Rule{Protocol: "tcp", FromPort: 443, ToPort: 443}}
+	web := Exposure{Rule: Rule{Protocol: "tcp", FromPort: 80, ToPort: 443}}
+	all := Exposure{Rule: Rule{Protocol: ProtocolAll, FromPort: -1, ToPort: -1}}
+
+	assert.True(t, https.Covers("tcp", []int{80, 443}))
+	assert.False(t, https.Covers("udp", []int{443}))
+	assert.False(t, web.Covers("tcp", []int{80, 443}))
+	assert.False(t, all.Covers("tcp", []int{80, 443}))
+}
+
+func TestParsePorts(t *testing.T) {
+	t.Parallel()
+
+	ports, err := ParsePorts("80, 443")
+	require.NoError(t, err)
+	assert.Equal(t, []int{80, 443}, ports)
+
+	_, err = ParsePorts("https")
+	assert.Error(t, err)
+	_, err = ParsePorts("70000")
+	assert.Error(t, err)
+}
+
+func TestLoadEC2ReplacesExternalGroups(t *testing.T) {
+	t.Parallel()
+
+	graph := loadGraph(t)
+	client := &fakeEC2{groups: []*ec2.SecurityGroup{{
+		GroupId:   aws.String("sg-0a1b2c3d4e5f60009"),
+		GroupName: aws.String("orders-app"),
+		IpPermissions: []*ec2.IpPermission{{
+			IpProtocol: aws.String("tcp"),
+			FromPort:   aws.Int64(8080),
+			ToPort:     aws.Int64(8080),
+			UserIdGroupPairs: []*ec2.UserIdGroupPair{
+				{GroupId: aws.String("sg-0a1b2c3d4e5f60010"), Description: aws.String("From the load balancer")},
+			},
+		}},
+		IpPermissionsEgress: []*ec2.IpPermission{{
+			IpProtocol: aws.String("-1"),
+			IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+		}},
+	}}}
+
+	require.NoError(t, graph.LoadEC2(client, graph.ExternalGroupIDs()))
+	assert.Equal(t, [][]string{{"sg-0a1b2c3d4e5f60009"}}, client.requested)
+
+	app := graph.Group("sg-0a1b2c3d4e5f60009")
+	assert.False(t, app.External)
+	assert.Equal(t, "orders-app", app.Name)
+	assert.Equal(t, []Rule{
+		{Source: "sg-0a1b2c3d4e5f60009.ingress[0]", Direction: Ingress, Protocol: "tcp", FromPort: 8080, ToPort: 8080, Groups: []string{"sg-0a1b2c3d4e5f60010"}, Description: "From the load balancer"},
+		{Source: "sg-0a1b2c3d4e5f60009.egress[0]", Direction: Egress, Protocol: ProtocolAll, FromPort: -1, ToPort: -1, CIDRs: []string{"0.0.0.0/0"}},
+	}, app.Rules)
+	assert.Equal(t, []string{"aws_instance.app"}, app.AttachedTo, "attachments from the plan are kept")
+
+	// The live rules replace the planned ones, so the world-open SSH rule
+	// and the rule from the load balancer group are gone
+	assert.Len(t, graph.WorldOpen(), 2)
+	_, ok := graph.CanReach("aws_security_group.alb", "sg-0a1b2c3d4e5f60009", "tcp", 8080)
+	assert.False(t, ok)
+	_, ok = graph.CanReach("sg-0a1b2c3d4e5f60009", "aws_security_group.alb", "tcp", 443)
+	assert.True(t, ok, "the live egress rule reaches the world-open listener")
+}
+
+// loadGraph builds the graph of testdata/plan.json
+func loadGraph(t *testing.T) *Graph {
+	plan, err := policy.LoadPlan(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+	return FromPlan(plan)
+}
+
+// fakeEC2 serves DescribeSecurityGroupsPages from a fixed list of groups
+type fakeEC2 struct {
+	ec2iface.EC2API
+	groups    []*ec2.SecurityGroup
+	requested [][]string
+}
+
+func (f *fakeEC2) DescribeSecurityGroupsPages(input *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool) error {
+	f.requested = append(f.requested, aws.StringValueSlice(input.GroupIds))
+	fn(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: f.groups}, true)
+	return nil
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/jaaparjazzery/aws-terraform-tests/secgroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eksSecurityGroupPaths are the connections the eks module's security groups
+// are meant to allow, and a few they must not
+var eksSecurityGroupPaths = []struct {
+	from     string
+	to       string
+	protocol string
+	port     int
+	expected bool
+}{
+	{"node", "cluster", "tcp", 443, true},
+	{"cluster", "node", "tcp", 10250, true},
+	{"cluster", "node", "tcp", 1025, true},
+	{"cluster", "node", "tcp", 65535, true},
+	{"node", "node", "tcp", 10250, true},
+	{"node", "node", "udp", 53, true},
+	{"node", "cluster", "tcp", 22, false},
+	{"cluster", "node", "tcp", 443, false},
+	{"cluster", "node", "tcp", 22, false},
+	{"cluster", "node", "udp", 53, false},
+}
+
+func TestEKSSecurityGroupExposure(t *testing.T) {
+	t.Parallel()
+
+	clusterName := fmt.Sprintf("test-eks-sg-%d", time.Now().Unix())
+	region := "us-east-1"
+
+	graph := secgroup.FromPlan(planModuleJSON(t, "eks", region, minimalPlanVars(t, "eks", clusterName, nil)))
+
+	groups := map[string]string{
+		"cluster": "aws_security_group.cluster",
+		"node":    "aws_security_group.node",
+	}
+	assert.Equal(t, []string{groups["cluster"], groups["node"]}, graph.GroupIDs())
+	assert.Equal(t, []string{"aws_eks_cluster.main"}, graph.Group(groups["cluster"]).AttachedTo)
+	assert.Empty(t, graph.WorldOpen(), "no security group rule of the eks module admits the internet")
+
+	for _, path := range eksSecurityGroupPaths {
+		_, ok := graph.CanReach(groups[path.from], groups[path.to], path.protocol, path.port)
+		assert.Equal(t, path.expected, ok, "%s -> %s on %s/%d", path.from, path.to, path.protocol, path.port)
+	}
+}
+
+func TestEKSLiveSecurityGroups(t *testing.T) {
+	t.Parallel()
+
+	clusterName := fmt.Sprintf("test-eks-livesg-%d", time.Now().Unix())
+	region := "us-east-1"
+	ec2Client := createEC2Client(t, region)
+
+	networkOptions := createEKSTestNetwork(t, clusterName, region, false)
+	defer terraform.Destroy(t, networkOptions)
+	terraform.InitAndApply(t, networkOptions)
+
+	terraformOptions := createModuleOptions(t, "eks", region, minimalPlanVars(t, "eks", clusterName, map[string]interface{}{
+		"cluster_version":            eksTestClusterVersion,
+		"vpc_id":                     terraform.Output(t, networkOptions, "vpc_id"),
+		"subnet_ids":                 terraform.OutputList(t, networkOptions, "private_subnet_ids"),
+		"cluster_encryption_key_arn": createKMSKey(t, region, fmt.Sprintf("Secrets encryption for %s", clusterName)),
+	}))
+	defer terraform.Destroy(t, terraformOptions)
+	terraform.InitAndApply(t, terraformOptions)
+
+	// The live security groups allow the same paths as the plan
+	groups := map[string]string{
+		"cluster": terraform.Output(t, terraformOptions, "cluster_security_group_id"),
+		"node":    terraform.Output(t, terraformOptions, "node_security_group_id"),
+	}
+	graph, err := secgroup.FromEC2(ec2Client, []string{groups["cluster"], groups["node"]})
+	require.NoError(t, err)
+	assert.Empty(t, graph.WorldOpen())
+
+	for _, path := range eksSecurityGroupPaths {
+		_, ok := graph.CanReach(groups[path.from], groups[path.to], path.protocol, path.port)
+		assert.Equal(t, path.expected, ok, "%s -> %s on %s/%d", path.from, path.to, path.protocol, path.port)
+	}
+}
+
+func TestModuleSecurityGroupAttachments(t *testing.T) {
+	t.Parallel()
+
+	name := fmt.Sprintf("test-sg-%d", time.Now().Unix())
+	securityGroupIDs := []string{"sg-0a1b2c3d4e5f60001", "sg-0a1b2c3d4e5f60002"}
+
+	testCases := []struct {
+		name     string
+		module   string
+		vars     map[string]interface{}
+		attached string
+	}{
+		{"alb", "alb", map[string]interface{}{
+			"alb_name":           name,
+			"subnet_ids":         planSubnetIDs,
+			"security_group_ids": securityGroupIDs,
+			"vpc_id":             "vpc-0a1b2c3d4e5f60001",
+			"target_groups":      map[string]interface{}{},
+		}, "aws_lb.main"},
+		{"ec2-instance", "ec2-instance", minimalPlanVars(t, "ec2-instance", name, map[string]interface{}{
+			"security_group_ids": securityGroupIDs,
+		}), "aws_instance.main[0]"},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			region := "us-east-1"
+			graph := secgroup.FromPlan(planModuleJSON(t, testCase.module, region, testCase.vars))
+
+			// The module only attaches the groups it is given, so their rules
+			// stay with the caller
+			assert.Equal(t, securityGroupIDs, graph.ExternalGroupIDs())
+			for _, id := range securityGroupIDs {
+				assert.Equal(t, []string{testCase.attached}, graph.Group(id).AttachedTo)
+			}
+			assert.Empty(t, graph.WorldOpen())
+		})
+	}
+}
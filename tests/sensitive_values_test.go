@@ -0,0 +1,117 @@
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/jaaparjazzery/aws-terraform-tests/leakcheck"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSecretVariablesAreSensitive checks that every module and example
+// variable that looks like a secret is declared sensitive = true
+func TestSecretVariablesAreSensitive(t *testing.T) {
+	t.Parallel()
+
+	for _, root := range []string{"../modules", "../examples"} {
+		modules, err := tfmodule.LoadAll(root)
+		require.NoError(t, err)
+		require.NotEmpty(t, modules)
+
+		for name, module := range modules {
+			module := module
+			t.Run(filepath.Base(root)+"/"+name, func(t *testing.T) {
+				t.Parallel()
+
+				for _, diagnostic := range leakcheck.CheckDeclarations(module) {
+					t.Error(diagnostic.String())
+				}
+			})
+		}
+	}
+}
+
+// TestModuleSecretsDoNotLeak plans the modules that take secrets and checks
+// that the secrets stay out of outputs, tags, resource names and the test log
+func TestModuleSecretsDoNotLeak(t *testing.T) {
+	t.Parallel()
+
+	name := fmt.Sprintf("test-leak-%d", time.Now().Unix())
+	testCases := []struct {
+		name   string
+		module string
+		vars   map[string]interface{}
+	}{
+		{"rds master password", "rds", minimalPlanVars(t, "rds", name, nil)},
+		{"elasticache auth token", "elasticache", minimalPlanVars(t, "elasticache", name, nil)},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			terraformOptions := createModulePlanOptions(t, testCase.module, "us-east-1", testCase.vars)
+			capture := captureTerratestLog(terraformOptions)
+			planJSON := terraform.InitAndPlanAndShow(t, terraformOptions)
+
+			secrets, err := leakcheck.PlanSecrets([]byte(planJSON))
+			require.NoError(t, err)
+			require.NotEmpty(t, secrets, "plan has no secret variables to trace")
+
+			assertSecretsDoNotLeak(t, planJSON, capture.String(), secrets)
+		})
+	}
+}
+
+// TestRDSSecretsDoNotLeakAfterApply applies the rds module and checks that
+// the master password stays out of the state's outputs, tags and names and
+// out of everything terratest logged while applying
+func TestRDSSecretsDoNotLeakAfterApply(t *testing.T) {
+	t.Parallel()
+
+	region := "us-east-1"
+	ec2Client := createEC2Client(t, region)
+	_, securityGroupID := getDefaultSubnetAndSecurityGroup(t, ec2Client)
+
+	vars := minimalPlanVars(t, "rds", fmt.Sprintf("test-db-leak-%d", time.Now().Unix()), map[string]interface{}{
+		"instance_class":         "db.t4g.micro",
+		"subnet_ids":             getDefaultVPCSubnetIDs(t, ec2Client),
+		"vpc_security_group_ids": []string{securityGroupID},
+		"deletion_protection":    false,
+		"skip_final_snapshot":    true,
+		"apply_immediately":      true,
+	})
+	terraformOptions := createModuleOptions(t, "rds", region, vars)
+	capture := captureTerratestLog(terraformOptions)
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	state := terraform.Show(t, terraformOptions)
+	assertSecretsDoNotLeak(t, state, capture.String(), moduleSecrets(t, "rds", vars))
+}
+
+// moduleSecrets returns the values vars passes for the secret variables of
+// modules/<moduleName>
+func moduleSecrets(t *testing.T, moduleName string, vars map[string]interface{}) []leakcheck.Secret {
+	module, err := tfmodule.Load(filepath.Join("..", "modules", moduleName))
+	require.NoError(t, err)
+	return leakcheck.SecretsFromVars(module, vars)
+}
+
+// assertSecretsDoNotLeak fails the test for every secret found in a plan or
+// state from terraform show -json, or in a captured terratest log
+func assertSecretsDoNotLeak(t *testing.T, document string, log string, secrets []leakcheck.Secret) {
+	leaks, err := leakcheck.ScanJSON([]byte(document), secrets)
+	require.NoError(t, err)
+
+	for _, leak := range append(leaks, leakcheck.ScanLog(log, secrets)...) {
+		t.Error(leak.String())
+	}
+}
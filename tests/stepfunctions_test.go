@@ -0,0 +1,165 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// greetingDefinition is a two-state machine that builds a greeting from its
+// input, so the execution output can be asserted exactly
+const greetingDefinition = `{
+  "Comment": "Terratest greeting state machine",
+  "StartAt": "Greet",
+  "States": {
+    "Greet": {
+      "Type": "Pass",
+      "Parameters": {
+        "greeting.$": "States.Format('Hello, {}!', $.name)"
+      },
+      "Next": "Done"
+    },
+    "Done": {
+      "Type": "Succeed"
+    }
+  }
+}`
+
+func TestStepFunctionsExecution(t *testing.T) {
+	t.Parallel()
+
+	endpoint := getLocalStackEndpoint(t)
+	name := fmt.Sprintf("test-sfn-%d", time.Now().Unix())
+
+	eventTriggers := map[string]interface{}{
+		"nightly": map[string]interface{}{
+			"description":         "Nightly run",
+			"schedule_expression": "cron(0 2 * * ? *)",
+			"input":               `{"name": "nightly"}`,
+		},
+		"uploads": map[string]interface{}{
+			"description":   "Run on new uploads",
+			"event_pattern": `{"source": ["aws.s3"], "detail-type": ["Object Created"]}`,
+		},
+	}
+
+	terraformOptions := createLocalStackModuleOptions(t, "stepfunctions", endpoint, map[string]interface{}{
+		"name":       name,
+		"definition": greetingDefinition,
+		"logging_configuration": map[string]interface{}{
+			"include_execution_data": true,
+			"level":                  "ALL",
+		},
+		"event_triggers": eventTriggers,
+	})
+
+	defer terraform.Destroy(t, terraformOptions)
+
+	terraform.InitAndApply(t, terraformOptions)
+
+	stateMachineArn := terraform.Output(t, terraformOptions, "state_machine_arn")
+	assert.Equal(t, name, terraform.Output(t, terraformOptions, "state_machine_name"))
+
+	sess := createLocalStackSession(t, endpoint)
+	sfnClient := sfn.New(sess)
+
+	execution, err := sfnClient.StartExecution(&sfn.StartExecutionInput{
+		StateMachineArn: aws.String(stateMachineArn),
+		Name:            aws.String(fmt.Sprintf("%s-run", name)),
+		Input:           aws.String(`{"name": "Terratest"}`),
+	})
+	require.NoError(t, err)
+
+	result := waitForSFNExecution(t, sfnClient, *execution.ExecutionArn, 2*time.Minute)
+	require.Equal(t, sfn.ExecutionStatusSucceeded, *result.Status)
+
+	var output map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(*result.Output), &output))
+	assert.Equal(t, map[string]interface{}{"greeting": "Hello, Terratest!"}, output)
+
+	// With logging at level ALL the log group receives an event for every
+	// state transition, ending with ExecutionSucceeded
+	logsClient := cloudwatchlogs.New(sess)
+	logGroupName := fmt.Sprintf("/aws/vendedlogs/states/%s", name)
+	eventTypes := waitForSFNLogEventTypes(t, logsClient, logGroupName, "ExecutionSucceeded", time.Minute)
+	assert.Contains(t, eventTypes, "ExecutionStarted")
+	assert.Contains(t, eventTypes, "ExecutionSucceeded")
+
+	eventsClient := cloudwatchevents.New(sess)
+	for triggerName := range eventTriggers {
+		ruleName := fmt.Sprintf("%s-%s", name, triggerName)
+
+		targets, err := eventsClient.ListTargetsByRule(&cloudwatchevents.ListTargetsByRuleInput{
+			Rule: aws.String(ruleName),
+		})
+		require.NoError(t, err)
+		require.Len(t, targets.Targets, 1, "rule %s should have exactly one target", ruleName)
+		assert.Equal(t, stateMachineArn, *targets.Targets[0].Arn)
+		assert.NotEmpty(t, aws.StringValue(targets.Targets[0].RoleArn))
+	}
+}
+
+// waitForSFNExecution polls an execution until it leaves the RUNNING state or
+// the timeout expires
+func waitForSFNExecution(t *testing.T, client *sfn.SFN, executionArn string, timeout time.Duration) *sfn.DescribeExecutionOutput {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		result, err := client.DescribeExecution(&sfn.DescribeExecutionInput{
+			ExecutionArn: aws.String(executionArn),
+		})
+		require.NoError(t, err)
+
+		if *result.Status != sfn.ExecutionStatusRunning {
+			return result
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Execution %s still running after %s", executionArn, timeout)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// waitForSFNLogEventTypes polls a state machine log group until an event of
+// type until shows up, and returns the types of all events seen
+func waitForSFNLogEventTypes(t *testing.T, client *cloudwatchlogs.CloudWatchLogs, logGroupName string, until string, timeout time.Duration) []string {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		eventTypes := []string{}
+		err := client.FilterLogEventsPages(&cloudwatchlogs.FilterLogEventsInput{
+			LogGroupName: aws.String(logGroupName),
+		}, func(page *cloudwatchlogs.FilterLogEventsOutput, lastPage bool) bool {
+			for _, event := range page.Events {
+				var entry struct {
+					Type string `json:"type"`
+				}
+				if json.Unmarshal([]byte(aws.StringValue(event.Message)), &entry) == nil {
+					eventTypes = append(eventTypes, entry.Type)
+				}
+			}
+			return true
+		})
+		require.NoError(t, err)
+
+		for _, eventType := range eventTypes {
+			if eventType == until {
+				return eventTypes
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("No %s event in %s after %s, saw [%s]", until, logGroupName, timeout, strings.Join(eventTypes, ", "))
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestModuleTagsAll plans each module with a marker tag and checks that the
+// marker reaches tags_all on every planned taggable resource, which confirms
+// what TestModuleTagPropagation finds statically
+func TestModuleTagsAll(t *testing.T) {
+	t.Parallel()
+
+	name := fmt.Sprintf("test-tags-%d", time.Now().Unix())
+	testCases := []struct {
+		name   string
+		module string
+		vars   map[string]interface{}
+	}{
+		{"vpc", "vpc", minimalPlanVars(t, "vpc", name, nil)},
+		{"s3-bucket", "s3-bucket", minimalPlanVars(t, "s3-bucket", name, nil)},
+		{"ec2-instance", "ec2-instance", minimalPlanVars(t, "ec2-instance", name, nil)},
+		{"rds", "rds", minimalPlanVars(t, "rds", name, nil)},
+		{"eks", "eks", minimalPlanVars(t, "eks", name, nil)},
+		{"elasticache", "elasticache", minimalPlanVars(t, "elasticache", name, map[string]interface{}{"create_alarms": true})},
+		{"messaging", "messaging", map[string]interface{}{
+			"sns_topics":        map[string]interface{}{name + "-events": map[string]interface{}{}},
+			"sqs_queues":        map[string]interface{}{name + "-jobs": map[string]interface{}{}},
+			"create_sqs_alarms": true,
+		}},
+		{"stepfunctions", "stepfunctions", minimalPlanVars(t, "stepfunctions", name, map[string]interface{}{"create_alarms": true})},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			region := "us-east-1"
+			vars := map[string]interface{}{}
+			for key, value := range testCase.vars {
+				vars[key] = value
+			}
+			vars["tags"] = map[string]string{
+				"Environment":    "test",
+				"TagPropagation": name,
+			}
+
+			plan := planModule(t, testCase.module, region, vars)
+			assertPlannedTagsPropagated(t, plan, "TagPropagation", name)
+		})
+	}
+}
+
+// assertPlannedTagsPropagated checks that every planned taggable resource
+// carries the given tag in tags_all. Resources whose tags are only known
+// after apply are skipped.
+func assertPlannedTagsPropagated(t *testing.T, plan *terraform.PlanStruct, key string, value string) {
+	checked := 0
+	for address, resource := range plan.ResourcePlannedValuesMap {
+		if resource.Mode != "managed" || !tfmodule.IsTaggable(resource.Type) {
+			continue
+		}
+
+		tags, ok := resource.AttributeValues["tags_all"].(map[string]interface{})
+		if !ok {
+			tags, ok = resource.AttributeValues["tags"].(map[string]interface{})
+		}
+		if !ok {
+			if _, known := resource.AttributeValues["tags"]; known {
+				t.Errorf("%s plans no tags", address)
+			}
+			continue
+		}
+
+		checked++
+		assert.Equal(t, value, tags[key], "%s does not carry tag %s in tags_all", address, key)
+	}
+	assert.NotZero(t, checked, "no taggable resources were planned")
+}
@@ -1,13 +1,39 @@
 package test
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/jaaparjazzery/aws-terraform-tests/cidrplan"
+	"github.com/jaaparjazzery/aws-terraform-tests/leakcheck"
+	"github.com/jaaparjazzery/aws-terraform-tests/policy"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// localStackServices are the provider endpoints redirected to LocalStack
+var localStackServices = []string{
+	"apigateway", "cloudwatch", "dynamodb", "ec2", "events", "iam", "kms",
+	"lambda", "logs", "rds", "route53", "s3", "sfn", "sns", "sqs", "sts",
+}
+
 // createAWSSession creates an AWS session for the specified region
 func createAWSSession(t *testing.T, region string) *session.Session {
 	sess, err := session.NewSession(&aws.Config{
@@ -16,3 +42,287 @@ func createAWSSession(t *testing.T, region string) *session.Session {
 	assert.NoError(t, err)
 	return sess
 }
+
+// createModuleOptions copies modules/<moduleName> to a temporary folder so
+// parallel tests don't share a .terraform directory, and returns options
+// pointing at the copy
+func createModuleOptions(t *testing.T, moduleName string, region string, vars map[string]interface{}) *terraform.Options {
+	moduleDir := test_structure.CopyTerraformFolderToTemp(t, "..", filepath.Join("modules", moduleName))
+
+	envVars := map[string]string{
+		"AWS_DEFAULT_REGION": region,
+	}
+
+	// Terratest logs every -var argument, so secrets are passed as TF_VAR_
+	// environment variables instead to keep them out of the test log
+	module, err := tfmodule.Load(filepath.Join("..", "modules", moduleName))
+	require.NoError(t, err)
+
+	moduleVars := make(map[string]interface{}, len(vars))
+	for name, value := range vars {
+		moduleVars[name] = value
+	}
+	for _, secret := range leakcheck.SecretsFromVars(module, vars) {
+		name := strings.TrimPrefix(secret.Name, "var.")
+		envVars["TF_VAR_"+name] = secret.Value
+		delete(moduleVars, name)
+	}
+
+	return terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: moduleDir,
+		Vars:         moduleVars,
+		EnvVars:      envVars,
+		NoColor:      true,
+	})
+}
+
+// logCapture records the terratest log of a test while still printing it,
+// so the log can be searched for leaked secrets
+type logCapture struct {
+	mu    sync.Mutex
+	lines strings.Builder
+}
+
+// Logf implements logger.TestLogger
+func (c *logCapture) Logf(t terratesting.TestingT, format string, args ...interface{}) {
+	c.mu.Lock()
+	fmt.Fprintf(&c.lines, format, args...)
+	c.lines.WriteByte('\n')
+	c.mu.Unlock()
+
+	logger.Default.Logf(t, format, args...)
+}
+
+// String returns everything logged so far
+func (c *logCapture) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lines.String()
+}
+
+// captureTerratestLog makes terratest log the commands run with
+// terraformOptions, and their output, into the returned capture
+func captureTerratestLog(terraformOptions *terraform.Options) *logCapture {
+	capture := &logCapture{}
+	terraformOptions.Logger = logger.New(capture)
+	return capture
+}
+
+// createModulePlanOptions is like createModuleOptions but also writes a plan
+// file so the plan can be parsed
+func createModulePlanOptions(t *testing.T, moduleName string, region string, vars map[string]interface{}) *terraform.Options {
+	terraformOptions := createModuleOptions(t, moduleName, region, vars)
+	terraformOptions.PlanFilePath = filepath.Join(terraformOptions.TerraformDir, "tfplan")
+	return terraformOptions
+}
+
+// planModule runs init and plan against a module and returns the parsed plan
+func planModule(t *testing.T, moduleName string, region string, vars map[string]interface{}) *terraform.PlanStruct {
+	terraformOptions := createModulePlanOptions(t, moduleName, region, vars)
+	return terraform.InitAndPlanAndShowWithStruct(t, terraformOptions)
+}
+
+// planModuleE runs init and plan against a module and returns the plan error,
+// for tests that expect invalid inputs to be rejected
+func planModuleE(t *testing.T, moduleName string, region string, vars map[string]interface{}) error {
+	terraformOptions := createModulePlanOptions(t, moduleName, region, vars)
+	_, err := terraform.InitAndPlanE(t, terraformOptions)
+	return err
+}
+
+// createVPCVars returns modules/vpc variables for a VPC spanning the given
+// availability zones, with one /24 public and one /24 private subnet per zone
+// carved out of vpcCIDR
+func createVPCVars(t *testing.T, vpcName string, vpcCIDR string, availabilityZones []string) map[string]interface{} {
+	layout, err := cidrplan.Plan(vpcCIDR, len(availabilityZones), 24)
+	require.NoError(t, err)
+
+	return map[string]interface{}{
+		"vpc_name":             vpcName,
+		"vpc_cidr":             layout.VPCCIDR,
+		"availability_zones":   availabilityZones,
+		"public_subnet_cidrs":  layout.PublicSubnetCIDRs,
+		"private_subnet_cidrs": layout.PrivateSubnetCIDRs,
+	}
+}
+
+// planSubnetIDs are placeholder subnets for modules that are only planned
+var planSubnetIDs = []string{"subnet-0a1b2c3d4e5f60001", "subnet-0a1b2c3d4e5f60002"}
+
+// minimalPlanVars returns the fewest variables that plan modules/<moduleName>
+// without any existing infrastructure, naming its resources name, with
+// overrides on top. A nil override drops the variable so the module default
+// applies.
+func minimalPlanVars(t *testing.T, moduleName string, name string, overrides map[string]interface{}) map[string]interface{} {
+	var vars map[string]interface{}
+	switch moduleName {
+	case "vpc":
+		vars = createVPCVars(t, name, "10.0.0.0/16", []string{"us-east-1a", "us-east-1b"})
+	case "s3-bucket":
+		vars = map[string]interface{}{"bucket_name": name}
+	case "ec2-instance":
+		vars = map[string]interface{}{
+			"instance_name":      name,
+			"subnet_id":          planSubnetIDs[0],
+			"security_group_ids": []string{"sg-0a1b2c3d4e5f60001"},
+		}
+	case "rds":
+		vars = map[string]interface{}{
+			"db_identifier":   name,
+			"engine":          "postgres",
+			"engine_version":  "16",
+			"database_name":   "testdb",
+			"master_username": "dbadmin",
+			"master_password": "TestPassword123!",
+			"subnet_ids":      planSubnetIDs,
+		}
+	case "eks":
+		vars = map[string]interface{}{
+			"cluster_name":               name,
+			"vpc_id":                     "vpc-0a1b2c3d4e5f60001",
+			"subnet_ids":                 planSubnetIDs,
+			"cluster_encryption_key_arn": "arn:aws:kms:us-east-1:123456789012:key/00000000-0000-0000-0000-000000000000",
+		}
+	case "elasticache":
+		vars = map[string]interface{}{
+			"engine":                     "redis",
+			"replication_group_id":       name,
+			"description":                "Terratest plan fixture",
+			"subnet_group_name":          name,
+			"subnet_ids":                 planSubnetIDs,
+			"parameter_group_name":       name,
+			"num_cache_clusters":         2,
+			"transit_encryption_enabled": true,
+			"auth_token":                 "TestAuthToken1234567890!",
+		}
+	case "stepfunctions":
+		vars = map[string]interface{}{
+			"name":       name,
+			"definition": `{"StartAt": "Done", "States": {"Done": {"Type": "Succeed"}}}`,
+		}
+	default:
+		t.Fatalf("No plan fixture for modules/%s", moduleName)
+	}
+
+	for key, value := range overrides {
+		if value == nil {
+			delete(vars, key)
+			continue
+		}
+		vars[key] = value
+	}
+	return vars
+}
+
+// planModuleJSON runs init and plan against a module and parses the JSON
+// rendering of the plan, for the policy, IAM and security group checks
+func planModuleJSON(t *testing.T, moduleName string, region string, vars map[string]interface{}) *policy.Plan {
+	terraformOptions := createModulePlanOptions(t, moduleName, region, vars)
+	planJSON := terraform.InitAndPlanAndShow(t, terraformOptions)
+
+	plan, err := policy.ParsePlan([]byte(planJSON))
+	require.NoError(t, err)
+	return plan
+}
+
+// getPlannedAttributes returns the planned attribute values of a resource,
+// failing the test if the resource is not part of the plan
+func getPlannedAttributes(t *testing.T, plan *terraform.PlanStruct, address string) map[string]interface{} {
+	terraform.RequirePlannedValuesMapKeyExists(t, plan, address)
+	return plan.ResourcePlannedValuesMap[address].AttributeValues
+}
+
+// isResourcePlanned reports whether a resource address is part of the plan
+func isResourcePlanned(plan *terraform.PlanStruct, address string) bool {
+	_, exists := plan.ResourcePlannedValuesMap[address]
+	return exists
+}
+
+// createKMSKey creates a symmetric KMS key for tests that need customer
+// managed encryption, and schedules it for deletion when the test finishes
+func createKMSKey(t *testing.T, region string, description string) string {
+	kmsClient := kms.New(createAWSSession(t, region))
+
+	result, err := kmsClient.CreateKey(&kms.CreateKeyInput{
+		Description: aws.String(description),
+	})
+	require.NoError(t, err)
+
+	keyID := result.KeyMetadata.KeyId
+	t.Cleanup(func() {
+		_, err := kmsClient.ScheduleKeyDeletion(&kms.ScheduleKeyDeletionInput{
+			KeyId:               keyID,
+			PendingWindowInDays: aws.Int64(7),
+		})
+		assert.NoError(t, err)
+	})
+
+	return aws.StringValue(result.KeyMetadata.Arn)
+}
+
+// isAWSErrorCode reports whether err is an AWS API error with the given code
+func isAWSErrorCode(err error, code string) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == code
+}
+
+// getLocalStackEndpoint returns the LocalStack edge endpoint from
+// LOCALSTACK_ENDPOINT (default http://localhost:4566) and skips the test when
+// nothing is listening there
+func getLocalStackEndpoint(t *testing.T) string {
+	endpoint := os.Getenv("LOCALSTACK_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "http://localhost:4566"
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(endpoint + "/_localstack/health")
+	if err != nil {
+		t.Skipf("LocalStack is not reachable at %s: %v", endpoint, err)
+	}
+	resp.Body.Close()
+
+	return endpoint
+}
+
+// createLocalStackModuleOptions copies a module to a temporary folder and adds
+// a provider configuration that points every AWS endpoint at LocalStack
+func createLocalStackModuleOptions(t *testing.T, moduleName string, endpoint string, vars map[string]interface{}) *terraform.Options {
+	terraformOptions := createModuleOptions(t, moduleName, "us-east-1", vars)
+
+	var endpoints strings.Builder
+	for _, service := range localStackServices {
+		fmt.Fprintf(&endpoints, "    %s = %q\n", service, endpoint)
+	}
+
+	provider := fmt.Sprintf(`provider "aws" {
+  region                      = "us-east-1"
+  access_key                  = "test"
+  secret_key                  = "test"
+  skip_credentials_validation = true
+  skip_metadata_api_check     = true
+  skip_requesting_account_id  = true
+  s3_use_path_style           = true
+
+  endpoints {
+%s  }
+}
+`, endpoints.String())
+
+	err := os.WriteFile(filepath.Join(terraformOptions.TerraformDir, "localstack_provider.tf"), []byte(provider), 0644)
+	require.NoError(t, err)
+
+	return terraformOptions
+}
+
+// createLocalStackSession creates an AWS session that talks to LocalStack
+func createLocalStackSession(t *testing.T, endpoint string) *session.Session {
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String(endpoint),
+		Credentials:      credentials.NewStaticCredentials("test", "test", ""),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	require.NoError(t, err)
+	return sess
+}
@@ -0,0 +1,306 @@
+package tfmodule
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// moduleMetaArguments are the arguments of a module block that don't set
+// input variables
+var moduleMetaArguments = map[string]bool{
+	"source":     true,
+	"version":    true,
+	"count":      true,
+	"for_each":   true,
+	"providers":  true,
+	"depends_on": true,
+}
+
+// calledVariable is what a module call needs to know about a variable of the
+// called module
+type calledVariable struct {
+	constraint cty.Type
+	required   bool
+}
+
+// CheckModuleCalls checks every module block whose source is a local path
+// against the variables the called module declares. It reports missing
+// required arguments, arguments the module doesn't declare, and values that
+// don't convert to the variable's type, down to nested object attributes,
+// including object attributes that the type doesn't declare. Parts of a value
+// computed from references or function calls are skipped, so only the
+// literal parts are checked.
+func CheckModuleCalls(m *Module) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	called := map[string]map[string]calledVariable{}
+
+	for _, block := range m.BlocksOfType("module") {
+		file := filepath.Join(m.Dir, block.File)
+		report := func(line int, format string, args ...interface{}) {
+			diagnostics = append(diagnostics, Diagnostic{
+				File:    file,
+				Line:    line,
+				Message: block.Address() + ": " + fmt.Sprintf(format, args...),
+			})
+		}
+
+		source, ok := localSource(block)
+		if !ok {
+			continue
+		}
+
+		dir := filepath.Join(m.Dir, source)
+		variables, ok := called[dir]
+		if !ok {
+			var err error
+			variables, err = loadCalledVariables(dir)
+			if err != nil {
+				report(block.Range.Start.Line, "cannot load source %q: %v", source, err)
+				continue
+			}
+			called[dir] = variables
+		}
+
+		for _, attr := range sortedAttributes(block.Body) {
+			if moduleMetaArguments[attr.Name] {
+				continue
+			}
+			variable, declared := variables[attr.Name]
+			if !declared {
+				report(attr.NameRange.Start.Line, "unsupported argument %q, %s declares no such variable%s", attr.Name, source, suggestion(attr.Name, variableNames(variables)))
+				continue
+			}
+			for _, problem := range checkValue(attr.Expr, variable.constraint, attr.Name) {
+				report(problem.line, "%s", problem.message)
+			}
+		}
+
+		for _, name := range variableNames(variables) {
+			if _, set := block.Body.Attributes[name]; !set && variables[name].required {
+				report(block.Range.Start.Line, "missing required argument %q", name)
+			}
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].File != diagnostics[j].File {
+			return diagnostics[i].File < diagnostics[j].File
+		}
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+	return diagnostics
+}
+
+// localSource returns the source of a module block when it is a local path
+func localSource(block *Block) (string, bool) {
+	attr, ok := block.Body.Attributes["source"]
+	if !ok {
+		return "", false
+	}
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !value.Type().Equals(cty.String) || value.IsNull() {
+		return "", false
+	}
+	source := value.AsString()
+	return source, strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// loadCalledVariables reads the type constraint of every variable of the
+// module in dir
+func loadCalledVariables(dir string) (map[string]calledVariable, error) {
+	module, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(module.Files) == 0 {
+		return nil, fmt.Errorf("no .tf files in %s", dir)
+	}
+
+	variables := map[string]calledVariable{}
+	for _, block := range module.BlocksOfType("variable") {
+		variable := calledVariable{constraint: cty.DynamicPseudoType}
+		if attr, ok := block.Body.Attributes["type"]; ok {
+			constraint, _, diags := typeexpr.TypeConstraintWithDefaults(attr.Expr)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("%s: %s", block.Pos(module.Dir), diags.Error())
+			}
+			variable.constraint = constraint
+		}
+		_, hasDefault := block.Body.Attributes["default"]
+		variable.required = !hasDefault
+		variables[block.Labels[0]] = variable
+	}
+	return variables, nil
+}
+
+func variableNames(variables map[string]calledVariable) []string {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// valueProblem is a part of an argument that doesn't fit the variable's type
+type valueProblem struct {
+	line    int
+	message string
+}
+
+// checkValue checks an expression against a type constraint. Object and
+// tuple constructors are walked element by element, so that a reference in
+// one attribute doesn't hide a mistake in another. Any other expression is
+// converted to the type when it evaluates without references or functions,
+// and skipped otherwise.
+func checkValue(expr hclsyntax.Expression, want cty.Type, path string) []valueProblem {
+	if want == cty.DynamicPseudoType {
+		return nil
+	}
+	line := expr.Range().Start.Line
+
+	switch expr := expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		switch {
+		case want.IsObjectType():
+			return checkObject(expr, want, path)
+		case want.IsMapType():
+			problems := []valueProblem{}
+			for _, item := range expr.Items {
+				elementPath := path + "[...]"
+				if key, ok := objectKey(item.KeyExpr); ok {
+					elementPath = fmt.Sprintf("%s[%q]", path, key)
+				}
+				problems = append(problems, checkValue(item.ValueExpr, want.ElementType(), elementPath)...)
+			}
+			return problems
+		}
+	case *hclsyntax.TupleConsExpr:
+		switch {
+		case want.IsListType() || want.IsSetType():
+			problems := []valueProblem{}
+			for i, element := range expr.Exprs {
+				problems = append(problems, checkValue(element, want.ElementType(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+			return problems
+		case want.IsTupleType():
+			elementTypes := want.TupleElementTypes()
+			if len(expr.Exprs) != len(elementTypes) {
+				return []valueProblem{{line, fmt.Sprintf("%s: tuple needs %d elements, got %d", path, len(elementTypes), len(expr.Exprs))}}
+			}
+			problems := []valueProblem{}
+			for i, element := range expr.Exprs {
+				problems = append(problems, checkValue(element, elementTypes[i], fmt.Sprintf("%s[%d]", path, i))...)
+			}
+			return problems
+		}
+	}
+
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsWhollyKnown() {
+		return nil
+	}
+	if _, err := convert.Convert(value, want); err != nil {
+		return []valueProblem{{line, fmt.Sprintf("%s: %s, got %s", path, err.Error(), value.Type().FriendlyName())}}
+	}
+	return nil
+}
+
+// checkObject checks the attributes of an object constructor against an
+// object type, which may mark attributes optional
+func checkObject(expr *hclsyntax.ObjectConsExpr, want cty.Type, path string) []valueProblem {
+	problems := []valueProblem{}
+	attributeTypes := want.AttributeTypes()
+	names := make([]string, 0, len(attributeTypes))
+	for name := range attributeTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	set := map[string]bool{}
+	allKeysKnown := true
+	for _, item := range expr.Items {
+		name, ok := objectKey(item.KeyExpr)
+		if !ok {
+			allKeysKnown = false
+			continue
+		}
+		set[name] = true
+
+		attributeType, declared := attributeTypes[name]
+		if !declared {
+			problems = append(problems, valueProblem{
+				item.KeyExpr.Range().Start.Line,
+				fmt.Sprintf("%s: unexpected attribute %q, which the type drops%s", path, name, suggestion(name, names)),
+			})
+			continue
+		}
+		problems = append(problems, checkValue(item.ValueExpr, attributeType, path+"."+name)...)
+	}
+
+	if allKeysKnown {
+		for _, name := range names {
+			if !set[name] && !want.AttributeOptional(name) {
+				problems = append(problems, valueProblem{expr.Range().Start.Line, fmt.Sprintf("%s: missing required attribute %q", path, name)})
+			}
+		}
+	}
+	return problems
+}
+
+// objectKey returns the literal key of an object constructor item, which may
+// be a bare name or a quoted string
+func objectKey(expr hclsyntax.Expression) (string, bool) {
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsWhollyKnown() || value.IsNull() {
+		return "", false
+	}
+	value, err := convert.Convert(value, cty.String)
+	if err != nil {
+		return "", false
+	}
+	return value.AsString(), true
+}
+
+// suggestion returns a hint naming the candidate closest to name, when one
+// is close enough to be a likely typo
+func suggestion(name string, candidates []string) string {
+	best, bestDistance := "", len(name)/3+2
+	for _, candidate := range candidates {
+		if distance := editDistance(name, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,45 @@
+package tfmodule
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckModuleCallsReportsTypeErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("testdata", "calls", "root")
+	module, err := Load(dir)
+	require.NoError(t, err)
+
+	messages := []string{}
+	for _, diagnostic := range CheckModuleCalls(module) {
+		messages = append(messages, diagnostic.String())
+	}
+
+	mainFile := filepath.Join(dir, "main.tf")
+	assert.Equal(t, []string{
+		mainFile + `:1: module.app: missing required argument "vpc_id"`,
+		mainFile + `:5: module.app: instance_count: a number is required, got string`,
+		mainFile + `:6: module.app: unsupported argument "instance_typ", ../modules/app declares no such variable, did you mean "instance_type"?`,
+		mainFile + `:7: module.app: enabled: a bool is required, got string`,
+		mainFile + `:12: module.app: node_groups["general"].disk_size: a number is required, got string`,
+		mainFile + `:13: module.app: node_groups["general"]: unexpected attribute "lables", which the type drops, did you mean "labels"?`,
+		mainFile + `:16: module.app: node_groups["general"].taints[0]: unexpected attribute "value", which the type drops`,
+		mainFile + `:16: module.app: node_groups["general"].taints[0]: missing required attribute "effect"`,
+		mainFile + `:22: module.app: node_groups[...]: missing required attribute "desired_size"`,
+		mainFile + `:53: module.missing: cannot load source "../modules/missing": no .tf files in ` + filepath.Join(dir, "..", "modules", "missing"),
+	}, messages)
+}
+
+func TestSuggestion(t *testing.T) {
+	t.Parallel()
+
+	candidates := []string{"instance_type", "instance_count", "vpc_id"}
+	assert.Equal(t, `, did you mean "instance_type"?`, suggestion("instance_typ", candidates))
+	assert.Equal(t, `, did you mean "vpc_id"?`, suggestion("vpcid", candidates))
+	assert.Equal(t, "", suggestion("subnet_ids", candidates))
+}
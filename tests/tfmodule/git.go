@@ -0,0 +1,79 @@
+package tfmodule
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LoadAllAtRef loads every module directory directly under root as of a git
+// revision, keyed by name, without touching the working tree. Root is a
+// directory of the working tree, such as ../modules. Positions name files as
+// ref:path, such as v1.2.0:modules/vpc/variables.tf.
+func LoadAllAtRef(root string, ref string) (map[string]*Module, error) {
+	top, err := git(root, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	// Resolve symlinks the same way git does, such as a /tmp link on macOS
+	if resolved, err := filepath.EvalSymlinks(absRoot); err == nil {
+		absRoot = resolved
+	}
+	relRoot, err := filepath.Rel(strings.TrimSpace(top), absRoot)
+	if err != nil {
+		return nil, err
+	}
+	relRoot = filepath.ToSlash(relRoot)
+
+	listing, err := git(root, "ls-tree", "-r", "-z", "--full-tree", "--name-only", ref, "--", relRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := map[string]map[string][]byte{}
+	for _, file := range strings.Split(strings.TrimRight(listing, "\x00"), "\x00") {
+		rest := strings.TrimPrefix(file, relRoot+"/")
+		parts := strings.Split(rest, "/")
+		if file == "" || len(parts) != 2 || path.Ext(parts[1]) != ".tf" {
+			continue
+		}
+
+		contents, err := git(root, "show", ref+":"+file)
+		if err != nil {
+			return nil, err
+		}
+		if sources[parts[0]] == nil {
+			sources[parts[0]] = map[string][]byte{}
+		}
+		sources[parts[0]][parts[1]] = []byte(contents)
+	}
+
+	modules := map[string]*Module{}
+	for name, files := range sources {
+		module, err := Parse(name, ref+":"+path.Join(relRoot, name), files)
+		if err != nil {
+			return nil, err
+		}
+		modules[name] = module
+	}
+	return modules, nil
+}
+
+// git runs a git command in dir and returns its output
+func git(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,56 @@
+package tfmodule
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAllAtRefReadsCommittedModules(t *testing.T) {
+	t.Parallel()
+
+	repo := t.TempDir()
+	runGit(t, repo, "init", "--quiet")
+	writeFile(t, repo, "modules/vpc/variables.tf", "variable \"cidr_block\" {\n  type = string\n}\n")
+	writeFile(t, repo, "modules/vpc/README.md", "# VPC\n")
+	writeFile(t, repo, "modules/vpc/examples/basic/main.tf", "module \"vpc\" {\n  source = \"../..\"\n}\n")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "Add vpc module")
+	runGit(t, repo, "tag", "v1.0.0")
+
+	writeFile(t, repo, "modules/vpc/variables.tf", "variable \"vpc_cidr_block\" {\n  type = string\n}\n")
+	writeFile(t, repo, "modules/sqs/main.tf", "resource \"aws_sqs_queue\" \"main\" {}\n")
+
+	modules, err := LoadAllAtRef(filepath.Join(repo, "modules"), "v1.0.0")
+	require.NoError(t, err)
+	require.Len(t, modules, 1, "uncommitted modules are not loaded")
+
+	vpc := modules["vpc"]
+	require.NotNil(t, vpc)
+	assert.Equal(t, "v1.0.0:modules/vpc", vpc.Dir)
+	assert.Equal(t, []string{"variables.tf"}, vpc.Files, "files of nested directories are skipped")
+	require.NotNil(t, vpc.Lookup("var.cidr_block"))
+	assert.Equal(t, "v1.0.0:modules/vpc/variables.tf:1", vpc.Lookup("var.cidr_block").Pos(vpc.Dir))
+
+	_, err = LoadAllAtRef(filepath.Join(repo, "modules"), "v9.9.9")
+	assert.Error(t, err)
+}
+
+// runGit runs a git command in dir with a fixed identity
+func runGit(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+// writeFile writes a file under dir, creating its parent directories
+func writeFile(t *testing.T, dir string, name string, contents string) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+}
@@ -0,0 +1,262 @@
+// Package tfmodule loads Terraform modules straight from their HCL source, so
+// they can be inspected without terraform init, provider plugins or network
+// access.
+package tfmodule
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Module is a parsed module directory
+type Module struct {
+	Name   string
+	Dir    string
+	Files  []string
+	Blocks []*Block
+
+	// sources holds the raw bytes of each file, keyed by base name
+	sources map[string][]byte
+}
+
+// Block is a top-level block of a module file
+type Block struct {
+	Type   string
+	Labels []string
+	File   string
+	Body   *hclsyntax.Body
+	Range  hcl.Range
+}
+
+// Reference is a traversal used in an expression somewhere in a module
+type Reference struct {
+	Traversal hcl.Traversal
+	Block     *Block
+}
+
+// Address returns the name other expressions use to refer to the block, such
+// as var.name, aws_s3_bucket.this or data.aws_ami.latest
+func (b *Block) Address() string {
+	switch b.Type {
+	case "resource":
+		return strings.Join(b.Labels, ".")
+	case "data":
+		return "data." + strings.Join(b.Labels, ".")
+	case "variable":
+		return "var." + b.Labels[0]
+	case "output":
+		return "output." + b.Labels[0]
+	case "module":
+		return "module." + b.Labels[0]
+	}
+	return b.Type
+}
+
+// Pos returns the block's position as path:line
+func (b *Block) Pos(dir string) string {
+	return fmt.Sprintf("%s:%d", filepath.Join(dir, b.File), b.Range.Start.Line)
+}
+
+// Load parses every .tf file in dir
+func Load(dir string) (*Module, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	sources := map[string][]byte{}
+	for _, path := range matches {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sources[filepath.Base(path)] = src
+	}
+
+	return Parse(filepath.Base(dir), dir, sources)
+}
+
+// Parse parses a module from the contents of its .tf files, keyed by base
+// name. Dir is only used to name the files in positions and diagnostics.
+func Parse(name string, dir string, sources map[string][]byte) (*Module, error) {
+	names := make([]string, 0, len(sources))
+	for file := range sources {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+
+	module := &Module{
+		Name:    name,
+		Dir:     dir,
+		sources: map[string][]byte{},
+	}
+
+	parser := hclparse.NewParser()
+	for _, file := range names {
+		src := sources[file]
+		parsed, diags := parser.ParseHCL(src, filepath.Join(dir, file))
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		module.Files = append(module.Files, file)
+		module.sources[file] = src
+
+		for _, block := range parsed.Body.(*hclsyntax.Body).Blocks {
+			module.Blocks = append(module.Blocks, &Block{
+				Type:   block.Type,
+				Labels: block.Labels,
+				File:   file,
+				Body:   block.Body,
+				Range:  block.DefRange(),
+			})
+		}
+	}
+
+	return module, nil
+}
+
+// LoadAll loads every module directory directly under root, keyed by name
+func LoadAll(root string) (map[string]*Module, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := map[string]*Module{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		module, err := Load(filepath.Join(root, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		modules[entry.Name()] = module
+	}
+
+	return modules, nil
+}
+
+// Source returns the raw contents of one of the module's files
+func (m *Module) Source(file string) []byte {
+	return m.sources[file]
+}
+
+// BlocksOfType returns the module's top-level blocks of the given type in
+// file order
+func (m *Module) BlocksOfType(blockType string) []*Block {
+	blocks := []*Block{}
+	for _, block := range m.Blocks {
+		if block.Type == blockType {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// Lookup returns the block declaring address, or nil if there is none
+func (m *Module) Lookup(address string) *Block {
+	for _, block := range m.Blocks {
+		if block.Type != "locals" && block.Type != "terraform" && block.Type != "provider" && block.Address() == address {
+			return block
+		}
+	}
+	return nil
+}
+
+// Locals returns the names of all locals declared in the module
+func (m *Module) Locals() map[string]*hclsyntax.Attribute {
+	locals := map[string]*hclsyntax.Attribute{}
+	for _, block := range m.BlocksOfType("locals") {
+		for name, attr := range block.Body.Attributes {
+			locals[name] = attr
+		}
+	}
+	return locals
+}
+
+// References returns every traversal used by expressions in the module, in
+// file order. Dynamic block iterators and lifecycle.ignore_changes entries
+// are not references and are left out.
+func (m *Module) References() []Reference {
+	references := []Reference{}
+	for _, block := range m.Blocks {
+		for _, traversal := range bodyTraversals(block.Body, nil, false) {
+			references = append(references, Reference{Traversal: traversal, Block: block})
+		}
+	}
+	return references
+}
+
+func bodyTraversals(body *hclsyntax.Body, iterators []string, inLifecycle bool) []hcl.Traversal {
+	traversals := []hcl.Traversal{}
+
+	for _, attr := range sortedAttributes(body) {
+		if inLifecycle && attr.Name == "ignore_changes" {
+			continue
+		}
+		for _, traversal := range attr.Expr.Variables() {
+			if !contains(iterators, traversal.RootName()) {
+				traversals = append(traversals, traversal)
+			}
+		}
+	}
+
+	for _, block := range body.Blocks {
+		nested := iterators
+		if block.Type == "dynamic" && len(block.Labels) == 1 {
+			// for_each is evaluated outside the iterator's scope
+			if forEach, ok := block.Body.Attributes["for_each"]; ok {
+				for _, traversal := range forEach.Expr.Variables() {
+					if !contains(iterators, traversal.RootName()) {
+						traversals = append(traversals, traversal)
+					}
+				}
+			}
+
+			iterator := block.Labels[0]
+			if attr, ok := block.Body.Attributes["iterator"]; ok {
+				iterator = hcl.ExprAsKeyword(attr.Expr)
+			}
+			nested = append(append([]string{}, iterators...), iterator)
+
+			for _, content := range block.Body.Blocks {
+				traversals = append(traversals, bodyTraversals(content.Body, nested, false)...)
+			}
+			continue
+		}
+
+		traversals = append(traversals, bodyTraversals(block.Body, nested, block.Type == "lifecycle")...)
+	}
+
+	return traversals
+}
+
+func sortedAttributes(body *hclsyntax.Body) []*hclsyntax.Attribute {
+	attrs := make([]*hclsyntax.Attribute, 0, len(body.Attributes))
+	for _, attr := range body.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].SrcRange.Start.Byte < attrs[j].SrcRange.Start.Byte
+	})
+	return attrs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package tfmodule
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// conventionalFiles lists the block types each conventionally named file may
+// hold. Files not listed here are not restricted.
+var conventionalFiles = map[string][]string{
+	"main.tf":      {"terraform", "provider", "locals", "resource", "data", "module"},
+	"variables.tf": {"variable"},
+	"outputs.tf":   {"output"},
+	"versions.tf":  {"terraform", "provider"},
+}
+
+// builtinRoots are reference roots that never need a declaration
+var builtinRoots = map[string]bool{
+	"each":      true,
+	"count":     true,
+	"self":      true,
+	"path":      true,
+	"terraform": true,
+}
+
+// Diagnostic is a structural problem found in a module
+type Diagnostic struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+}
+
+// CheckStructure reports blocks that sit in the wrong conventional file,
+// files whose header comment names a different file, duplicate declarations
+// and references to resources, data sources, variables, locals or modules
+// that the module does not declare.
+func CheckStructure(m *Module) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	diagnostics = append(diagnostics, checkHeaders(m)...)
+	diagnostics = append(diagnostics, checkPlacement(m)...)
+	diagnostics = append(diagnostics, checkDuplicates(m)...)
+	diagnostics = append(diagnostics, checkReferences(m)...)
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].File != diagnostics[j].File {
+			return diagnostics[i].File < diagnostics[j].File
+		}
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+	return diagnostics
+}
+
+// checkHeaders compares a leading "# modules/<name>/<file>" comment with the
+// file it is in, which catches files whose contents were swapped
+func checkHeaders(m *Module) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, file := range m.Files {
+		scanner := bufio.NewScanner(bytes.NewReader(m.Source(file)))
+		if !scanner.Scan() {
+			continue
+		}
+
+		header := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(header, "# modules/") {
+			continue
+		}
+
+		want := fmt.Sprintf("# modules/%s/%s", m.Name, file)
+		if header != want {
+			diagnostics = append(diagnostics, Diagnostic{
+				File:    filepath.Join(m.Dir, file),
+				Line:    1,
+				Message: fmt.Sprintf("header comment %q does not match file, expected %q", header, want),
+			})
+		}
+	}
+	return diagnostics
+}
+
+func checkPlacement(m *Module) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, block := range m.Blocks {
+		allowed, ok := conventionalFiles[block.File]
+		if !ok || contains(allowed, block.Type) {
+			continue
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			File:    filepath.Join(m.Dir, block.File),
+			Line:    block.Range.Start.Line,
+			Message: fmt.Sprintf("%s block %s does not belong in %s, which may only contain %s", block.Type, block.Address(), block.File, strings.Join(allowed, ", ")),
+		})
+	}
+	return diagnostics
+}
+
+func checkDuplicates(m *Module) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	seen := map[string]*Block{}
+	for _, block := range m.Blocks {
+		if block.Type == "locals" || block.Type == "terraform" || block.Type == "provider" {
+			continue
+		}
+
+		address := block.Address()
+		if first, ok := seen[address]; ok {
+			diagnostics = append(diagnostics, Diagnostic{
+				File:    filepath.Join(m.Dir, block.File),
+				Line:    block.Range.Start.Line,
+				Message: fmt.Sprintf("duplicate %s block %s, first declared at %s", block.Type, address, first.Pos(m.Dir)),
+			})
+			continue
+		}
+		seen[address] = block
+	}
+	return diagnostics
+}
+
+func checkReferences(m *Module) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	locals := m.Locals()
+
+	for _, reference := range m.References() {
+		address, kind := referencedAddress(reference.Traversal)
+		if address == "" {
+			continue
+		}
+
+		declared := false
+		if kind == "local" {
+			_, declared = locals[strings.TrimPrefix(address, "local.")]
+		} else {
+			declared = m.Lookup(address) != nil
+		}
+		if declared {
+			continue
+		}
+
+		rng := reference.Traversal.SourceRange()
+		diagnostics = append(diagnostics, Diagnostic{
+			File:    filepath.Join(m.Dir, filepath.Base(rng.Filename)),
+			Line:    rng.Start.Line,
+			Message: fmt.Sprintf("reference to undeclared %s %s", kind, address),
+		})
+	}
+	return diagnostics
+}
+
+// referencedAddress returns the declaration a traversal points at, such as
+// var.name or aws_s3_bucket.this, and what kind of declaration it is. It
+// returns an empty address for traversals that need no declaration.
+func referencedAddress(traversal hcl.Traversal) (string, string) {
+	root := traversal.RootName()
+	if builtinRoots[root] {
+		return "", ""
+	}
+
+	names := []string{root}
+	for _, step := range traversal[1:] {
+		attr, ok := step.(hcl.TraverseAttr)
+		if !ok {
+			break
+		}
+		names = append(names, attr.Name)
+	}
+
+	switch root {
+	case "var":
+		if len(names) >= 2 {
+			return "var." + names[1], "variable"
+		}
+	case "local":
+		if len(names) >= 2 {
+			return "local." + names[1], "local"
+		}
+	case "module":
+		if len(names) >= 2 {
+			return "module." + names[1], "module"
+		}
+	case "data":
+		if len(names) >= 3 {
+			return "data." + names[1] + "." + names[2], "data source"
+		}
+	default:
+		// Anything else with a provider prefix is a managed resource
+		if strings.Contains(root, "_") && len(names) >= 2 {
+			return root + "." + names[1], "resource"
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,57 @@
+package tfmodule
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckStructureReportsBrokenModule(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("testdata", "broken")
+	module, err := Load(dir)
+	require.NoError(t, err)
+
+	messages := []string{}
+	for _, diagnostic := range CheckStructure(module) {
+		messages = append(messages, diagnostic.String())
+	}
+
+	mainFile := filepath.Join(dir, "main.tf")
+	variablesFile := filepath.Join(dir, "variables.tf")
+	assert.Equal(t, []string{
+		mainFile + `:1: header comment "# modules/broken/variables.tf" does not match file, expected "# modules/broken/main.tf"`,
+		mainFile + ":3: variable block var.misplaced does not belong in main.tf, which may only contain terraform, provider, locals, resource, data, module",
+		mainFile + ":8: reference to undeclared variable var.bucket_name",
+		mainFile + ":9: reference to undeclared local local.tags",
+		mainFile + ":20: reference to undeclared resource aws_s3_bucket.missing",
+		mainFile + ":21: reference to undeclared data source data.aws_iam_policy_document.missing",
+		variablesFile + ":7: duplicate variable block var.rules, first declared at " + variablesFile + ":3",
+	}, messages)
+}
+
+func TestReferencesSkipIteratorsAndIgnoreChanges(t *testing.T) {
+	t.Parallel()
+
+	module, err := Load(filepath.Join("testdata", "broken"))
+	require.NoError(t, err)
+
+	roots := []string{}
+	versioningReferences := 0
+	for _, reference := range module.References() {
+		roots = append(roots, reference.Traversal.RootName())
+		if reference.Block.Address() == "aws_s3_bucket_versioning.this" {
+			versioningReferences++
+		}
+	}
+	assert.NotContains(t, roots, "rule", "dynamic block iterator reported as a reference")
+	assert.NotContains(t, roots, "versioning_configuration", "ignore_changes entry reported as a reference")
+	assert.NotContains(t, roots, "expected_bucket_owner", "ignore_changes entry reported as a reference")
+
+	// Only the bucket argument of the resource holding the lifecycle block
+	// is a reference
+	assert.Equal(t, 1, versioningReferences)
+}
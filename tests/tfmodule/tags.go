@@ -0,0 +1,190 @@
+package tfmodule
+
+import (
+	_ "embed"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+//go:embed taggable_resources.txt
+var taggableResourcesFile string
+
+// taggableResources is the bundled set of resource types that accept tags
+var taggableResources = parseTaggableResources(taggableResourcesFile)
+
+func parseTaggableResources(contents string) map[string]bool {
+	types := map[string]bool{}
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			types[line] = true
+		}
+	}
+	return types
+}
+
+// IsTaggable reports whether resources of the given type accept tags
+func IsTaggable(resourceType string) bool {
+	return taggableResources[resourceType]
+}
+
+// TaggableResourceTypes returns the bundled list of taggable resource types,
+// sorted
+func TaggableResourceTypes() []string {
+	types := make([]string, 0, len(taggableResources))
+	for resourceType := range taggableResources {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// tagPropagation is how much of var.tags an expression passes on
+type tagPropagation int
+
+const (
+	tagsDropped tagPropagation = iota
+	tagsPartial
+	tagsPropagated
+)
+
+// CheckTags reports taggable resources that do not pass var.tags on in full,
+// either because they set no tags at all, build their tags without var.tags,
+// or only pick individual keys out of it. Tags built from a local are
+// followed to the local's definition. Modules without a tags variable have
+// nothing to propagate and are not checked.
+func CheckTags(m *Module) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	if m.Lookup("var.tags") == nil {
+		return diagnostics
+	}
+
+	locals := m.Locals()
+	for _, block := range m.BlocksOfType("resource") {
+		if len(block.Labels) != 2 || !IsTaggable(block.Labels[0]) {
+			continue
+		}
+
+		line := block.Range.Start.Line
+		message := ""
+		attr, ok := block.Body.Attributes["tags"]
+		if !ok {
+			message = "does not set tags, so var.tags is dropped"
+		} else {
+			line = attr.SrcRange.Start.Line
+			switch tagPropagationOf(attr.Expr, locals, map[string]bool{}) {
+			case tagsDropped:
+				message = "sets tags without var.tags"
+			case tagsPartial:
+				message = "only uses individual keys of var.tags instead of merging all of it"
+			}
+		}
+		if message == "" {
+			continue
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			File:    filepath.Join(m.Dir, block.File),
+			Line:    line,
+			Message: fmt.Sprintf("%s %s", block.Address(), message),
+		})
+	}
+	return diagnostics
+}
+
+// tagPropagationOf classifies a tags expression. merge() passes var.tags on
+// if any of its arguments does, a conditional only if both branches do, and
+// any other use of var.tags, such as var.tags["Name"] or lookup(var.tags, ...),
+// is partial.
+func tagPropagationOf(expr hclsyntax.Expression, locals map[string]*hclsyntax.Attribute, visiting map[string]bool) tagPropagation {
+	switch e := expr.(type) {
+	case *hclsyntax.ScopeTraversalExpr:
+		if isVarTags(e.Traversal) {
+			return tagsPropagated
+		}
+		if e.Traversal.RootName() == "local" && len(e.Traversal) >= 2 {
+			if step, ok := e.Traversal[1].(hcl.TraverseAttr); ok {
+				if local, ok := locals[step.Name]; ok && !visiting[step.Name] {
+					visiting[step.Name] = true
+					defer delete(visiting, step.Name)
+
+					propagation := tagPropagationOf(local.Expr, locals, visiting)
+					if len(e.Traversal) > 2 && propagation == tagsPropagated {
+						// Only part of a local holding var.tags is used
+						return tagsPartial
+					}
+					return propagation
+				}
+			}
+		}
+
+	case *hclsyntax.FunctionCallExpr:
+		if e.Name == "merge" {
+			result := tagsDropped
+			for _, arg := range e.Args {
+				if propagation := tagPropagationOf(arg, locals, visiting); propagation > result {
+					result = propagation
+				}
+			}
+			return result
+		}
+
+	case *hclsyntax.ConditionalExpr:
+		trueResult := tagPropagationOf(e.TrueResult, locals, visiting)
+		falseResult := tagPropagationOf(e.FalseResult, locals, visiting)
+		if trueResult < falseResult {
+			return trueResult
+		}
+		return falseResult
+
+	case *hclsyntax.ParenthesesExpr:
+		return tagPropagationOf(e.Expression, locals, visiting)
+	}
+
+	if referencesVarTags(expr, locals, visiting) {
+		return tagsPartial
+	}
+	return tagsDropped
+}
+
+// referencesVarTags reports whether an expression uses var.tags anywhere,
+// directly or through a local
+func referencesVarTags(expr hclsyntax.Expression, locals map[string]*hclsyntax.Attribute, visiting map[string]bool) bool {
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() == "var" && len(traversal) >= 2 {
+			if step, ok := traversal[1].(hcl.TraverseAttr); ok && step.Name == "tags" {
+				return true
+			}
+		}
+
+		if traversal.RootName() == "local" && len(traversal) >= 2 {
+			step, ok := traversal[1].(hcl.TraverseAttr)
+			if !ok || visiting[step.Name] {
+				continue
+			}
+			if local, ok := locals[step.Name]; ok {
+				visiting[step.Name] = true
+				found := referencesVarTags(local.Expr, locals, visiting)
+				delete(visiting, step.Name)
+				if found {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isVarTags reports whether a traversal is exactly var.tags
+func isVarTags(traversal hcl.Traversal) bool {
+	if len(traversal) != 2 || traversal.RootName() != "var" {
+		return false
+	}
+	step, ok := traversal[1].(hcl.TraverseAttr)
+	return ok && step.Name == "tags"
+}
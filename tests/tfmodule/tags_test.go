@@ -0,0 +1,57 @@
+package tfmodule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTagsReportsDroppedAndPartialTags(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("testdata", "tags")
+	module, err := Load(dir)
+	require.NoError(t, err)
+
+	messages := []string{}
+	for _, diagnostic := range CheckTags(module) {
+		messages = append(messages, diagnostic.String())
+	}
+
+	mainFile := filepath.Join(dir, "main.tf")
+	assert.Equal(t, []string{
+		mainFile + ":29: aws_eip.untagged does not set tags, so var.tags is dropped",
+		mainFile + ":36: aws_internet_gateway.literal sets tags without var.tags",
+		mainFile + ":44: aws_route_table.indexed only uses individual keys of var.tags instead of merging all of it",
+		mainFile + ":54: aws_nat_gateway.lookup only uses individual keys of var.tags instead of merging all of it",
+		mainFile + ":62: aws_cloudwatch_log_group.conditional only uses individual keys of var.tags instead of merging all of it",
+	}, messages)
+}
+
+func TestCheckTagsSkipsModulesWithoutTagsVariable(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+`), 0o600))
+
+	module, err := Load(dir)
+	require.NoError(t, err)
+	assert.Empty(t, CheckTags(module))
+}
+
+func TestTaggableResourceTypes(t *testing.T) {
+	t.Parallel()
+
+	types := TaggableResourceTypes()
+	assert.Contains(t, types, "aws_cloudwatch_metric_alarm")
+	assert.NotContains(t, types, "aws_route_table_association")
+	assert.IsNonDecreasing(t, types)
+	assert.True(t, IsTaggable("aws_eip"))
+	assert.False(t, IsTaggable("aws_s3_bucket_versioning"))
+}
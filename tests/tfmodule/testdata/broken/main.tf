@@ -0,0 +1,30 @@
+# modules/broken/variables.tf
+
+variable "misplaced" {
+  type = string
+}
+
+resource "aws_s3_bucket" "this" {
+  bucket = var.bucket_name
+  tags   = local.tags
+
+  dynamic "rule" {
+    for_each = var.rules
+    content {
+      id = rule.value.id
+    }
+  }
+}
+
+resource "aws_s3_bucket_policy" "this" {
+  bucket = aws_s3_bucket.missing.id
+  policy = data.aws_iam_policy_document.missing.json
+}
+
+resource "aws_s3_bucket_versioning" "this" {
+  bucket = aws_s3_bucket.this.id
+
+  lifecycle {
+    ignore_changes = [versioning_configuration[0].mfa_delete, expected_bucket_owner]
+  }
+}
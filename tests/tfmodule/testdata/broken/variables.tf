@@ -0,0 +1,9 @@
+# modules/broken/variables.tf
+
+variable "rules" {
+  type = list(any)
+}
+
+variable "rules" {
+  type = list(any)
+}
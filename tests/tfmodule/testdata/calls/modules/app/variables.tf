@@ -0,0 +1,44 @@
+variable "name" {
+  type = string
+}
+
+variable "vpc_id" {
+  type = string
+}
+
+variable "instance_type" {
+  type    = string
+  default = "t3.micro"
+}
+
+variable "instance_count" {
+  type    = number
+  default = 1
+}
+
+variable "enabled" {
+  type    = bool
+  default = true
+}
+
+variable "node_groups" {
+  type = map(object({
+    desired_size = number
+    disk_size    = optional(number)
+    labels       = optional(map(string), {})
+    taints = optional(list(object({
+      key    = string
+      effect = string
+    })), [])
+  }))
+  default = {}
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+variable "settings" {
+  default = null
+}
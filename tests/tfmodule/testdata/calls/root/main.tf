@@ -0,0 +1,55 @@
+module "app" {
+  source = "../modules/app"
+
+  name           = "app"
+  instance_count = "two"
+  instance_typ   = "t3.small"
+  enabled        = "yes"
+
+  node_groups = {
+    general = {
+      desired_size = "2"
+      disk_size    = "large"
+      lables = {
+        role = "general"
+      }
+      taints = [{ key = "dedicated", value = "gpu" }]
+    }
+    spot = {
+      desired_size = var.spot_size
+      disk_size    = 20
+    }
+    (var.extra_group) = {
+      disk_size = 20
+    }
+  }
+
+  tags = {
+    Name = "${var.name}-app"
+  }
+  settings = { anything = [1, "two"] }
+}
+
+module "computed" {
+  source = "../modules/app"
+  count  = 2
+
+  name   = "computed-${count.index}"
+  vpc_id = var.vpc_id
+  node_groups = {
+    for name in var.group_names : name => {
+      desired_size = "many"
+    }
+  }
+}
+
+module "registry" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "5.0.0"
+
+  anything = true
+}
+
+module "missing" {
+  source = "../modules/missing"
+}
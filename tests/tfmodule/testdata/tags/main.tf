@@ -0,0 +1,68 @@
+locals {
+  common_tags = merge(var.tags, { Module = "tags" })
+  name_tag    = { Name = var.tags["Name"] }
+}
+
+resource "aws_vpc" "direct" {
+  cidr_block = "10.0.0.0/16"
+  tags       = var.tags
+}
+
+resource "aws_subnet" "merged" {
+  vpc_id     = aws_vpc.direct.id
+  cidr_block = "10.0.1.0/24"
+
+  tags = merge(
+    var.tags,
+    {
+      Name = "${var.name}-subnet"
+    }
+  )
+}
+
+resource "aws_security_group" "from_local" {
+  name   = var.name
+  vpc_id = aws_vpc.direct.id
+  tags   = local.common_tags
+}
+
+resource "aws_eip" "untagged" {
+  domain = "vpc"
+}
+
+resource "aws_internet_gateway" "literal" {
+  vpc_id = aws_vpc.direct.id
+
+  tags = {
+    Name = var.name
+  }
+}
+
+resource "aws_route_table" "indexed" {
+  vpc_id = aws_vpc.direct.id
+
+  tags = {
+    Name  = var.name
+    Owner = var.tags["Owner"]
+  }
+}
+
+resource "aws_nat_gateway" "lookup" {
+  allocation_id = aws_eip.untagged.id
+  subnet_id     = aws_subnet.merged.id
+
+  tags = merge(
+    { Name = var.name },
+    { Owner = lookup(var.tags, "Owner", "platform") }
+  )
+}
+
+resource "aws_cloudwatch_log_group" "conditional" {
+  name = var.name
+  tags = var.name == "" ? var.tags : local.name_tag
+}
+
+resource "aws_route_table_association" "untaggable" {
+  subnet_id      = aws_subnet.merged.id
+  route_table_id = aws_route_table.indexed.id
+}
@@ -0,0 +1,248 @@
+package varfuzz
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Planner runs terraform plan against the module with a tfvars file and
+// returns its output. It returns an error whenever the plan fails, and the
+// output tells a rejected value apart from other plan errors.
+type Planner func(tfvarsPath string) (string, error)
+
+// Config controls a fuzzing run
+type Config struct {
+	// Seed makes the random cases reproducible
+	Seed int64
+
+	// Iterations is the number of random invalid values tried per variable
+	Iterations int
+
+	// WorkDir holds the tfvars file of every case that is planned
+	WorkDir string
+
+	// OutputDir receives a minimized tfvars file for every failure
+	OutputDir string
+
+	// MinimizeBudget caps the plans spent minimizing each failure
+	MinimizeBudget int
+
+	// Inconclusive, when set, is called with every case whose plan failed
+	// for a reason other than the variable being rejected, such as a
+	// precondition or a provider error. Those cases say nothing about the
+	// value and are not failures.
+	Inconclusive func(testCase Case, output string)
+}
+
+// verdict is how a plan handled a value
+type verdict int
+
+const (
+	accepted verdict = iota
+	rejected
+	inconclusive
+)
+
+// failed reports whether the verdict on a case is the wrong one for its
+// validity
+func (v verdict) failed(valid bool) bool {
+	if valid {
+		return v == rejected
+	}
+	return v == accepted
+}
+
+// Failure is a case terraform handled the wrong way: an invalid value it
+// accepted at plan time, or a valid value it rejected
+type Failure struct {
+	Module    string
+	Case      Case
+	Minimized cty.Value
+
+	// TFVars is the minimized tfvars file that reproduces the failure
+	TFVars string
+}
+
+func (f Failure) String() string {
+	return fmt.Sprintf("%s: %s, reproduce with %s", f.Module, f.problem(), f.TFVars)
+}
+
+func (f Failure) problem() string {
+	problem := "accepted invalid value"
+	if f.Case.Valid {
+		problem = "rejected valid value"
+	}
+	return fmt.Sprintf("var.%s %s %s (%s)", f.Case.Variable, problem, renderValue(f.Minimized), f.Case.Description)
+}
+
+// Rejects reports whether terraform output rejects the named variable,
+// either because the value fails a validation block or because it can't be
+// converted to the declared type
+func Rejects(output string, name string) bool {
+	if !strings.Contains(output, "Invalid value for") {
+		return false
+	}
+	quoted := regexp.QuoteMeta(name)
+	return regexp.MustCompile(`var\.` + quoted + `\b|variable "` + quoted + `"`).MatchString(output)
+}
+
+// Fuzz plans the module once per generated case, with every required
+// variable set to a valid value, and returns the cases whose rejection does
+// not match their validity. It fails if the valid values alone do not plan.
+// Cases whose plan fails for another reason are inconclusive and skipped.
+// Each failure is minimized and written to OutputDir as a tfvars file, and
+// failures that minimize to the same value are reported once.
+func Fuzz(m *tfmodule.Module, plan Planner, config Config) ([]Failure, error) {
+	variables, err := Variables(m)
+	if err != nil {
+		return nil, err
+	}
+
+	base := map[string]cty.Value{}
+	for _, variable := range variables {
+		if variable.Required {
+			base[variable.Name] = ValidValue(variable)
+		}
+	}
+
+	// Every case changes one variable of the base values, so the base values
+	// have to plan for any verdict to mean something
+	basePath := filepath.Join(config.WorkDir, m.Name+"-base.tfvars")
+	if err := WriteTFVars(basePath, base, nil); err != nil {
+		return nil, err
+	}
+	if output, err := plan(basePath); err != nil {
+		return nil, fmt.Errorf("planning %s with valid values for its required variables: %w\n%s", m.Name, err, output)
+	}
+
+	run := 0
+	check := func(name string, value cty.Value) (verdict, string, error) {
+		run++
+		values := copyValues(base)
+		values[name] = value
+
+		path := filepath.Join(config.WorkDir, fmt.Sprintf("%s-%04d.tfvars", m.Name, run))
+		if err := WriteTFVars(path, values, nil); err != nil {
+			return inconclusive, "", err
+		}
+
+		output, err := plan(path)
+		switch {
+		case err == nil:
+			return accepted, output, nil
+		case output == "":
+			return inconclusive, "", fmt.Errorf("planning %s: %w", path, err)
+		case Rejects(output, name):
+			return rejected, output, nil
+		}
+		return inconclusive, output, nil
+	}
+
+	rng := rand.New(rand.NewSource(config.Seed))
+	failures := []Failure{}
+	reported := map[string]bool{}
+	for _, variable := range variables {
+		for _, testCase := range Generate(variable, rng, config.Iterations) {
+			result, output, err := check(variable.Name, testCase.Value)
+			if err != nil {
+				return nil, err
+			}
+			if result == inconclusive && config.Inconclusive != nil {
+				config.Inconclusive(testCase, output)
+			}
+			if !result.failed(testCase.Valid) {
+				continue
+			}
+
+			var minimizeErr error
+			minimized := Minimize(testCase.Value, func(candidate cty.Value) bool {
+				if minimizeErr != nil || variable.Domain.Accepts(candidate) != testCase.Valid {
+					return false
+				}
+				result, _, err := check(variable.Name, candidate)
+				if err != nil {
+					minimizeErr = err
+					return false
+				}
+				return result.failed(testCase.Valid)
+			}, config.MinimizeBudget)
+			if minimizeErr != nil {
+				return nil, minimizeErr
+			}
+
+			// Different cases often shrink to the same value, which only
+			// needs reporting once
+			key := variable.Name + "=" + renderValue(minimized)
+			if reported[key] {
+				continue
+			}
+			reported[key] = true
+
+			failure := Failure{
+				Module:    m.Name,
+				Case:      testCase,
+				Minimized: minimized,
+				TFVars:    filepath.Join(config.OutputDir, fmt.Sprintf("%s-%s-%d.tfvars", m.Name, variable.Name, len(failures)+1)),
+			}
+
+			values := copyValues(base)
+			values[variable.Name] = minimized
+			header := []string{
+				fmt.Sprintf("varfuzz seed %d: modules/%s %s", config.Seed, m.Name, failure.problem()),
+				fmt.Sprintf("terraform -chdir=%s plan -var-file=%s", m.Dir, failure.TFVars),
+			}
+			if err := WriteTFVars(failure.TFVars, values, header); err != nil {
+				return nil, err
+			}
+			failures = append(failures, failure)
+		}
+	}
+	return failures, nil
+}
+
+// WriteTFVars writes values as a tfvars file, in name order, preceded by the
+// header lines as comments
+func WriteTFVars(path string, values map[string]cty.Value, header []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	file := hclwrite.NewEmptyFile()
+	for _, name := range names {
+		file.Body().SetAttributeValue(name, values[name])
+	}
+
+	contents := []byte{}
+	for _, line := range header {
+		contents = append(contents, "# "+line+"\n"...)
+	}
+	contents = append(contents, file.Bytes()...)
+	return os.WriteFile(path, contents, 0o644)
+}
+
+func renderValue(value cty.Value) string {
+	return strings.TrimSpace(string(hclwrite.TokensForValue(value).Bytes()))
+}
+
+func copyValues(values map[string]cty.Value) map[string]cty.Value {
+	copied := make(map[string]cty.Value, len(values))
+	for name, value := range values {
+		copied[name] = value
+	}
+	return copied
+}
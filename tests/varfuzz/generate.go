@@ -0,0 +1,286 @@
+package varfuzz
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Case is one value to plan a variable with
+type Case struct {
+	Variable    string
+	Value       cty.Value
+	Valid       bool
+	Description string
+}
+
+// fixedInvalid are invalid values tried for every variable of a kind, on
+// top of the random ones
+var fixedInvalid = map[Kind][]string{
+	KindCIDR: {"10.0.0.0/33", "10.0.0.256/24", "10.0.0.0", "not-a-cidr"},
+	KindARN:  {"", "arn:aws", "123456789012", "arn:aws:iam::role/varfuzz"},
+}
+
+// validStrings are the valid values tried for every variable of a kind
+var validStrings = map[Kind][]string{
+	KindCIDR: {"10.0.0.0/16", "0.0.0.0/0"},
+	KindARN:  {"arn:aws:iam::123456789012:role/varfuzz", "arn:aws:kms:us-east-1:123456789012:key/00000000-0000-0000-0000-000000000000"},
+}
+
+// Generate returns the cases for a variable: valid members or boundaries of
+// its domain, fixed invalid values such as numbers just outside a range, and
+// iterations random invalid values drawn from rng. Lists, sets and maps get
+// the invalid element alongside a valid one. Variables with no known domain,
+// or whose type is not a string, a number or a collection of them, get no
+// cases.
+func Generate(v Variable, rng *rand.Rand, iterations int) []Case {
+	elementType, wrap := elementOf(v.Type)
+	if v.Domain.Kind == KindAny || wrap == nil {
+		return nil
+	}
+
+	valid := validElements(v.Domain, elementType)
+	if len(valid) == 0 {
+		return nil
+	}
+
+	cases := []Case{}
+	seen := map[string]bool{}
+	add := func(element cty.Value, description string) {
+		value := wrap(valid[0], element)
+		key := value.GoString()
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+
+		cases = append(cases, Case{
+			Variable:    v.Name,
+			Value:       value,
+			Valid:       v.Domain.Accepts(value),
+			Description: description,
+		})
+	}
+
+	for _, element := range valid {
+		add(element, fmt.Sprintf("valid %s", v.Domain.Kind))
+	}
+	for _, element := range invalidElements(v.Domain, elementType) {
+		add(element, fmt.Sprintf("invalid %s", v.Domain.Kind))
+	}
+	for i := 0; i < iterations; i++ {
+		if element, ok := randomInvalidElement(v.Domain, elementType, rng); ok {
+			add(element, fmt.Sprintf("random invalid %s", v.Domain.Kind))
+		}
+	}
+	return cases
+}
+
+// ValidValue returns a value of the variable's type that lies in its
+// domain, used to fill required variables that are not being fuzzed
+func ValidValue(v Variable) cty.Value {
+	return validValue(v.Type, v.Domain, v.Name)
+}
+
+func validValue(valueType cty.Type, domain Domain, name string) cty.Value {
+	switch {
+	case valueType == cty.String || valueType == cty.DynamicPseudoType:
+		if valid := validElements(domain, cty.String); len(valid) > 0 {
+			return valid[0]
+		}
+		return cty.StringVal("varfuzz-" + strings.ReplaceAll(name, "_", "-"))
+	case valueType == cty.Number:
+		if valid := validElements(domain, cty.Number); len(valid) > 0 {
+			return valid[0]
+		}
+		return cty.NumberIntVal(1)
+	case valueType == cty.Bool:
+		return cty.False
+	case valueType.IsListType():
+		return cty.ListVal([]cty.Value{validValue(valueType.ElementType(), domain, name)})
+	case valueType.IsSetType():
+		return cty.SetVal([]cty.Value{validValue(valueType.ElementType(), domain, name)})
+	case valueType.IsMapType():
+		return cty.MapVal(map[string]cty.Value{"varfuzz": validValue(valueType.ElementType(), domain, name)})
+	case valueType.IsObjectType():
+		// Optional attributes are left out and take their defaults
+		attributes := map[string]cty.Value{}
+		for attributeName, attributeType := range valueType.AttributeTypes() {
+			if !valueType.AttributeOptional(attributeName) {
+				attributes[attributeName] = validValue(attributeType, Domain{}, attributeName)
+			}
+		}
+		return cty.ObjectVal(attributes)
+	}
+	return cty.NullVal(valueType)
+}
+
+// elementOf returns the primitive type a domain applies to and a function
+// that places a valid and a candidate element into a value of the variable's
+// type. It returns a nil function for types the fuzzer does not handle.
+func elementOf(valueType cty.Type) (cty.Type, func(valid, element cty.Value) cty.Value) {
+	switch {
+	case valueType == cty.String || valueType == cty.Number:
+		return valueType, func(_, element cty.Value) cty.Value { return element }
+	case valueType.IsListType() && valueType.ElementType().IsPrimitiveType():
+		return valueType.ElementType(), func(valid, element cty.Value) cty.Value {
+			if valid.Equals(element).True() {
+				return cty.ListVal([]cty.Value{element})
+			}
+			return cty.ListVal([]cty.Value{valid, element})
+		}
+	case valueType.IsSetType() && valueType.ElementType().IsPrimitiveType():
+		return valueType.ElementType(), func(valid, element cty.Value) cty.Value {
+			return cty.SetVal([]cty.Value{valid, element})
+		}
+	case valueType.IsMapType() && valueType.ElementType().IsPrimitiveType():
+		return valueType.ElementType(), func(valid, element cty.Value) cty.Value {
+			if valid.Equals(element).True() {
+				return cty.MapVal(map[string]cty.Value{"a": element})
+			}
+			return cty.MapVal(map[string]cty.Value{"a": valid, "b": element})
+		}
+	}
+	return cty.NilType, nil
+}
+
+func validElements(domain Domain, elementType cty.Type) []cty.Value {
+	switch domain.Kind {
+	case KindEnum:
+		members := []cty.Value{}
+		for _, member := range domain.Enum {
+			if member.Type() == elementType {
+				members = append(members, member)
+			}
+		}
+		if len(members) > 2 {
+			// The first and last members are enough to show the list is read
+			members = []cty.Value{members[0], members[len(members)-1]}
+		}
+		return members
+	case KindRange:
+		if elementType == cty.Number {
+			return []cty.Value{cty.NumberIntVal(domain.Min), cty.NumberIntVal(domain.Max)}
+		}
+	case KindCIDR, KindARN:
+		if elementType == cty.String {
+			return stringValues(validStrings[domain.Kind]...)
+		}
+	}
+	return nil
+}
+
+func invalidElements(domain Domain, elementType cty.Type) []cty.Value {
+	switch domain.Kind {
+	case KindEnum:
+		if elementType == cty.String {
+			member := domain.Enum[0].AsString()
+			return stringValues("", swapCase(member), member+"-invalid")
+		}
+		min, max := enumBounds(domain)
+		return numberValues(min-1, max+1)
+	case KindRange:
+		return numberValues(domain.Min-1, domain.Max+1)
+	case KindCIDR, KindARN:
+		return stringValues(fixedInvalid[domain.Kind]...)
+	}
+	return nil
+}
+
+// randomInvalidElement draws a random value outside the domain. It gives up
+// after a few draws that all happen to be valid.
+func randomInvalidElement(domain Domain, elementType cty.Type, rng *rand.Rand) (cty.Value, bool) {
+	for attempt := 0; attempt < 10; attempt++ {
+		var element cty.Value
+		switch domain.Kind {
+		case KindEnum:
+			if elementType == cty.String {
+				element = cty.StringVal(mutate(domain.Enum[rng.Intn(len(domain.Enum))].AsString(), rng))
+			} else {
+				_, max := enumBounds(domain)
+				element = cty.NumberIntVal(rng.Int63n(2*max+2) - 1)
+			}
+		case KindRange:
+			if rng.Intn(2) == 0 {
+				element = cty.NumberIntVal(domain.Min - 1 - rng.Int63n(1000))
+			} else {
+				element = cty.NumberIntVal(domain.Max + 1 + rng.Int63n(1000))
+			}
+		case KindCIDR:
+			element = cty.StringVal(randomCIDR(rng))
+		case KindARN:
+			element = cty.StringVal(mutate(validStrings[KindARN][rng.Intn(len(validStrings[KindARN]))], rng))
+		default:
+			return cty.NilVal, false
+		}
+
+		if !domain.Accepts(element) {
+			return element, true
+		}
+	}
+	return cty.NilVal, false
+}
+
+// randomCIDR returns a CIDR block with one part out of range
+func randomCIDR(rng *rand.Rand) string {
+	octets := []int{rng.Intn(256), rng.Intn(256), rng.Intn(256), rng.Intn(256)}
+	prefix := rng.Intn(33)
+	switch rng.Intn(3) {
+	case 0:
+		octets[rng.Intn(4)] = 256 + rng.Intn(744)
+	case 1:
+		prefix = 33 + rng.Intn(96)
+	case 2:
+		return fmt.Sprintf("%d.%d.%d/%d", octets[0], octets[1], octets[2], prefix)
+	}
+	return fmt.Sprintf("%d.%d.%d.%d/%d", octets[0], octets[1], octets[2], octets[3], prefix)
+}
+
+// mutate makes a small random edit to s: it deletes a character, inserts a
+// character or swaps the case of a character
+func mutate(s string, rng *rand.Rand) string {
+	if s == "" {
+		return string(rune('a' + rng.Intn(26)))
+	}
+
+	runes := []rune(s)
+	i := rng.Intn(len(runes))
+	switch rng.Intn(3) {
+	case 0:
+		return string(append(runes[:i:i], runes[i+1:]...))
+	case 1:
+		inserted := append([]rune{}, runes[:i]...)
+		inserted = append(inserted, []rune(":-_x9")[rng.Intn(5)])
+		return string(append(inserted, runes[i:]...))
+	}
+	runes[i] = []rune(swapCase(string(runes[i])))[0]
+	return string(runes)
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if lower := strings.ToLower(string(r)); lower != string(r) {
+			return []rune(lower)[0]
+		}
+		return []rune(strings.ToUpper(string(r)))[0]
+	}, s)
+}
+
+func enumBounds(domain Domain) (int64, int64) {
+	var min, max int64
+	for i, member := range domain.Enum {
+		if member.Type() != cty.Number {
+			continue
+		}
+		value, _ := member.AsBigFloat().Int64()
+		if i == 0 || value < min {
+			min = value
+		}
+		if i == 0 || value > max {
+			max = value
+		}
+	}
+	return min, max
+}
@@ -0,0 +1,62 @@
+package varfuzz
+
+import (
+	"regexp"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// logRetentionDays are the retention periods CloudWatch Logs accepts
+var logRetentionDays = []int64{0, 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653}
+
+// nameHints give a domain to variables without a recognised validation,
+// based on how the variable is named
+var nameHints = []struct {
+	pattern *regexp.Regexp
+	domain  Domain
+}{
+	{regexp.MustCompile(`(^|_)cidrs?$|(^|_)cidr_blocks$`), Domain{Kind: KindCIDR}},
+	{regexp.MustCompile(`_arns?$`), Domain{Kind: KindARN}},
+	{regexp.MustCompile(`(^|_)port$`), Domain{Kind: KindRange, Min: 1, Max: 65535}},
+	{regexp.MustCompile(`log_retention_days$`), Domain{Kind: KindEnum, Enum: numberValues(logRetentionDays...)}},
+}
+
+// moduleHints give a domain to specific variables whose valid values can't
+// be told from their name, keyed by module and variable name
+var moduleHints = map[string]Domain{
+	"cloudfront.price_class":               {Kind: KindEnum, Enum: stringValues("PriceClass_All", "PriceClass_200", "PriceClass_100")},
+	"cloudfront.ssl_support_method":        {Kind: KindEnum, Enum: stringValues("sni-only", "vip", "static-ip")},
+	"elasticache.az_mode":                  {Kind: KindEnum, Enum: stringValues("single-az", "cross-az")},
+	"rds.backup_retention_period":          {Kind: KindRange, Min: 0, Max: 35},
+	"elasticache.snapshot_retention_limit": {Kind: KindRange, Min: 0, Max: 35},
+}
+
+// hintedDomain returns the bundled domain of a module variable, or KindAny
+// if there is none
+func hintedDomain(module string, name string) Domain {
+	if domain, ok := moduleHints[module+"."+name]; ok {
+		return domain
+	}
+	for _, hint := range nameHints {
+		if hint.pattern.MatchString(name) {
+			return hint.domain
+		}
+	}
+	return Domain{Kind: KindAny}
+}
+
+func stringValues(values ...string) []cty.Value {
+	members := make([]cty.Value, len(values))
+	for i, value := range values {
+		members[i] = cty.StringVal(value)
+	}
+	return members
+}
+
+func numberValues(values ...int64) []cty.Value {
+	members := make([]cty.Value, len(values))
+	for i, value := range values {
+		members[i] = cty.NumberIntVal(value)
+	}
+	return members
+}
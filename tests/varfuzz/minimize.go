@@ -0,0 +1,134 @@
+package varfuzz
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Minimize shrinks a failing value while fails keeps reporting true, so the
+// reproduction holds as little as possible. It first drops elements of
+// lists, sets and maps, then removes runs of characters from strings.
+// budget caps how many times fails is called, since each call is usually a
+// terraform plan.
+func Minimize(value cty.Value, fails func(cty.Value) bool, budget int) cty.Value {
+	calls := 0
+	try := func(candidate cty.Value) bool {
+		if calls >= budget {
+			return false
+		}
+		calls++
+		return fails(candidate)
+	}
+
+	value = dropElements(value, try)
+	return shrinkStrings(value, try)
+}
+
+func dropElements(value cty.Value, try func(cty.Value) bool) cty.Value {
+	if !isCollection(value) {
+		return value
+	}
+
+	for removed := true; removed && value.LengthInt() > 1; {
+		removed = false
+		elements := elementsOf(value)
+		for i := range elements {
+			rest := append(append([]elementEntry{}, elements[:i]...), elements[i+1:]...)
+			candidate := rebuild(value, rest)
+			if try(candidate) {
+				value = candidate
+				removed = true
+				break
+			}
+		}
+	}
+	return value
+}
+
+func shrinkStrings(value cty.Value, try func(cty.Value) bool) cty.Value {
+	if value.Type() == cty.String && !value.IsNull() {
+		return shrinkString(value.AsString(), func(s string) bool { return try(cty.StringVal(s)) })
+	}
+	if !isCollection(value) {
+		return value
+	}
+
+	elements := elementsOf(value)
+	for i := range elements {
+		if elements[i].value.Type() != cty.String {
+			continue
+		}
+		elements[i].value = shrinkString(elements[i].value.AsString(), func(s string) bool {
+			candidate := append([]elementEntry{}, elements...)
+			candidate[i].value = cty.StringVal(s)
+			return try(rebuild(value, candidate))
+		})
+	}
+	return rebuild(value, elements)
+}
+
+// shrinkString removes runs of characters, halving the run length each time
+// no run of the current length can be removed
+func shrinkString(s string, try func(string) bool) cty.Value {
+	runes := []rune(s)
+	for size := len(runes) / 2; size >= 1; size /= 2 {
+		for start := 0; start+size <= len(runes); {
+			candidate := append(append([]rune{}, runes[:start]...), runes[start+size:]...)
+			if try(string(candidate)) {
+				runes = candidate
+				continue
+			}
+			start += size
+		}
+	}
+	return cty.StringVal(string(runes))
+}
+
+type elementEntry struct {
+	key   cty.Value
+	value cty.Value
+}
+
+func isCollection(value cty.Value) bool {
+	valueType := value.Type()
+	return !value.IsNull() && (valueType.IsListType() || valueType.IsSetType() || valueType.IsMapType())
+}
+
+func elementsOf(value cty.Value) []elementEntry {
+	elements := []elementEntry{}
+	for it := value.ElementIterator(); it.Next(); {
+		key, element := it.Element()
+		elements = append(elements, elementEntry{key: key, value: element})
+	}
+	return elements
+}
+
+// rebuild makes a collection of the same kind as value from elements
+func rebuild(value cty.Value, elements []elementEntry) cty.Value {
+	valueType := value.Type()
+	if len(elements) == 0 {
+		switch {
+		case valueType.IsListType():
+			return cty.ListValEmpty(valueType.ElementType())
+		case valueType.IsSetType():
+			return cty.SetValEmpty(valueType.ElementType())
+		}
+		return cty.MapValEmpty(valueType.ElementType())
+	}
+
+	if valueType.IsMapType() {
+		values := map[string]cty.Value{}
+		for _, element := range elements {
+			values[element.key.AsString()] = element.value
+		}
+		return cty.MapVal(values)
+	}
+
+	values := make([]cty.Value, len(elements))
+	for i, element := range elements {
+		values[i] = element.value
+	}
+	if valueType.IsSetType() {
+		return cty.SetVal(values)
+	}
+	return cty.ListVal(values)
+}
@@ -0,0 +1,58 @@
+variable "name" {
+  description = "Required, with no known domain"
+  type        = string
+}
+
+variable "settings" {
+  description = "Required object with an optional attribute"
+  type = object({
+    size = number
+    mode = optional(string, "fast")
+  })
+}
+
+variable "engine" {
+  description = "Validated enum"
+  type        = string
+  default     = "redis"
+  validation {
+    condition     = contains(["redis", "memcached"], var.engine)
+    error_message = "Engine must be redis or memcached."
+  }
+}
+
+variable "port" {
+  description = "Validated nullable range"
+  type        = number
+  default     = null
+  validation {
+    condition     = var.port == null ? true : var.port >= 1 && var.port <= 65535
+    error_message = "Port must be between 1 and 65535."
+  }
+}
+
+variable "subnet_cidrs" {
+  description = "Validated list of CIDR blocks"
+  type        = list(string)
+  default     = []
+  validation {
+    condition     = alltrue([for cidr in var.subnet_cidrs : can(cidrhost(cidr, 0))])
+    error_message = "Subnet CIDRs must all be valid CIDR blocks."
+  }
+}
+
+variable "allowed_cidrs" {
+  description = "CIDR blocks by name, but never validated"
+  type        = list(string)
+  default     = []
+}
+
+variable "topic_arn" {
+  description = "Validated nullable ARN"
+  type        = string
+  default     = null
+  validation {
+    condition     = var.topic_arn == null ? true : can(regex("^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$", var.topic_arn))
+    error_message = "Topic ARN must be a valid ARN."
+  }
+}
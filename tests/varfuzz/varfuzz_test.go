@@ -0,0 +1,294 @@
+package varfuzz
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestVariablesReadDomainsFromValidations(t *testing.T) {
+	t.Parallel()
+
+	module, err := tfmodule.Load(filepath.Join("testdata", "module"))
+	require.NoError(t, err)
+	variables, err := Variables(module)
+	require.NoError(t, err)
+
+	summary := map[string]string{}
+	for _, variable := range variables {
+		summary[variable.Name] = fmt.Sprintf("%s %s required=%t validated=%t", variable.Type.FriendlyName(), variable.Domain.Kind, variable.Required, variable.Validated)
+	}
+	assert.Equal(t, map[string]string{
+		"allowed_cidrs": "list of string CIDR required=false validated=false",
+		"engine":        "string enum required=false validated=true",
+		"name":          "string any required=true validated=false",
+		"port":          "number range required=false validated=true",
+		"settings":      "object any required=true validated=false",
+		"subnet_cidrs":  "list of string CIDR required=false validated=true",
+		"topic_arn":     "string ARN required=false validated=true",
+	}, summary)
+
+	for _, variable := range variables {
+		if variable.Name == "port" {
+			assert.Equal(t, int64(1), variable.Domain.Min)
+			assert.Equal(t, int64(65535), variable.Domain.Max)
+		}
+	}
+}
+
+func TestGenerateLabelsCasesByDomain(t *testing.T) {
+	t.Parallel()
+
+	variable := Variable{
+		Name:   "engine",
+		Type:   cty.String,
+		Domain: Domain{Kind: KindEnum, Enum: stringValues("redis", "memcached")},
+	}
+
+	cases := Generate(variable, rand.New(rand.NewSource(1)), 5)
+	require.NotEmpty(t, cases)
+
+	valid := []string{}
+	for _, testCase := range cases {
+		assert.Equal(t, variable.Domain.Accepts(testCase.Value), testCase.Valid, testCase.Value.GoString())
+		if testCase.Valid {
+			valid = append(valid, testCase.Value.AsString())
+		}
+	}
+	assert.Equal(t, []string{"redis", "memcached"}, valid)
+	assert.Greater(t, len(cases), len(valid)+3, "expected fixed and random invalid values")
+
+	// The same seed yields the same cases
+	assert.Equal(t, cases, Generate(variable, rand.New(rand.NewSource(1)), 5))
+}
+
+func TestGenerateWrapsCollections(t *testing.T) {
+	t.Parallel()
+
+	variable := Variable{Name: "subnet_cidrs", Type: cty.List(cty.String), Domain: Domain{Kind: KindCIDR}}
+
+	for _, testCase := range Generate(variable, rand.New(rand.NewSource(1)), 3) {
+		require.True(t, testCase.Value.Type().IsListType())
+		first := testCase.Value.Index(cty.NumberIntVal(0))
+		assert.True(t, variable.Domain.Accepts(first), "the first element is always valid")
+	}
+}
+
+func TestValidValue(t *testing.T) {
+	t.Parallel()
+
+	module, err := tfmodule.Load(filepath.Join("testdata", "module"))
+	require.NoError(t, err)
+	variables, err := Variables(module)
+	require.NoError(t, err)
+
+	values := map[string]string{}
+	for _, variable := range variables {
+		values[variable.Name] = renderValue(ValidValue(variable))
+	}
+	assert.Equal(t, `"varfuzz-name"`, values["name"])
+	assert.Equal(t, `"redis"`, values["engine"])
+	assert.Equal(t, "1", values["port"])
+	assert.Equal(t, `["10.0.0.0/16"]`, values["allowed_cidrs"])
+	assert.Equal(t, "{\n  size = 1\n}", values["settings"])
+}
+
+func TestMinimizeDropsElementsAndShrinksStrings(t *testing.T) {
+	t.Parallel()
+
+	value := cty.ListVal([]cty.Value{cty.StringVal("ok"), cty.StringVal("a-bad-value"), cty.StringVal("fine")})
+	calls := 0
+	minimized := Minimize(value, func(candidate cty.Value) bool {
+		calls++
+		for it := candidate.ElementIterator(); it.Next(); {
+			_, element := it.Element()
+			if strings.Contains(element.AsString(), "bad") {
+				return true
+			}
+		}
+		return false
+	}, 100)
+
+	assert.Equal(t, cty.ListVal([]cty.Value{cty.StringVal("bad")}), minimized)
+	assert.LessOrEqual(t, calls, 100)
+}
+
+func TestMinimizeRespectsBudget(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	minimized := Minimize(cty.StringVal("abcdefgh"), func(cty.Value) bool {
+		calls++
+		return true
+	}, 1)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, cty.StringVal("efgh"), minimized)
+}
+
+func TestRejects(t *testing.T) {
+	t.Parallel()
+
+	validation := `Error: Invalid value for variable
+
+  on variables.tf line 14:
+  14: variable "engine" {
+    ├────────────────
+    │ var.engine is "REDIS"
+
+Engine must be redis or memcached.`
+	conversion := `Error: Invalid value for input variable
+
+The given value is not suitable for var.port declared at variables.tf:24,1-16:
+a number is required.`
+
+	assert.True(t, Rejects(validation, "engine"))
+	assert.True(t, Rejects(conversion, "port"))
+	assert.False(t, Rejects(validation, "port"))
+	assert.False(t, Rejects(`Error: No valid credential sources found`, "engine"))
+	assert.False(t, Rejects(strings.ReplaceAll(conversion, "var.port", "var.port_range"), "port"))
+}
+
+func TestFuzzReportsUnvalidatedVariables(t *testing.T) {
+	t.Parallel()
+
+	module, err := tfmodule.Load(filepath.Join("testdata", "module"))
+	require.NoError(t, err)
+
+	outputDir := filepath.Join(t.TempDir(), "failures")
+	config := Config{
+		Seed:           42,
+		Iterations:     3,
+		WorkDir:        t.TempDir(),
+		OutputDir:      outputDir,
+		MinimizeBudget: 50,
+	}
+
+	failures, err := Fuzz(module, fakePlanner(t, module), config)
+	require.NoError(t, err)
+
+	require.Len(t, failures, 1, "failures: %v", failures)
+	failure := failures[0]
+	assert.Equal(t, "allowed_cidrs", failure.Case.Variable)
+	assert.False(t, failure.Case.Valid)
+	assert.Equal(t, cty.ListVal([]cty.Value{cty.StringVal("")}), failure.Minimized)
+	assert.Equal(t, filepath.Join(outputDir, "module-allowed_cidrs-1.tfvars"), failure.TFVars)
+
+	contents, err := os.ReadFile(failure.TFVars)
+	require.NoError(t, err)
+	assert.Equal(t, `# varfuzz seed 42: modules/module var.allowed_cidrs accepted invalid value [""] (invalid CIDR)
+# terraform -chdir=`+module.Dir+` plan -var-file=`+failure.TFVars+`
+allowed_cidrs = [""]
+name          = "varfuzz-name"
+settings = {
+  size = 1
+}
+`, string(contents))
+
+	// The same seed finds the same failures
+	again, err := Fuzz(module, fakePlanner(t, module), config)
+	require.NoError(t, err)
+	assert.Equal(t, failures, again)
+}
+
+func TestFuzzFailsWithoutPlanOutput(t *testing.T) {
+	t.Parallel()
+
+	module, err := tfmodule.Load(filepath.Join("testdata", "module"))
+	require.NoError(t, err)
+
+	_, err = Fuzz(module, func(string) (string, error) {
+		return "", fmt.Errorf("terraform not found")
+	}, Config{WorkDir: t.TempDir(), OutputDir: t.TempDir()})
+	assert.ErrorContains(t, err, "terraform not found")
+}
+
+func TestFuzzFailsWhenBaseValuesDoNotPlan(t *testing.T) {
+	t.Parallel()
+
+	module, err := tfmodule.Load(filepath.Join("testdata", "module"))
+	require.NoError(t, err)
+
+	_, err = Fuzz(module, func(string) (string, error) {
+		return "Error: No valid credential sources found", fmt.Errorf("exit status 1")
+	}, Config{WorkDir: t.TempDir(), OutputDir: t.TempDir()})
+	assert.ErrorContains(t, err, "planning module with valid values")
+	assert.ErrorContains(t, err, "No valid credential sources found")
+}
+
+func TestFuzzSkipsInconclusivePlans(t *testing.T) {
+	t.Parallel()
+
+	module, err := tfmodule.Load(filepath.Join("testdata", "module"))
+	require.NoError(t, err)
+
+	// Only the base values plan, every case fails for an unrelated reason
+	planner := func(path string) (string, error) {
+		if strings.HasSuffix(path, "-base.tfvars") {
+			return "No changes.", nil
+		}
+		return "Error: No valid credential sources found", fmt.Errorf("exit status 1")
+	}
+
+	inconclusive := 0
+	failures, err := Fuzz(module, planner, Config{
+		Seed:           42,
+		Iterations:     3,
+		WorkDir:        t.TempDir(),
+		OutputDir:      t.TempDir(),
+		MinimizeBudget: 50,
+		Inconclusive: func(Case, string) {
+			inconclusive++
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+	assert.Positive(t, inconclusive)
+}
+
+// fakePlanner stands in for terraform plan: it rejects values of validated
+// variables that fall outside their domain, the way a validation block would
+func fakePlanner(t *testing.T, module *tfmodule.Module) Planner {
+	variables, err := Variables(module)
+	require.NoError(t, err)
+
+	return func(path string) (string, error) {
+		file, diags := hclparse.NewParser().ParseHCLFile(path)
+		if diags.HasErrors() {
+			return "", diags
+		}
+		attributes, diags := file.Body.JustAttributes()
+		if diags.HasErrors() {
+			return "", diags
+		}
+
+		output := []string{}
+		for _, variable := range variables {
+			attribute, ok := attributes[variable.Name]
+			if !ok || !variable.Validated {
+				continue
+			}
+			value, diags := attribute.Expr.Value(nil)
+			if diags.HasErrors() {
+				return "", diags
+			}
+			if !variable.Domain.Accepts(value) {
+				output = append(output, fmt.Sprintf("Error: Invalid value for variable\n\n  on variables.tf line 1:\n   1: variable %q {", variable.Name))
+			}
+		}
+
+		if len(output) == 0 {
+			return "No changes.", nil
+		}
+		return strings.Join(output, "\n\n"), fmt.Errorf("exit status 1")
+	}
+}
@@ -0,0 +1,263 @@
+// Package varfuzz generates valid and invalid values for module input
+// variables and checks that terraform rejects the invalid ones at plan time,
+// instead of leaving them to fail against the AWS API during apply
+package varfuzz
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Kind is the shape of a variable's valid values
+type Kind int
+
+const (
+	// KindAny is any value of the declared type. No invalid values are
+	// generated for it.
+	KindAny Kind = iota
+	KindEnum
+	KindRange
+	KindCIDR
+	KindARN
+)
+
+var kindNames = map[Kind]string{
+	KindAny:   "any",
+	KindEnum:  "enum",
+	KindRange: "range",
+	KindCIDR:  "CIDR",
+	KindARN:   "ARN",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// arnPattern matches ARNs of resources owned by an account, which is every
+// ARN the modules take as input
+var arnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]{12}:.+$`)
+
+// Domain is the set of valid values of a variable, or of each element when
+// the variable is a list, set or map
+type Domain struct {
+	Kind Kind
+	Enum []cty.Value
+	Min  int64
+	Max  int64
+}
+
+// Accepts reports whether a value lies in the domain. Null is always
+// accepted, since it means the variable was left unset.
+func (d Domain) Accepts(value cty.Value) bool {
+	if value.IsNull() || !value.IsKnown() {
+		return true
+	}
+
+	valueType := value.Type()
+	if valueType.IsListType() || valueType.IsSetType() || valueType.IsMapType() || valueType.IsTupleType() {
+		for it := value.ElementIterator(); it.Next(); {
+			_, element := it.Element()
+			if !d.Accepts(element) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch d.Kind {
+	case KindEnum:
+		for _, member := range d.Enum {
+			if member.Type().Equals(valueType) && member.Equals(value).True() {
+				return true
+			}
+		}
+		return false
+	case KindRange:
+		if valueType != cty.Number {
+			return false
+		}
+		number := value.AsBigFloat()
+		return number.IsInt() && number.Cmp(new(big.Float).SetInt64(d.Min)) >= 0 && number.Cmp(new(big.Float).SetInt64(d.Max)) <= 0
+	case KindCIDR:
+		if valueType != cty.String {
+			return false
+		}
+		_, _, err := net.ParseCIDR(value.AsString())
+		return err == nil
+	case KindARN:
+		return valueType == cty.String && arnPattern.MatchString(value.AsString())
+	}
+	return true
+}
+
+// Variable is a module input variable and what is known about its valid
+// values
+type Variable struct {
+	Name     string
+	Type     cty.Type
+	Required bool
+	Domain   Domain
+
+	// Validated is true when the variable declares validation blocks, and
+	// false when its domain comes from the bundled naming hints
+	Validated bool
+
+	// Block is the variable's declaration
+	Block *tfmodule.Block
+}
+
+// Variables returns the module's variables in name order. Each domain is
+// read from a contains() or range validation when there is one, and from
+// the bundled hints otherwise.
+func Variables(m *tfmodule.Module) ([]Variable, error) {
+	variables := []Variable{}
+	for _, block := range m.BlocksOfType("variable") {
+		variable := Variable{
+			Name:  block.Labels[0],
+			Type:  cty.DynamicPseudoType,
+			Block: block,
+		}
+
+		if attr, ok := block.Body.Attributes["type"]; ok {
+			variableType, _, diags := typeexpr.TypeConstraintWithDefaults(attr.Expr)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("%s: %s", block.Pos(m.Dir), diags.Error())
+			}
+			variable.Type = variableType
+		}
+
+		_, hasDefault := block.Body.Attributes["default"]
+		variable.Required = !hasDefault
+
+		for _, nested := range block.Body.Blocks {
+			if nested.Type != "validation" {
+				continue
+			}
+			variable.Validated = true
+			if condition, ok := nested.Body.Attributes["condition"]; ok {
+				if domain, ok := validationDomain(condition.Expr); ok {
+					variable.Domain = domain
+				}
+			}
+		}
+		if variable.Domain.Kind == KindAny {
+			variable.Domain = hintedDomain(m.Name, variable.Name)
+		}
+
+		variables = append(variables, variable)
+	}
+
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Name < variables[j].Name
+	})
+	return variables, nil
+}
+
+// validationDomain recognises the validation conditions the modules use:
+//
+//	contains([...], var.name)
+//	var.name >= min && var.name <= max
+//	can(cidrhost(var.name, 0))
+//	can(regex("^arn:...", var.name))
+//
+// optionally guarded by var.name == null ? true : ..., and
+// alltrue([for ... : <one of the above>]) over collections of them
+func validationDomain(expr hclsyntax.Expression) (Domain, bool) {
+	switch e := expr.(type) {
+	case *hclsyntax.FunctionCallExpr:
+		switch e.Name {
+		case "contains":
+			if len(e.Args) == 2 {
+				if values, diags := e.Args[0].Value(nil); !diags.HasErrors() && values.CanIterateElements() {
+					domain := Domain{Kind: KindEnum}
+					for it := values.ElementIterator(); it.Next(); {
+						_, member := it.Element()
+						domain.Enum = append(domain.Enum, member)
+					}
+					return domain, true
+				}
+			}
+		case "alltrue":
+			if len(e.Args) == 1 {
+				if forExpr, ok := e.Args[0].(*hclsyntax.ForExpr); ok {
+					return validationDomain(forExpr.ValExpr)
+				}
+			}
+		case "can":
+			if len(e.Args) == 1 {
+				if call, ok := e.Args[0].(*hclsyntax.FunctionCallExpr); ok {
+					switch call.Name {
+					case "cidrhost", "cidrnetmask":
+						return Domain{Kind: KindCIDR}, true
+					case "regex":
+						if len(call.Args) == 2 {
+							if pattern, diags := call.Args[0].Value(nil); !diags.HasErrors() && pattern.Type() == cty.String {
+								if regexp.MustCompile(`^\^arn:`).MatchString(pattern.AsString()) {
+									return Domain{Kind: KindARN}, true
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+
+	case *hclsyntax.BinaryOpExpr:
+		if e.Op == hclsyntax.OpLogicalAnd {
+			min, minOK := bound(e.LHS, hclsyntax.OpGreaterThanOrEqual)
+			max, maxOK := bound(e.RHS, hclsyntax.OpLessThanOrEqual)
+			if minOK && maxOK {
+				return Domain{Kind: KindRange, Min: min, Max: max}, true
+			}
+		}
+
+	case *hclsyntax.ConditionalExpr:
+		// var.name == null ? true : <condition>
+		if isNullCheck(e.Condition) {
+			if value, diags := e.TrueResult.Value(nil); !diags.HasErrors() && value.Type() == cty.Bool && value.True() {
+				return validationDomain(e.FalseResult)
+			}
+		}
+
+	case *hclsyntax.ParenthesesExpr:
+		return validationDomain(e.Expression)
+	}
+
+	return Domain{}, false
+}
+
+// isNullCheck reports whether expr compares something with null
+func isNullCheck(expr hclsyntax.Expression) bool {
+	binary, ok := expr.(*hclsyntax.BinaryOpExpr)
+	if !ok || binary.Op != hclsyntax.OpEqual {
+		return false
+	}
+	value, diags := binary.RHS.Value(nil)
+	return !diags.HasErrors() && value.IsNull()
+}
+
+// bound reads the constant of a comparison such as var.name >= 1
+func bound(expr hclsyntax.Expression, op *hclsyntax.Operation) (int64, bool) {
+	binary, ok := expr.(*hclsyntax.BinaryOpExpr)
+	if !ok || binary.Op != op {
+		return 0, false
+	}
+	if _, ok := binary.LHS.(*hclsyntax.ScopeTraversalExpr); !ok {
+		return 0, false
+	}
+
+	value, diags := binary.RHS.Value(nil)
+	if diags.HasErrors() || value.Type() != cty.Number {
+		return 0, false
+	}
+	number, accuracy := value.AsBigFloat().Int64()
+	return number, accuracy == big.Exact
+}
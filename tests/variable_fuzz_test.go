@@ -0,0 +1,100 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/jaaparjazzery/aws-terraform-tests/tfmodule"
+	"github.com/jaaparjazzery/aws-terraform-tests/varfuzz"
+	"github.com/stretchr/testify/require"
+)
+
+// TestModuleVariableValidation plans every module with generated valid and
+// invalid values for each variable whose valid values are known, and checks
+// that terraform rejects exactly the invalid ones at plan time. Set
+// FUZZ_SEED to replay a run, FUZZ_ITERATIONS for the number of random values
+// per variable and FUZZ_OUTPUT_DIR to choose where the minimized tfvars of
+// each failure are written.
+func TestModuleVariableValidation(t *testing.T) {
+	t.Parallel()
+
+	seed := getFuzzSeed(t)
+	iterations := getFuzzIterations(t)
+	outputDir := os.Getenv("FUZZ_OUTPUT_DIR")
+	if outputDir == "" {
+		outputDir = filepath.Join(os.TempDir(), fmt.Sprintf("varfuzz-%d", seed))
+	}
+	// The reproduction command runs terraform from the module directory
+	outputDir, err := filepath.Abs(outputDir)
+	require.NoError(t, err)
+	t.Logf("varfuzz seed %d, %d random values per variable, failures written to %s", seed, iterations, outputDir)
+
+	modules, err := tfmodule.LoadAll("../modules")
+	require.NoError(t, err)
+
+	for name, module := range modules {
+		name, module := name, module
+		if len(module.BlocksOfType("variable")) == 0 {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			region := "us-east-1"
+			terraformOptions := createModuleOptions(t, name, region, nil)
+			terraform.Init(t, terraformOptions)
+
+			planner := func(tfvarsPath string) (string, error) {
+				options := *terraformOptions
+				options.VarFiles = []string{tfvarsPath}
+				return terraform.PlanE(t, &options)
+			}
+
+			failures, err := varfuzz.Fuzz(module, planner, varfuzz.Config{
+				Seed:           seed,
+				Iterations:     iterations,
+				WorkDir:        t.TempDir(),
+				OutputDir:      outputDir,
+				MinimizeBudget: 16,
+				Inconclusive: func(testCase varfuzz.Case, output string) {
+					t.Logf("%s: var.%s case (%s) failed to plan for another reason, skipped:\n%s", module.Name, testCase.Variable, testCase.Description, output)
+				},
+			})
+			require.NoError(t, err)
+
+			for _, failure := range failures {
+				t.Error(failure.String())
+			}
+		})
+	}
+}
+
+// getFuzzSeed returns FUZZ_SEED, or a seed based on the current time
+func getFuzzSeed(t *testing.T) int64 {
+	value := os.Getenv("FUZZ_SEED")
+	if value == "" {
+		return time.Now().UnixNano()
+	}
+
+	seed, err := strconv.ParseInt(value, 10, 64)
+	require.NoError(t, err, "FUZZ_SEED must be an integer")
+	return seed
+}
+
+// getFuzzIterations returns FUZZ_ITERATIONS, defaulting to 2
+func getFuzzIterations(t *testing.T) int {
+	value := os.Getenv("FUZZ_ITERATIONS")
+	if value == "" {
+		return 2
+	}
+
+	iterations, err := strconv.Atoi(value)
+	require.NoError(t, err, "FUZZ_ITERATIONS must be an integer")
+	return iterations
+}
@@ -1,25 +1,23 @@
 package test
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestVPCCreation(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../terraform/vpc",
-		Vars: map[string]interface{}{
-			"vpc_cidr":           "10.0.0.0/16",
-			"environment":        "test",
-			"availability_zones": []string{"us-east-1a", "us-east-1b"},
-		},
-	})
+	vpcName := fmt.Sprintf("test-vpc-%d", time.Now().Unix())
+	region := "us-east-1"
+	terraformOptions := createModuleOptions(t, "vpc", region, createVPCVars(t, vpcName, "10.0.0.0/16", []string{"us-east-1a", "us-east-1b"}))
 
 	defer terraform.Destroy(t, terraformOptions)
 
@@ -28,7 +26,6 @@ func TestVPCCreation(t *testing.T) {
 	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
 	assert.NotEmpty(t, vpcID)
 
-	region := "us-east-1"
 	ec2Client := createEC2Client(t, region)
 
 	vpc := getVPC(t, ec2Client, vpcID)
@@ -52,21 +49,15 @@ func TestVPCWithCustomCIDR(t *testing.T) {
 	t.Parallel()
 
 	customCIDR := "172.16.0.0/16"
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../terraform/vpc",
-		Vars: map[string]interface{}{
-			"vpc_cidr":           customCIDR,
-			"environment":        "test-custom",
-			"availability_zones": []string{"us-west-2a", "us-west-2b"},
-		},
-	})
+	vpcName := fmt.Sprintf("test-vpc-custom-%d", time.Now().Unix())
+	region := "us-west-2"
+	terraformOptions := createModuleOptions(t, "vpc", region, createVPCVars(t, vpcName, customCIDR, []string{"us-west-2a", "us-west-2b"}))
 
 	defer terraform.Destroy(t, terraformOptions)
 
 	terraform.InitAndApply(t, terraformOptions)
 
 	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
-	region := "us-west-2"
 	ec2Client := createEC2Client(t, region)
 
 	vpc := getVPC(t, ec2Client, vpcID)
@@ -76,35 +67,140 @@ func TestVPCWithCustomCIDR(t *testing.T) {
 func TestVPCTags(t *testing.T) {
 	t.Parallel()
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: "../terraform/vpc",
-		Vars: map[string]interface{}{
-			"vpc_cidr":           "10.1.0.0/16",
-			"environment":        "test-tags",
-			"availability_zones": []string{"us-east-1a", "us-east-1b"},
-			"tags": map[string]string{
-				"Project": "Infrastructure-Test",
-				"Owner":   "DevOps-Team",
-			},
-		},
-	})
+	vpcName := fmt.Sprintf("test-vpc-tags-%d", time.Now().Unix())
+	region := "us-east-1"
+	vars := createVPCVars(t, vpcName, "10.1.0.0/16", []string{"us-east-1a", "us-east-1b"})
+	vars["tags"] = map[string]string{
+		"Environment": "test-tags",
+		"Project":     "Infrastructure-Test",
+		"Owner":       "DevOps-Team",
+	}
+	terraformOptions := createModuleOptions(t, "vpc", region, vars)
 
 	defer terraform.Destroy(t, terraformOptions)
 
 	terraform.InitAndApply(t, terraformOptions)
 
 	vpcID := terraform.Output(t, terraformOptions, "vpc_id")
-	region := "us-east-1"
 	ec2Client := createEC2Client(t, region)
 
 	vpc := getVPC(t, ec2Client, vpcID)
 	tags := convertEC2TagsToMap(vpc.Tags)
 
+	assert.Equal(t, vpcName, tags["Name"])
 	assert.Equal(t, "test-tags", tags["Environment"])
 	assert.Equal(t, "Infrastructure-Test", tags["Project"])
 	assert.Equal(t, "DevOps-Team", tags["Owner"])
 }
 
+func TestVPCRouting(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		enableNATGateway bool
+	}{
+		{"nat", true},
+		{"no-nat", false},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			vpcName := fmt.Sprintf("test-vpc-routing-%s-%d", testCase.name, time.Now().Unix())
+			region := "us-east-1"
+
+			vars := createVPCVars(t, vpcName, "10.0.0.0/16", []string{region + "a", region + "b"})
+			vars["enable_nat_gateway"] = testCase.enableNATGateway
+
+			terraformOptions := createModuleOptions(t, "vpc", region, vars)
+
+			defer terraform.Destroy(t, terraformOptions)
+
+			terraform.InitAndApply(t, terraformOptions)
+
+			vpcID := terraform.Output(t, terraformOptions, "vpc_id")
+			igwID := terraform.Output(t, terraformOptions, "internet_gateway_id")
+			publicSubnets := terraform.OutputList(t, terraformOptions, "public_subnet_ids")
+			privateSubnets := terraform.OutputList(t, terraformOptions, "private_subnet_ids")
+			natGatewayIDs := terraform.OutputList(t, terraformOptions, "nat_gateway_ids")
+			publicRouteTableID := terraform.Output(t, terraformOptions, "public_route_table_id")
+			privateRouteTableIDs := terraform.OutputList(t, terraformOptions, "private_route_table_ids")
+
+			ec2Client := createEC2Client(t, region)
+			routeTables := getRouteTables(t, ec2Client, vpcID)
+			subnetAZs := getSubnetAvailabilityZones(t, ec2Client, append(append([]string{}, publicSubnets...), privateSubnets...))
+
+			// Every subnet must be explicitly associated with exactly one
+			// table, otherwise it silently falls back to the main table
+			associations := map[string][]string{}
+			for _, routeTable := range routeTables {
+				for _, association := range routeTable.Associations {
+					if association.SubnetId != nil {
+						associations[*association.SubnetId] = append(associations[*association.SubnetId], *routeTable.RouteTableId)
+					}
+				}
+			}
+			for _, subnetID := range publicSubnets {
+				assert.Equal(t, []string{publicRouteTableID}, associations[subnetID], "route tables of public subnet %s", subnetID)
+			}
+			require.Len(t, privateRouteTableIDs, len(privateSubnets))
+			for _, subnetID := range privateSubnets {
+				if assert.Len(t, associations[subnetID], 1, "route tables of private subnet %s", subnetID) {
+					assert.Contains(t, privateRouteTableIDs, associations[subnetID][0], "private subnet %s is associated with a non-private table", subnetID)
+				}
+			}
+
+			publicRouteTable, ok := routeTables[publicRouteTableID]
+			require.True(t, ok, "public route table %s not found in VPC %s", publicRouteTableID, vpcID)
+			publicDefaultRoute := getDefaultRoute(publicRouteTable)
+			require.NotNil(t, publicDefaultRoute, "public route table has no 0.0.0.0/0 route")
+			assert.Equal(t, igwID, aws.StringValue(publicDefaultRoute.GatewayId))
+			assert.Equal(t, "active", aws.StringValue(publicDefaultRoute.State))
+
+			if testCase.enableNATGateway {
+				assert.Len(t, natGatewayIDs, len(publicSubnets))
+			} else {
+				assert.Empty(t, natGatewayIDs)
+			}
+			natGatewayAZs := getNATGatewayAvailabilityZones(t, ec2Client, natGatewayIDs)
+
+			for _, routeTableID := range privateRouteTableIDs {
+				routeTable, ok := routeTables[routeTableID]
+				if !assert.True(t, ok, "private route table %s not found in VPC %s", routeTableID, vpcID) {
+					continue
+				}
+
+				for _, route := range routeTable.Routes {
+					assert.NotEqual(t, igwID, aws.StringValue(route.GatewayId), "private route table %s routes %s to the internet gateway", routeTableID, aws.StringValue(route.DestinationCidrBlock))
+				}
+
+				defaultRoute := getDefaultRoute(routeTable)
+				if !testCase.enableNATGateway {
+					assert.Nil(t, defaultRoute, "private route table %s has a default route without NAT gateways", routeTableID)
+					continue
+				}
+				if !assert.NotNil(t, defaultRoute, "private route table %s has no 0.0.0.0/0 route", routeTableID) {
+					continue
+				}
+
+				natGatewayID := aws.StringValue(defaultRoute.NatGatewayId)
+				natGatewayAZ, ok := natGatewayAZs[natGatewayID]
+				if !assert.True(t, ok, "default route of %s targets %q, not a NAT gateway of this VPC", routeTableID, natGatewayID) {
+					continue
+				}
+				for _, association := range routeTable.Associations {
+					if association.SubnetId != nil {
+						assert.Equal(t, subnetAZs[*association.SubnetId], natGatewayAZ, "subnet %s routes through NAT gateway %s in another AZ", *association.SubnetId, natGatewayID)
+					}
+				}
+			}
+		})
+	}
+}
+
 func createEC2Client(t *testing.T, region string) *ec2.EC2 {
 	sess := createAWSSession(t, region)
 	return ec2.New(sess)
@@ -131,3 +227,77 @@ func convertEC2TagsToMap(tags []*ec2.Tag) map[string]string {
 	}
 	return tagMap
 }
+
+// getRouteTables returns the route tables of a VPC keyed by ID
+func getRouteTables(t *testing.T, client *ec2.EC2, vpcID string) map[string]*ec2.RouteTable {
+	input := &ec2.DescribeRouteTablesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{aws.String(vpcID)},
+			},
+		},
+	}
+
+	routeTables := map[string]*ec2.RouteTable{}
+	err := client.DescribeRouteTablesPages(input, func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+		for _, routeTable := range page.RouteTables {
+			routeTables[aws.StringValue(routeTable.RouteTableId)] = routeTable
+		}
+		return true
+	})
+	require.NoError(t, err)
+
+	return routeTables
+}
+
+// getDefaultRoute returns the IPv4 default route of a route table, or nil if
+// it has none
+func getDefaultRoute(routeTable *ec2.RouteTable) *ec2.Route {
+	for _, route := range routeTable.Routes {
+		if aws.StringValue(route.DestinationCidrBlock) == "0.0.0.0/0" {
+			return route
+		}
+	}
+	return nil
+}
+
+// getSubnetAvailabilityZones returns the availability zone of each subnet
+// keyed by subnet ID
+func getSubnetAvailabilityZones(t *testing.T, client *ec2.EC2, subnetIDs []string) map[string]string {
+	result, err := client.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice(subnetIDs),
+	})
+	require.NoError(t, err)
+
+	zones := map[string]string{}
+	for _, subnet := range result.Subnets {
+		zones[aws.StringValue(subnet.SubnetId)] = aws.StringValue(subnet.AvailabilityZone)
+	}
+	return zones
+}
+
+// getNATGatewayAvailabilityZones returns the availability zone of each NAT
+// gateway keyed by NAT gateway ID. A NAT gateway lives in the AZ of its subnet.
+func getNATGatewayAvailabilityZones(t *testing.T, client *ec2.EC2, natGatewayIDs []string) map[string]string {
+	zones := map[string]string{}
+	if len(natGatewayIDs) == 0 {
+		return zones
+	}
+
+	result, err := client.DescribeNatGateways(&ec2.DescribeNatGatewaysInput{
+		NatGatewayIds: aws.StringSlice(natGatewayIDs),
+	})
+	require.NoError(t, err)
+
+	subnetIDs := []string{}
+	for _, natGateway := range result.NatGateways {
+		subnetIDs = append(subnetIDs, aws.StringValue(natGateway.SubnetId))
+	}
+	subnetZones := getSubnetAvailabilityZones(t, client, subnetIDs)
+
+	for _, natGateway := range result.NatGateways {
+		zones[aws.StringValue(natGateway.NatGatewayId)] = subnetZones[aws.StringValue(natGateway.SubnetId)]
+	}
+	return zones
+}
@@ -0,0 +1,330 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWAFRuleSets(t *testing.T) {
+	t.Parallel()
+
+	name := fmt.Sprintf("test-waf-%d", time.Now().Unix())
+	partnerIPSetArn := "arn:aws:wafv2:us-east-1:123456789012:regional/ipset/partners/a1b2c3d4-5678-90ab-cdef-EXAMPLE11111"
+
+	region := "us-east-1"
+	plan := planModule(t, "waf", region, map[string]interface{}{
+		"name":  name,
+		"scope": "REGIONAL",
+		"ip_sets": map[string]interface{}{
+			"blocked-ips": map[string]interface{}{
+				"ip_address_version": "IPV4",
+				"addresses":          []string{"192.0.2.0/24", "198.51.100.7/32"},
+			},
+		},
+		"regex_pattern_sets": map[string]interface{}{
+			"bad-paths": map[string]interface{}{
+				"patterns": []string{"^/wp-admin", "\\.php$"},
+			},
+		},
+		"rules": []map[string]interface{}{
+			{
+				"name":            "aws-common",
+				"priority":        10,
+				"override_action": "none",
+				"statement": map[string]interface{}{
+					"managed_rule_group_statement": map[string]interface{}{
+						"name":           "AWSManagedRulesCommonRuleSet",
+						"excluded_rules": []string{"SizeRestrictions_BODY"},
+					},
+				},
+			},
+			{
+				"name":     "rate-limit",
+				"priority": 20,
+				"action":   "block",
+				"statement": map[string]interface{}{
+					"rate_based_statement": map[string]interface{}{
+						"limit": 2000,
+					},
+				},
+			},
+			{
+				"name":     "block-ips",
+				"priority": 30,
+				"action":   "block",
+				"statement": map[string]interface{}{
+					"ip_set_reference_statement": map[string]interface{}{
+						"arn": "blocked-ips",
+					},
+				},
+			},
+			{
+				"name":     "block-paths",
+				"priority": 40,
+				"action":   "block",
+				"statement": map[string]interface{}{
+					"regex_pattern_set_reference_statement": map[string]interface{}{
+						"arn": "bad-paths",
+						"field_to_match": map[string]interface{}{
+							"uri_path": true,
+						},
+						"text_transformations": []map[string]interface{}{
+							{"priority": 0, "type": "LOWERCASE"},
+						},
+					},
+				},
+			},
+			{
+				"name":     "allow-partners",
+				"priority": 50,
+				"action":   "allow",
+				"statement": map[string]interface{}{
+					"ip_set_reference_statement": map[string]interface{}{
+						"arn": partnerIPSetArn,
+					},
+				},
+			},
+		},
+	})
+
+	webACL := getPlannedAttributes(t, plan, "aws_wafv2_web_acl.this")
+	assert.Equal(t, name, webACL["name"])
+	assert.Equal(t, "REGIONAL", webACL["scope"])
+
+	rules := webACL["rule"].([]interface{})
+	require.Len(t, rules, 5)
+
+	priorities := map[float64]string{}
+	for _, r := range rules {
+		rule := r.(map[string]interface{})
+		priority := rule["priority"].(float64)
+		if other, ok := priorities[priority]; ok {
+			t.Errorf("Rules %s and %s share priority %v", other, rule["name"], priority)
+		}
+		priorities[priority] = rule["name"].(string)
+	}
+
+	_, managed := getWAFRule(t, rules, "aws-common")
+	assert.Len(t, managed["override_action"], 1)
+	assert.Empty(t, managed["action"])
+	managedStatement := getWAFStatement(t, managed, "managed_rule_group_statement")
+	assert.Equal(t, "AWSManagedRulesCommonRuleSet", managedStatement["name"])
+	assert.Equal(t, "AWS", managedStatement["vendor_name"])
+	overrides := managedStatement["rule_action_override"].([]interface{})
+	require.Len(t, overrides, 1)
+	assert.Equal(t, "SizeRestrictions_BODY", overrides[0].(map[string]interface{})["name"])
+
+	_, rateLimit := getWAFRule(t, rules, "rate-limit")
+	rateStatement := getWAFStatement(t, rateLimit, "rate_based_statement")
+	assert.EqualValues(t, 2000, rateStatement["limit"])
+	assert.Equal(t, "IP", rateStatement["aggregate_key_type"])
+
+	// Rules that name a set from ip_sets or regex_pattern_sets get the ARN of
+	// the planned set, which is only known after apply
+	ipSet := getPlannedAttributes(t, plan, `aws_wafv2_ip_set.this["blocked-ips"]`)
+	assert.Equal(t, "blocked-ips", ipSet["name"])
+	assert.ElementsMatch(t, []interface{}{"192.0.2.0/24", "198.51.100.7/32"}, ipSet["addresses"])
+
+	blockIPsIndex, blockIPs := getWAFRule(t, rules, "block-ips")
+	assert.Len(t, blockIPs["action"], 1)
+	assert.NotContains(t, getWAFStatement(t, blockIPs, "ip_set_reference_statement"), "arn")
+	assert.Equal(t, true, getWAFUnknownStatement(t, plan, blockIPsIndex, "ip_set_reference_statement")["arn"])
+
+	regexSet := getPlannedAttributes(t, plan, `aws_wafv2_regex_pattern_set.this["bad-paths"]`)
+	assert.Equal(t, "bad-paths", regexSet["name"])
+	assert.Len(t, regexSet["regular_expression"], 2)
+
+	blockPathsIndex, blockPaths := getWAFRule(t, rules, "block-paths")
+	regexStatement := getWAFStatement(t, blockPaths, "regex_pattern_set_reference_statement")
+	assert.NotContains(t, regexStatement, "arn")
+	assert.Equal(t, true, getWAFUnknownStatement(t, plan, blockPathsIndex, "regex_pattern_set_reference_statement")["arn"])
+	fieldToMatch := regexStatement["field_to_match"].([]interface{})[0].(map[string]interface{})
+	assert.Len(t, fieldToMatch["uri_path"], 1)
+	assert.Empty(t, fieldToMatch["query_string"])
+	transformations := regexStatement["text_transformation"].([]interface{})
+	require.Len(t, transformations, 1)
+	assert.Equal(t, "LOWERCASE", transformations[0].(map[string]interface{})["type"])
+
+	// An ARN that is not a key of ip_sets is passed through unchanged
+	_, allowPartners := getWAFRule(t, rules, "allow-partners")
+	assert.Equal(t, partnerIPSetArn, getWAFStatement(t, allowPartners, "ip_set_reference_statement")["arn"])
+}
+
+func TestWAFDuplicateRulePriorities(t *testing.T) {
+	t.Parallel()
+
+	name := fmt.Sprintf("test-waf-dup-%d", time.Now().Unix())
+
+	region := "us-east-1"
+	err := planModuleE(t, "waf", region, map[string]interface{}{
+		"name": name,
+		"rules": []map[string]interface{}{
+			{
+				"name":            "aws-common",
+				"priority":        1,
+				"override_action": "none",
+				"statement": map[string]interface{}{
+					"managed_rule_group_statement": map[string]interface{}{
+						"name": "AWSManagedRulesCommonRuleSet",
+					},
+				},
+			},
+			{
+				"name":     "rate-limit",
+				"priority": 1,
+				"action":   "block",
+				"statement": map[string]interface{}{
+					"rate_based_statement": map[string]interface{}{
+						"limit": 1000,
+					},
+				},
+			},
+		},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Each rule must have a unique priority")
+}
+
+func TestWAFScope(t *testing.T) {
+	t.Parallel()
+
+	for _, scope := range []string{"REGIONAL", "CLOUDFRONT"} {
+		scope := scope
+		t.Run(scope, func(t *testing.T) {
+			t.Parallel()
+
+			name := fmt.Sprintf("test-waf-%s-%d", scope, time.Now().Unix())
+
+			region := "us-east-1"
+			plan := planModule(t, "waf", region, map[string]interface{}{
+				"name":  name,
+				"scope": scope,
+				"ip_sets": map[string]interface{}{
+					"office": map[string]interface{}{
+						"ip_address_version": "IPV4",
+						"addresses":          []string{"203.0.113.0/24"},
+					},
+				},
+				"regex_pattern_sets": map[string]interface{}{
+					"scanners": map[string]interface{}{
+						"patterns": []string{"(?i)sqlmap"},
+					},
+				},
+			})
+
+			// Every WAFv2 resource has to share the Web ACL's scope, otherwise
+			// rules cannot reference the sets
+			for _, address := range []string{
+				"aws_wafv2_web_acl.this",
+				`aws_wafv2_ip_set.this["office"]`,
+				`aws_wafv2_regex_pattern_set.this["scanners"]`,
+			} {
+				attributes := getPlannedAttributes(t, plan, address)
+				assert.Equal(t, scope, attributes["scope"], "scope of %s", address)
+			}
+		})
+	}
+
+	// CloudFront only accepts Web ACLs from us-east-1
+	name := fmt.Sprintf("test-waf-cf-west-%d", time.Now().Unix())
+	err := planModuleE(t, "waf", "us-west-2", map[string]interface{}{
+		"name":  name,
+		"scope": "CLOUDFRONT",
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "CLOUDFRONT scoped Web ACLs must be created in us-east-1")
+}
+
+func TestWAFDefaultBlockAndLogging(t *testing.T) {
+	t.Parallel()
+
+	name := fmt.Sprintf("test-waf-log-%d", time.Now().Unix())
+
+	region := "us-east-1"
+	plan := planModule(t, "waf", region, map[string]interface{}{
+		"name":           name,
+		"default_action": "block",
+		"default_block_custom_response": map[string]interface{}{
+			"response_code": 403,
+			"response_headers": []map[string]string{
+				{"name": "x-blocked-by", "value": "waf"},
+			},
+		},
+		"create_log_group": true,
+		"logging_configuration": map[string]interface{}{
+			"log_destination_configs": []string{},
+			"redacted_fields": []map[string]interface{}{
+				{"single_header": "authorization"},
+			},
+		},
+	})
+
+	webACL := getPlannedAttributes(t, plan, "aws_wafv2_web_acl.this")
+	defaultAction := webACL["default_action"].([]interface{})[0].(map[string]interface{})
+	assert.Empty(t, defaultAction["allow"])
+	block := defaultAction["block"].([]interface{})
+	require.Len(t, block, 1)
+	customResponse := block[0].(map[string]interface{})["custom_response"].([]interface{})
+	require.Len(t, customResponse, 1)
+	assert.EqualValues(t, 403, customResponse[0].(map[string]interface{})["response_code"])
+
+	logGroup := getPlannedAttributes(t, plan, "aws_cloudwatch_log_group.waf[0]")
+	assert.Equal(t, fmt.Sprintf("aws-waf-logs-%s", name), logGroup["name"])
+
+	logging := getPlannedAttributes(t, plan, "aws_wafv2_web_acl_logging_configuration.this[0]")
+	redacted := logging["redacted_fields"].([]interface{})
+	require.Len(t, redacted, 1)
+	header := redacted[0].(map[string]interface{})["single_header"].([]interface{})
+	require.Len(t, header, 1)
+	assert.Equal(t, "authorization", header[0].(map[string]interface{})["name"])
+}
+
+// getWAFRule returns a planned Web ACL rule by name along with its position in
+// the rule set
+func getWAFRule(t *testing.T, rules []interface{}, name string) (int, map[string]interface{}) {
+	for i, r := range rules {
+		rule := r.(map[string]interface{})
+		if rule["name"] == name {
+			return i, rule
+		}
+	}
+
+	t.Fatalf("No rule named %s in the planned Web ACL", name)
+	return 0, nil
+}
+
+// getWAFStatement returns the single statement of the given type inside a
+// planned rule
+func getWAFStatement(t *testing.T, rule map[string]interface{}, statementType string) map[string]interface{} {
+	statement := rule["statement"].([]interface{})
+	require.Len(t, statement, 1)
+
+	statements := statement[0].(map[string]interface{})[statementType].([]interface{})
+	require.Len(t, statements, 1, "rule %s has no %s", rule["name"], statementType)
+
+	return statements[0].(map[string]interface{})
+}
+
+// getWAFUnknownStatement returns which attributes of a rule statement are
+// unknown until apply, for the rule at index in the planned rule set
+func getWAFUnknownStatement(t *testing.T, plan *terraform.PlanStruct, index int, statementType string) map[string]interface{} {
+	change, ok := plan.ResourceChangesMap["aws_wafv2_web_acl.this"]
+	require.True(t, ok, "aws_wafv2_web_acl.this is not part of the plan")
+
+	afterUnknown := change.Change.AfterUnknown.(map[string]interface{})
+	rules := afterUnknown["rule"].([]interface{})
+	require.Greater(t, len(rules), index)
+
+	statement := rules[index].(map[string]interface{})["statement"].([]interface{})
+	require.Len(t, statement, 1)
+
+	statements := statement[0].(map[string]interface{})[statementType].([]interface{})
+	require.Len(t, statements, 1)
+
+	return statements[0].(map[string]interface{})
+}